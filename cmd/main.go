@@ -1,16 +1,33 @@
 package main
 
 import (
+	"context"
+	"log/slog"
 	"os"
 
 	"github.com/CelanMatjaz/job_application_tracker_api/pkg/api"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/assert"
 	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/logging"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
 )
 
-func main()  {
+func main() {
+	logging.Init()
+
+	shutdownTracing, err := tracing.Init(context.Background())
+	assert.AssertError(err, "Could not initialize tracing")
+
 	database := db.NewDbConnection(os.Getenv("CONNECTION_STRING"))
 
+	err = database.Migrate(context.Background())
+	assert.AssertError(err, "Could not run database migrations")
+
 	server := api.NewAPIServer("8080", database)
-	server.Start()
+	err = server.Start()
+	shutdownTracing(context.Background())
+	if err != nil {
+		slog.Error("Server stopped", "error", err)
+		os.Exit(1)
+	}
 }
-
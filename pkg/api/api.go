@@ -1,19 +1,42 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/mailer"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/metrics"
 	"github.com/CelanMatjaz/job_application_tracker_api/pkg/middleware"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/openapi"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/ratelimit"
 	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service/admin"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service/apikeys"
 	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service/auth"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service/exports"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service/health"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service/jwks"
 	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service/resumes"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
 )
 
+const (
+	shutdownTimeout    = 15 * time.Second
+	defaultDrainPeriod = 5 * time.Second
+)
+
 type APIServer struct {
 	port string
 	db   *db.DbConnection
@@ -29,37 +52,242 @@ func NewAPIServer(port string, db *db.DbConnection) *APIServer {
 func (s *APIServer) Start() error {
 	err := service.JwtClient.InitJwtAuth()
 	if err != nil {
-		log.Fatal("Could not initialize jwt auth: ", err)
+		return fmt.Errorf("could not initialize jwt auth: %w", err)
+	}
+
+	healthHandler := health.NewHandler(s.db)
+
+	r, err := s.newRouter(healthHandler)
+	if err != nil {
+		return err
+	}
+
+	server := http.Server{
+		Addr:    fmt.Sprintf("0.0.0.0:%s", s.port),
+		Handler: r,
+	}
+
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Serve(listener)
+	}()
+
+	slog.Info("Starting server", "port", s.port)
+	healthHandler.SetReady(true)
+
+	select {
+	case err := <-serverErr:
+		healthHandler.SetReady(false)
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down server")
+	healthHandler.SetReady(false)
+
+	// Keep serving while readiness reports 503 so the orchestrator can stop
+	// routing new traffic here before the listener goes away.
+	time.Sleep(drainPeriod())
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	if err := <-serverErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Handler returns the router Start serves, without marking it ready, so the
+// API can be run by tests or another server. JwtClient has to be initialized
+// first.
+func (s *APIServer) Handler() (http.Handler, error) {
+	return s.newRouter(health.NewHandler(s.db))
+}
+
+func drainPeriod() time.Duration {
+	period, err := time.ParseDuration(os.Getenv("SHUTDOWN_DRAIN_PERIOD"))
+	if err != nil {
+		return defaultDrainPeriod
+	}
+
+	return period
+}
+
+func (s *APIServer) newRouter(healthHandler *health.Handler) (*chi.Mux, error) {
+	revocationCacheTTL, err := service.DurationFromEnv("REVOCATION_CACHE_TTL", service.DefaultRevocationCacheTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	authConfig, err := auth.ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	exportConfig, err := exports.ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	mailClient, err := mailer.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	authStore := auth.NewStore(s.db)
+	if err := service.JwtClient.SetKeyStore(authStore); err != nil {
+		return nil, err
+	}
+	revocations := service.NewRevocationCache(authStore, revocationCacheTTL)
+	service.JwtClient.SetRevocations(revocations)
+
+	trustedProxies, err := middleware.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, fmt.Errorf("TRUSTED_PROXIES is not valid: %w", err)
+	}
+
+	limiter, err := ratelimit.FromEnv(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	// Routes used before signing in are limited by address, which makes
+	// guessing credentials and sending emails costly. Signed in users are
+	// limited by id like the rest of the API.
+	authRateLimit, err := ratelimit.RuleFromEnv("RATE_LIMIT_AUTH", ratelimit.Rule{Name: "auth", Limit: 30, Period: time.Minute})
+	if err != nil {
+		return nil, err
+	}
+
+	apiRateLimit, err := ratelimit.RuleFromEnv("RATE_LIMIT_API", ratelimit.Rule{Name: "api", Limit: 300, Period: time.Minute})
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeyStore := apikeys.NewStore(s.db)
+	service.ApiKeys = apikeys.NewVerifier(apiKeyStore)
+
+	openAPIDocument := newOpenAPIDocument()
+	openAPIHandler, err := openapi.Handler(openAPIDocument)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode OpenAPI document: %w", err)
 	}
 
 	r := chi.NewRouter()
 
+	r.Use(middleware.RealIP(trustedProxies))
+	r.Use(middleware.Tracing())
+	r.Use(middleware.RequestLogger())
+	r.Use(middleware.Metrics())
 	r.Use(chiMiddleware.StripSlashes)
+	// Requests are validated before they are authenticated or rate limited.
+	// The document is public, so its errors tell clients nothing new, and
+	// invalid requests never reach the database or count against a limit.
+	r.Use(middleware.ValidateRequests(openAPIDocument, os.Getenv("OPENAPI_VALIDATE_RESPONSES") == "true"))
 
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(fmt.Sprint("Test")))
 	})
 
+	healthHandler.AddRoutes(r)
+	jwks.NewHandler(service.JwtClient.Keys()).AddRoutes(r)
+
+	if err := metrics.RegisterDBStats(s.db.DB); err != nil {
+		return nil, err
+	}
+	if token := os.Getenv("METRICS_TOKEN"); token != "" {
+		r.Method(http.MethodGet, "/metrics", metrics.Handler(token))
+	} else {
+		slog.Warn("METRICS_TOKEN not provided, /metrics endpoint is disabled")
+	}
+
 	r.Route("/api/v1", func(r chi.Router) {
+		r.Get("/openapi.json", openAPIHandler)
+
+		authHandler := auth.NewHandler(authStore, revocations, mailClient, limiter, authConfig)
+
 		r.Group(func(r chi.Router) {
-			authStore := auth.NewStore(s.db)
-			authHandler := auth.NewHandler(authStore)
+			r.Use(middleware.RateLimit(limiter, authRateLimit))
+
 			authHandler.AddRoutes(r)
 		})
 
 		r.Group(func(r chi.Router) {
 			r.Use(middleware.JwtAuthenticator())
+			r.Use(middleware.RateLimit(limiter, apiRateLimit))
+
+			authHandler.AddAccountRoutes(r)
 		})
-	})
 
-	server := http.Server{
-		Addr:    fmt.Sprintf("0.0.0.0:%s", s.port),
-		Handler: r,
-	}
+		exportHandler := exports.NewHandler(exports.NewStore(s.db), exportConfig)
 
-	fmt.Printf("Starting server on port %s\n", s.port)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.JwtAuthenticator())
+			r.Use(middleware.RateLimit(limiter, apiRateLimit))
 
-	server.ListenAndServe()
+			exportHandler.AddRoutes(r)
+		})
 
-	return nil
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RateLimit(limiter, apiRateLimit))
+
+			exportHandler.AddDownloadRoutes(r)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.JwtAuthenticator())
+			if authConfig.VerificationPolicy == auth.VerificationRequired {
+				r.Use(middleware.RequireVerifiedEmail())
+			}
+			r.Use(middleware.RateLimit(limiter, apiRateLimit))
+
+			apiKeyHandler := apikeys.NewHandler(apiKeyStore)
+			apiKeyHandler.AddRoutes(r)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Authenticator())
+			if authConfig.VerificationPolicy == auth.VerificationRequired {
+				r.Use(middleware.RequireVerifiedEmail())
+			}
+			r.Use(middleware.RateLimit(limiter, apiRateLimit))
+
+			resumeStore := resumes.NewStore(s.db)
+			resumeHandler := resumes.NewHandler(resumeStore)
+			resumeHandler.AddRoutes(r)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.JwtAuthenticator())
+			r.Use(middleware.RequireRole(types.RoleAdmin))
+			r.Use(middleware.RateLimit(limiter, apiRateLimit))
+
+			adminHandler := admin.NewHandler(admin.NewStore(s.db))
+			adminHandler.AddRoutes(r)
+		})
+	})
+
+	missingRoutes, err := openapi.MissingRoutes(openAPIDocument, r)
+	if err != nil {
+		return nil, err
+	}
+	if len(missingRoutes) > 0 {
+		return nil, fmt.Errorf("routes missing from the OpenAPI document: %s", strings.Join(missingRoutes, ", "))
+	}
+
+	return r, nil
 }
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// newTestRouter builds a router with API requests limited to 2 a minute.
+func newTestRouter(t *testing.T) http.Handler {
+	t.Helper()
+
+	setTestEnv(t)
+	t.Setenv("RATE_LIMIT_API", "2/1m")
+
+	if err := service.JwtClient.InitJwtAuth(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The database is never reached while building the router.
+	s := NewAPIServer("0", db.NewDbConnection("postgres://localhost:1/unused?sslmode=disable"))
+	handler, err := s.Handler()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return handler
+}
+
+// testToken signs an access token for the user. Tokens are accepted without
+// the database once the revocations are unset, requests still fail in the
+// handlers after the rate limit.
+func testToken(t *testing.T, userId int) string {
+	t.Helper()
+	service.JwtClient.SetRevocations(nil)
+
+	user := types.User{}
+	user.Id = userId
+	signed, err := service.JwtClient.CreateToken(context.Background(), user, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+type rateLimitTest struct {
+	name      string
+	method    string
+	target    string
+	token     string
+	remaining string
+	limited   bool
+}
+
+// testRateLimits sends the requests in order from the same address.
+func testRateLimits(t *testing.T, router http.Handler, tests []rateLimitTest) {
+	t.Helper()
+
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, test.target, nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		if test.token != "" {
+			r.Header.Set("Authorization", "Bearer "+test.token)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if remaining := w.Header().Get("RateLimit-Remaining"); remaining != test.remaining {
+			t.Errorf("%s: RateLimit-Remaining = %q, want %q", test.name, remaining, test.remaining)
+		}
+		if limited := w.Code == http.StatusTooManyRequests; limited != test.limited {
+			t.Errorf("%s: status = %d", test.name, w.Code)
+		}
+	}
+}
+
+func TestExportRateLimitIsPerUser(t *testing.T) {
+	router := newTestRouter(t)
+	first, second := testToken(t, 1), testToken(t, 2)
+
+	testRateLimits(t, router, []rateLimitTest{
+		{"first user", http.MethodGet, "/api/v1/me/export/1", first, "1", false},
+		{"first user again", http.MethodGet, "/api/v1/me/export/1", first, "0", false},
+		{"first user over the limit", http.MethodGet, "/api/v1/me/export/1", first, "0", true},
+		{"second user from the same address", http.MethodGet, "/api/v1/me/export/1", second, "1", false},
+		{"unauthenticated", http.MethodGet, "/api/v1/me/export/1", "", "", false},
+		{"download by address", http.MethodGet, "/api/v1/exports/1/download?token=invalid", "", "1", false},
+	})
+}
+
+func TestAccountRateLimitIsPerUser(t *testing.T) {
+	router := newTestRouter(t)
+	first, second := testToken(t, 1), testToken(t, 2)
+
+	testRateLimits(t, router, []rateLimitTest{
+		{"first user", http.MethodGet, "/api/v1/me", first, "1", false},
+		{"first user again", http.MethodPost, "/api/v1/auth/logout", first, "0", false},
+		{"first user over the limit", http.MethodGet, "/api/v1/me/sessions", first, "0", true},
+		{"second user from the same address", http.MethodGet, "/api/v1/me", second, "1", false},
+		{"unauthenticated", http.MethodGet, "/api/v1/me", "", "", false},
+		{"sign in by address", http.MethodGet, "/api/v1/auth/oauth/providers", "", "29", false},
+	})
+}
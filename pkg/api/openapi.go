@@ -0,0 +1,1032 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/openapi"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service/admin"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service/apikeys"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service/auth"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service/exports"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service/health"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service/jwks"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service/resumes"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/webauthn"
+)
+
+const openAPIPath = "/api/v1/openapi.json"
+
+var bearerAuth = []map[string][]string{{"bearerAuth": {}}}
+
+// apiKeyOrBearerAuth lists the scope an API key needs for the operation.
+func apiKeyOrBearerAuth(scope string) []map[string][]string {
+	return []map[string][]string{{"bearerAuth": {}}, {"apiKey": {scope}}}
+}
+
+// newOpenAPIDocument describes every route registered in Start. Routes that
+// are added to the router without an operation here stop the server from
+// starting, see openapi.MissingRoutes.
+func newOpenAPIDocument() *openapi.Document {
+	doc := openapi.NewDocument(openapi.Info{
+		Title:       "Job application tracker API",
+		Description: "Successful responses wrap their payload in a JsonResponse envelope, failed ones return an ErrorResponse.",
+		Version:     "1.0.0",
+	})
+
+	doc.Components.SecuritySchemes["bearerAuth"] = &openapi.SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+	}
+	doc.Components.SecuritySchemes["apiKey"] = &openapi.SecurityScheme{
+		Type:        "http",
+		Scheme:      "bearer",
+		Description: "Personal API key starting with " + service.ApiKeyPrefix + ", limited to the listed scopes.",
+	}
+	doc.Components.SecuritySchemes["metricsToken"] = &openapi.SecurityScheme{
+		Type:        "http",
+		Scheme:      "bearer",
+		Description: "Token configured with METRICS_TOKEN.",
+	}
+
+	doc.Register("ErrorResponse", service.ErrorResponse{})
+	user := doc.Register("User", types.User{})
+	resume := doc.Register("Resume", types.Resume{})
+	identity := doc.Register("Identity", types.Identity{})
+	apiKey := doc.Register("ApiKey", types.ApiKey{})
+	session := doc.Register("Session", types.Session{})
+	passkey := doc.Register("Passkey", types.Passkey{})
+
+	addHealthOperations(doc)
+	addJwksOperations(doc)
+	addAuthOperations(doc, user)
+	addAccountOperations(doc, user)
+	addSessionOperations(doc, session)
+	addPasskeyOperations(doc, passkey)
+	addDataExportOperations(doc)
+	addTwoFactorOperations(doc)
+	addOAuthOperations(doc, identity)
+	addApiKeyOperations(doc, apiKey)
+	addResumeOperations(doc, resume)
+	addAdminOperations(doc, user)
+	addRateLimitResponses(doc)
+
+	return doc
+}
+
+// addRateLimitResponses documents the 429 of the rate limits on every API
+// route, see middleware.RateLimit.
+func addRateLimitResponses(doc *openapi.Document) {
+	for path, item := range doc.Paths {
+		if !strings.HasPrefix(path, "/api/v1/") || path == openAPIPath {
+			continue
+		}
+
+		for _, operation := range *item {
+			if _, ok := operation.Responses["429"]; !ok {
+				operation.Responses["429"] = errorResponse("Rate limit is exceeded, see the Retry-After and RateLimit-* headers")
+			}
+		}
+	}
+}
+
+func addHealthOperations(doc *openapi.Document) {
+	doc.AddOperation(http.MethodGet, "/", &openapi.Operation{
+		OperationId: "getRoot",
+		Summary:     "Test route",
+		Tags:        []string{"health"},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Plain text test response"},
+		},
+	})
+
+	doc.AddOperation(http.MethodGet, "/healthz", &openapi.Operation{
+		OperationId: "getLiveness",
+		Summary:     "Reports whether the process is alive",
+		Tags:        []string{"health"},
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Process is alive", openapi.SchemaFor(health.LivenessResponseData{})),
+		},
+	})
+
+	readiness := openapi.SchemaFor(health.ReadinessResponseData{})
+	doc.AddOperation(http.MethodGet, "/readyz", &openapi.Operation{
+		OperationId: "getReadiness",
+		Summary:     "Reports whether the server and its dependencies can serve traffic",
+		Tags:        []string{"health"},
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Ready to serve traffic", readiness),
+			"503": jsonResponse("Starting up, shutting down or a dependency is unavailable", readiness),
+		},
+	})
+
+	doc.AddOperation(http.MethodGet, "/metrics", &openapi.Operation{
+		OperationId: "getMetrics",
+		Summary:     "Prometheus metrics, only served when METRICS_TOKEN is set",
+		Tags:        []string{"health"},
+		Security:    []map[string][]string{{"metricsToken": {}}},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Metrics in the Prometheus text exposition format"},
+			"401": {Description: "Missing or invalid metrics token"},
+		},
+	})
+
+	doc.AddOperation(http.MethodGet, openAPIPath, &openapi.Operation{
+		OperationId: "getOpenAPI",
+		Summary:     "This document",
+		Tags:        []string{"health"},
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JsonContent("OpenAPI document", &openapi.Schema{Type: "object"}),
+		},
+	})
+}
+
+func addJwksOperations(doc *openapi.Document) {
+	doc.AddOperation(http.MethodGet, "/.well-known/jwks.json", &openapi.Operation{
+		OperationId: "getJwks",
+		Summary:     "Public keys access tokens are signed with, as a bare JWK set without the JsonResponse envelope",
+		Tags:        []string{"auth"},
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JsonContent("Keys that sign or will soon sign tokens, and retired keys whose tokens have not expired", openapi.SchemaFor(jwks.KeySetResponse{})),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+}
+
+func addAuthOperations(doc *openapi.Document, user *openapi.Schema) {
+	registerResponse := openapi.SchemaFor(auth.RegisterResponseData{})
+	registerResponse.Properties["user"] = user
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/register", &openapi.Operation{
+		OperationId: "register",
+		Summary:     "Creates a user and returns an access and refresh token pair",
+		Tags:        []string{"auth"},
+		RequestBody: openapi.JsonBody(doc.Register("RegisterBody", auth.RegisterBody{})),
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("User was created", registerResponse),
+			"400": errorResponse("Body is not valid, the password does not satisfy the password policy or the email is taken"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/login", &openapi.Operation{
+		OperationId: "login",
+		Summary:     "Exchanges credentials for an access and refresh token pair",
+		Tags:        []string{"auth"},
+		RequestBody: openapi.JsonBody(doc.Register("LoginBody", auth.LoginBody{})),
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Credentials are valid", openapi.SchemaFor(auth.LoginResponseData{})),
+			"202": jsonResponse("Credentials are valid and the user has to complete a two-factor challenge", openapi.SchemaFor(auth.TwoFactorChallengeData{})),
+			"400": errorResponse("Body is not valid"),
+			"401": errorResponse("Email or password is not valid"),
+			"403": errorResponse("Account is disabled"),
+			"429": errorResponse("Too many failed login attempts for the account or address, or the rate limit is exceeded, see the Retry-After header"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/refresh", &openapi.Operation{
+		OperationId: "refreshToken",
+		Summary:     "Exchanges a refresh token for a new token pair",
+		Tags:        []string{"auth"},
+		RequestBody: openapi.JsonBody(doc.Register("RefreshBody", auth.RefreshBody{})),
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Refresh token was rotated", openapi.SchemaFor(auth.TokenResponseData{})),
+			"400": errorResponse("Body is not valid"),
+			"401": errorResponse("Refresh token is unknown, expired or was already used"),
+			"403": errorResponse("Account is disabled"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	message := openapi.SchemaFor(auth.MessageResponseData{})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/password/forgot", &openapi.Operation{
+		OperationId: "forgotPassword",
+		Summary:     "Emails a password reset link; the response does not reveal whether the email exists",
+		Tags:        []string{"auth"},
+		RequestBody: openapi.JsonBody(doc.Register("ForgotPasswordBody", auth.ForgotPasswordBody{})),
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Request was accepted", message),
+			"400": errorResponse("Body is not valid"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/password/reset", &openapi.Operation{
+		OperationId: "resetPassword",
+		Summary:     "Sets a new password with a reset token and revokes every existing token",
+		Tags:        []string{"auth"},
+		RequestBody: openapi.JsonBody(doc.Register("ResetPasswordBody", auth.ResetPasswordBody{})),
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Password was reset", message),
+			"400": errorResponse("Body is not valid, the token is not valid or has expired or the password does not satisfy the password policy"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/magic-link", &openapi.Operation{
+		OperationId: "requestMagicLink",
+		Summary:     "Emails a single use sign-in link; the response does not reveal whether the email exists",
+		Tags:        []string{"auth"},
+		RequestBody: openapi.JsonBody(doc.Register("MagicLinkBody", auth.MagicLinkBody{})),
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Request was accepted; the device token has to be sent along with the link", openapi.SchemaFor(auth.MagicLinkResponseData{})),
+			"400": errorResponse("Body is not valid"),
+			"429": errorResponse("Too many links were requested for the email, or the rate limit is exceeded, see the Retry-After header"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodGet, "/api/v1/auth/magic-link/verify", &openapi.Operation{
+		OperationId: "verifyMagicLink",
+		Summary:     "Exchanges a sign-in link for an access and refresh token pair, like a login with a password",
+		Tags:        []string{"auth"},
+		Parameters: []*openapi.Parameter{
+			{Name: "token", In: "query", Description: "Token of the sign-in link", Required: true, Schema: &openapi.Schema{Type: "string", MaxLength: intPointer(1000)}},
+			{Name: auth.MagicLinkDeviceHeader, In: "header", Description: "Device token returned when the link was requested", Required: true, Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Link is valid", openapi.SchemaFor(auth.LoginResponseData{})),
+			"202": jsonResponse("Link is valid and the user has to complete a two-factor challenge", openapi.SchemaFor(auth.TwoFactorChallengeData{})),
+			"400": errorResponse("Parameter is not valid"),
+			"401": errorResponse("Link is not valid, has expired, was already used or was requested on another device"),
+			"403": errorResponse("Account is disabled"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/email/verify", &openapi.Operation{
+		OperationId: "verifyEmail",
+		Summary:     "Marks the email address as verified using the token from a verification link",
+		Tags:        []string{"auth"},
+		RequestBody: openapi.JsonBody(doc.Register("VerifyEmailBody", auth.VerifyEmailBody{})),
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Email address was verified", message),
+			"400": errorResponse("Body is not valid or the token is not valid or has expired"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/email/resend", &openapi.Operation{
+		OperationId: "resendVerificationEmail",
+		Summary:     "Sends a new verification link to the user's email address",
+		Tags:        []string{"auth"},
+		Security:    bearerAuth,
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Verification email was sent", message),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"404": errorResponse("Email verification is disabled"),
+			"409": errorResponse("Email address is already verified"),
+			"429": errorResponse("A verification email was sent recently"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	logoutBody := openapi.JsonBody(doc.Register("LogoutBody", auth.LogoutBody{}))
+	logoutBody.Required = false
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/logout", &openapi.Operation{
+		OperationId: "logout",
+		Summary:     "Revokes the access token and its session, or for tokens without a session the provided refresh token",
+		Tags:        []string{"auth"},
+		Security:    bearerAuth,
+		RequestBody: logoutBody,
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Tokens were revoked"},
+			"400": errorResponse("Body is not valid"),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/logout-all", &openapi.Operation{
+		OperationId: "logoutAll",
+		Summary:     "Revokes every access and refresh token issued to the user",
+		Tags:        []string{"auth"},
+		Security:    bearerAuth,
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Tokens were revoked"},
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/check", &openapi.Operation{
+		OperationId: "checkAuth",
+		Summary:     "Checks whether the bearer token is valid",
+		Tags:        []string{"auth"},
+		Security:    bearerAuth,
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Token is valid"},
+			"401": errorResponse("Token is missing or not valid"),
+		},
+	})
+}
+
+func addAccountOperations(doc *openapi.Document, user *openapi.Schema) {
+	message := openapi.SchemaFor(auth.MessageResponseData{})
+
+	doc.AddOperation(http.MethodGet, "/api/v1/me", &openapi.Operation{
+		OperationId: "getMe",
+		Summary:     "Returns the authenticated user",
+		Tags:        []string{"account"},
+		Security:    bearerAuth,
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("User", user),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPatch, "/api/v1/me", &openapi.Operation{
+		OperationId: "updateMe",
+		Summary:     "Updates the provided profile fields; the email is changed through changeEmail",
+		Tags:        []string{"account"},
+		Security:    bearerAuth,
+		RequestBody: openapi.JsonBody(doc.Register("UpdateProfileBody", auth.UpdateProfileBody{})),
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Updated user", user),
+			"400": errorResponse("Body is not valid"),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodDelete, "/api/v1/me", &openapi.Operation{
+		OperationId: "deleteMe",
+		Summary:     "Deletes the user and everything they own after checking the password and, with 2FA enabled, a code",
+		Tags:        []string{"account"},
+		Security:    bearerAuth,
+		RequestBody: openapi.JsonBody(doc.Register("DeleteAccountBody", auth.DeleteAccountBody{})),
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Account was deleted"},
+			"400": errorResponse("Body is not valid or the password or code is wrong"),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/me/password", &openapi.Operation{
+		OperationId: "changePassword",
+		Summary:     "Changes the password, revokes every token and returns a new token pair",
+		Tags:        []string{"account"},
+		Security:    bearerAuth,
+		RequestBody: openapi.JsonBody(doc.Register("ChangePasswordBody", auth.ChangePasswordBody{})),
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Password was changed", openapi.SchemaFor(auth.TokenResponseData{})),
+			"400": errorResponse("Body is not valid, the current password is wrong or the new password does not satisfy the password policy"),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/me/email", &openapi.Operation{
+		OperationId: "changeEmail",
+		Summary:     "Emails a link to the new address; the email changes once the link is confirmed",
+		Tags:        []string{"account"},
+		Security:    bearerAuth,
+		RequestBody: openapi.JsonBody(doc.Register("ChangeEmailBody", auth.ChangeEmailBody{})),
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Confirmation link was sent", message),
+			"400": errorResponse("Body is not valid, the password is wrong or the email did not change"),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"409": errorResponse("Email is taken"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/email/change/confirm", &openapi.Operation{
+		OperationId: "confirmEmailChange",
+		Summary:     "Changes the email address using the token from an email change link",
+		Tags:        []string{"account"},
+		RequestBody: openapi.JsonBody(doc.Register("ConfirmEmailChangeBody", auth.ConfirmEmailChangeBody{})),
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Email address was changed", message),
+			"400": errorResponse("Body is not valid or the token is not valid or has expired"),
+			"409": errorResponse("Email is taken"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+}
+
+func addSessionOperations(doc *openapi.Document, session *openapi.Schema) {
+	doc.AddOperation(http.MethodGet, "/api/v1/me/sessions", &openapi.Operation{
+		OperationId: "listSessions",
+		Summary:     "Lists the devices the user is logged in on, most recently seen first",
+		Tags:        []string{"account"},
+		Security:    bearerAuth,
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Sessions", &openapi.Schema{Type: "array", Items: session}),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodDelete, "/api/v1/me/sessions/{sessionId}", &openapi.Operation{
+		OperationId: "revokeSession",
+		Summary:     "Signs a session out, revoking its refresh and access tokens",
+		Tags:        []string{"account"},
+		Security:    bearerAuth,
+		Parameters:  []*openapi.Parameter{pathParameter("sessionId", "Id of the session")},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Session was revoked"},
+			"400": errorResponse("Path parameter is not valid"),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"404": errorResponse("Session does not exist"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+}
+
+func addPasskeyOperations(doc *openapi.Document, passkey *openapi.Schema) {
+	doc.AddOperation(http.MethodGet, "/api/v1/me/passkeys", &openapi.Operation{
+		OperationId: "listPasskeys",
+		Summary:     "Lists the user's passkeys",
+		Tags:        []string{"account"},
+		Security:    bearerAuth,
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Passkeys", &openapi.Schema{Type: "array", Items: passkey}),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/me/passkeys/register/begin", &openapi.Operation{
+		OperationId: "beginPasskeyRegistration",
+		Summary:     "Returns WebAuthn creation options for navigator.credentials.create",
+		Tags:        []string{"account"},
+		Security:    bearerAuth,
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Creation options, valid for WEBAUTHN_TIMEOUT", doc.Register("PasskeyCreationOptions", webauthn.CreationOptions{})),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/me/passkeys/register/finish", &openapi.Operation{
+		OperationId: "finishPasskeyRegistration",
+		Summary:     "Verifies the credential created with the creation options and adds it as a passkey",
+		Tags:        []string{"account"},
+		Security:    bearerAuth,
+		RequestBody: openapi.JsonBody(doc.Register("RegisterPasskeyBody", auth.RegisterPasskeyBody{})),
+		Responses: map[string]*openapi.Response{
+			"201": jsonResponse("Passkey was added", passkey),
+			"400": errorResponse("Body is not valid, the options expired or were already used, or the credential could not be verified"),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"409": errorResponse("Passkey is already registered"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodDelete, "/api/v1/me/passkeys/{passkeyId}", &openapi.Operation{
+		OperationId: "deletePasskey",
+		Summary:     "Removes a passkey, which can no longer be used to log in",
+		Tags:        []string{"account"},
+		Security:    bearerAuth,
+		Parameters:  []*openapi.Parameter{pathParameter("passkeyId", "Id of the passkey")},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Passkey was removed"},
+			"400": errorResponse("Path parameter is not valid"),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"404": errorResponse("Passkey does not exist"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/passkeys/login/begin", &openapi.Operation{
+		OperationId: "beginPasskeyLogin",
+		Summary:     "Returns WebAuthn request options for navigator.credentials.get, accepting any of the service's passkeys",
+		Tags:        []string{"auth"},
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Request options, valid for WEBAUTHN_TIMEOUT", doc.Register("PasskeyRequestOptions", webauthn.RequestOptions{})),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/passkeys/login/finish", &openapi.Operation{
+		OperationId: "finishPasskeyLogin",
+		Summary:     "Exchanges a passkey assertion for an access and refresh token pair; no two-factor challenge follows",
+		Tags:        []string{"auth"},
+		RequestBody: openapi.JsonBody(doc.Register("PasskeyLoginBody", auth.PasskeyLoginBody{})),
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Passkey is valid", openapi.SchemaFor(auth.LoginResponseData{})),
+			"400": errorResponse("Body is not valid"),
+			"401": errorResponse("Options expired or were already used, or the passkey is unknown, not valid or appears to be cloned"),
+			"403": errorResponse("Account is disabled"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+}
+
+func addDataExportOperations(doc *openapi.Document) {
+	export := doc.Register("DataExport", exports.DataExportResponseData{})
+	exportId := pathParameter("exportId", "Id of the data export")
+
+	doc.AddOperation(http.MethodPost, "/api/v1/me/export", &openapi.Operation{
+		OperationId: "createDataExport",
+		Summary:     "Starts building a ZIP archive of the user's data, or returns the export already being built",
+		Tags:        []string{"account"},
+		Security:    bearerAuth,
+		Responses: map[string]*openapi.Response{
+			"202": jsonResponse("Export is being built", export),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodGet, "/api/v1/me/export/{exportId}", &openapi.Operation{
+		OperationId: "getDataExport",
+		Summary:     "Returns the status of a data export and, once ready, its download link",
+		Tags:        []string{"account"},
+		Security:    bearerAuth,
+		Parameters:  []*openapi.Parameter{exportId},
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Data export", export),
+			"400": errorResponse("Path parameter is not valid"),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"404": errorResponse("Data export does not exist"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodGet, "/api/v1/exports/{exportId}/download", &openapi.Operation{
+		OperationId: "downloadDataExport",
+		Summary:     "Downloads a data export archive through the link returned by getDataExport",
+		Tags:        []string{"account"},
+		Parameters: []*openapi.Parameter{
+			exportId,
+			{Name: "token", In: "query", Description: "Token of the download link", Required: true, Schema: &openapi.Schema{Type: "string", MaxLength: intPointer(200)}},
+		},
+		Responses: map[string]*openapi.Response{
+			"200": {
+				Description: "ZIP archive with a README and a JSON file per kind of record",
+				Content:     map[string]*openapi.MediaType{"application/zip": {Schema: &openapi.Schema{Type: "string", Format: "binary"}}},
+			},
+			"400": errorResponse("Parameter is not valid"),
+			"403": errorResponse("Download link is not valid or has expired"),
+			"404": errorResponse("Data export does not exist or has expired"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+}
+
+func addTwoFactorOperations(doc *openapi.Document) {
+	codeBody := openapi.JsonBody(doc.Register("TwoFactorCodeBody", auth.TwoFactorCodeBody{}))
+	recoveryCodes := openapi.SchemaFor(auth.RecoveryCodesResponseData{})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/login/2fa", &openapi.Operation{
+		OperationId: "loginTwoFactor",
+		Summary:     "Completes a login challenge with a TOTP or recovery code",
+		Tags:        []string{"auth", "2fa"},
+		RequestBody: openapi.JsonBody(doc.Register("TwoFactorLoginBody", auth.TwoFactorLoginBody{})),
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Code is valid", openapi.SchemaFor(auth.LoginResponseData{})),
+			"400": errorResponse("Body is not valid"),
+			"401": errorResponse("Challenge is not valid, has expired or the code is wrong"),
+			"403": errorResponse("Account is disabled"),
+			"429": errorResponse("Too many failed login attempts for the account or address, or the rate limit is exceeded, see the Retry-After header"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/2fa/setup", &openapi.Operation{
+		OperationId: "setupTotp",
+		Summary:     "Creates a new TOTP secret that is enabled once confirmed with a code",
+		Tags:        []string{"2fa"},
+		Security:    bearerAuth,
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Secret was created", openapi.SchemaFor(auth.TotpSetupResponseData{})),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"409": errorResponse("Two-factor authentication is already enabled"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/2fa/enable", &openapi.Operation{
+		OperationId: "enableTotp",
+		Summary:     "Enables two-factor authentication with a code from the new secret and returns recovery codes",
+		Tags:        []string{"2fa"},
+		Security:    bearerAuth,
+		RequestBody: codeBody,
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Two-factor authentication was enabled", recoveryCodes),
+			"400": errorResponse("Body is not valid, the code is wrong or setup was not started"),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"409": errorResponse("Two-factor authentication is already enabled"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/2fa/disable", &openapi.Operation{
+		OperationId: "disableTotp",
+		Summary:     "Disables two-factor authentication after checking the password and a code",
+		Tags:        []string{"2fa"},
+		Security:    bearerAuth,
+		RequestBody: openapi.JsonBody(doc.Register("DisableTwoFactorBody", auth.DisableTwoFactorBody{})),
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Two-factor authentication was disabled", openapi.SchemaFor(auth.MessageResponseData{})),
+			"400": errorResponse("Body is not valid or the password or code is wrong"),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"409": errorResponse("Two-factor authentication is not enabled"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/2fa/recovery-codes", &openapi.Operation{
+		OperationId: "regenerateRecoveryCodes",
+		Summary:     "Replaces every recovery code with new ones",
+		Tags:        []string{"2fa"},
+		Security:    bearerAuth,
+		RequestBody: codeBody,
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Recovery codes were replaced", recoveryCodes),
+			"400": errorResponse("Body is not valid or the code is wrong"),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"409": errorResponse("Two-factor authentication is not enabled"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+}
+
+func addOAuthOperations(doc *openapi.Document, identity *openapi.Schema) {
+	provider := &openapi.Parameter{
+		Name:        "provider",
+		In:          "path",
+		Description: "Name of a provider listed by listOAuthProviders",
+		Required:    true,
+		Schema:      &openapi.Schema{Type: "string", Pattern: "^[a-z0-9_]+$"},
+	}
+
+	doc.AddOperation(http.MethodGet, "/api/v1/auth/oauth/providers", &openapi.Operation{
+		OperationId: "listOAuthProviders",
+		Summary:     "Lists the configured OAuth2 and OpenID Connect providers",
+		Tags:        []string{"oauth"},
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Providers", &openapi.Schema{Type: "array", Items: openapi.SchemaFor(auth.OAuthProviderData{})}),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/oauth/{provider}/authorize", &openapi.Operation{
+		OperationId: "authorizeOAuth",
+		Summary:     "Starts an authorization code flow with PKCE and returns the url to send the user to",
+		Tags:        []string{"oauth"},
+		Parameters:  []*openapi.Parameter{provider},
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Authorization was started", openapi.SchemaFor(auth.OAuthAuthorizeResponseData{})),
+			"404": errorResponse("Provider does not exist"),
+			"500": errorResponse("Internal server error"),
+			"502": errorResponse("Provider is not available"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/auth/oauth/{provider}/callback", &openapi.Operation{
+		OperationId: "oauthCallback",
+		Summary:     "Exchanges the code and state the provider redirected back with for tokens",
+		Tags:        []string{"oauth"},
+		Parameters:  []*openapi.Parameter{provider},
+		RequestBody: openapi.JsonBody(doc.Register("OAuthCallbackBody", auth.OAuthCallbackBody{})),
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("User was signed in", openapi.SchemaFor(auth.LoginResponseData{})),
+			"202": jsonResponse("User has to complete a two-factor challenge", openapi.SchemaFor(auth.TwoFactorChallengeData{})),
+			"400": errorResponse("Body is not valid, the state expired or the provider shared no email"),
+			"401": errorResponse("Provider rejected the code or the ID token is not valid"),
+			"403": errorResponse("Account is disabled"),
+			"404": errorResponse("Provider does not exist"),
+			"409": errorResponse("A user with the email exists but the provider or the user did not verify it"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodGet, "/api/v1/auth/identities", &openapi.Operation{
+		OperationId: "listIdentities",
+		Summary:     "Lists the provider identities linked to the user",
+		Tags:        []string{"oauth"},
+		Security:    bearerAuth,
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Identities", &openapi.Schema{Type: "array", Items: identity}),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodDelete, "/api/v1/auth/identities/{identityId}", &openapi.Operation{
+		OperationId: "deleteIdentity",
+		Summary:     "Unlinks a provider identity from the user",
+		Tags:        []string{"oauth"},
+		Security:    bearerAuth,
+		Parameters:  []*openapi.Parameter{pathParameter("identityId", "Id of the identity")},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Identity was unlinked"},
+			"400": errorResponse("Path parameter is not valid"),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled"),
+			"404": errorResponse("Identity does not exist"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+}
+
+func addApiKeyOperations(doc *openapi.Document, apiKey *openapi.Schema) {
+	createBody := doc.Register("CreateApiKeyBody", apikeys.CreateApiKeyBody{})
+	doc.Resolve(createBody).Properties["scopes"].Items.Enum = types.Scopes
+
+	createResponse := openapi.SchemaFor(apikeys.CreateApiKeyResponseData{})
+	createResponse.Properties["api_key"] = apiKey
+
+	doc.AddOperation(http.MethodGet, "/api/v1/api-keys", &openapi.Operation{
+		OperationId: "listApiKeys",
+		Summary:     "Lists the user's API keys that were not revoked",
+		Tags:        []string{"api-keys"},
+		Security:    bearerAuth,
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("API keys", &openapi.Schema{Type: "array", Items: apiKey}),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled, email address is not verified"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/api-keys", &openapi.Operation{
+		OperationId: "createApiKey",
+		Summary:     "Creates an API key; the key itself is only returned in this response",
+		Tags:        []string{"api-keys"},
+		Security:    bearerAuth,
+		RequestBody: openapi.JsonBody(createBody),
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("API key was created", createResponse),
+			"400": errorResponse("Body is not valid"),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled, email address is not verified"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodDelete, "/api/v1/api-keys/{apiKeyId}", &openapi.Operation{
+		OperationId: "revokeApiKey",
+		Summary:     "Revokes an API key",
+		Tags:        []string{"api-keys"},
+		Security:    bearerAuth,
+		Parameters:  []*openapi.Parameter{pathParameter("apiKeyId", "Id of the API key")},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "API key was revoked"},
+			"400": errorResponse("Path parameter is not valid"),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled, email address is not verified"),
+			"404": errorResponse("API key does not exist"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+}
+
+func addResumeOperations(doc *openapi.Document, resume *openapi.Schema) {
+	resumeBody := doc.Register("ResumePostBody", resumes.ResumePostBody{})
+	resumeId := pathParameter("resumeId", "Id of the resume")
+
+	doc.AddOperation(http.MethodGet, "/api/v1/resumes", &openapi.Operation{
+		OperationId: "listResumes",
+		Summary:     "Lists the user's resumes, newest first",
+		Tags:        []string{"resumes"},
+		Security:    apiKeyOrBearerAuth(types.ScopeResumesRead),
+		Parameters:  paginationParameters(),
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Page of resumes", &openapi.Schema{Type: "array", Items: resume}),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled, email address is not verified or the API key is missing the scope"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodGet, "/api/v1/resumes/{resumeId}", &openapi.Operation{
+		OperationId: "getResume",
+		Summary:     "Returns a single resume",
+		Tags:        []string{"resumes"},
+		Security:    apiKeyOrBearerAuth(types.ScopeResumesRead),
+		Parameters:  []*openapi.Parameter{resumeId},
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Resume", resume),
+			"400": errorResponse("Path parameter is not valid"),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled, email address is not verified or the API key is missing the scope"),
+			"404": errorResponse("Resume does not exist"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/resumes", &openapi.Operation{
+		OperationId: "createResume",
+		Summary:     "Creates a resume",
+		Tags:        []string{"resumes"},
+		Security:    apiKeyOrBearerAuth(types.ScopeResumesWrite),
+		RequestBody: openapi.JsonBody(resumeBody),
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Created resume", resume),
+			"400": errorResponse("Body is not valid"),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled, email address is not verified or the API key is missing the scope"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodPut, "/api/v1/resumes/{resumeId}", &openapi.Operation{
+		OperationId: "updateResume",
+		Summary:     "Replaces the name and note of a resume",
+		Tags:        []string{"resumes"},
+		Security:    apiKeyOrBearerAuth(types.ScopeResumesWrite),
+		Parameters:  []*openapi.Parameter{resumeId},
+		RequestBody: openapi.JsonBody(resumeBody),
+		Responses: map[string]*openapi.Response{
+			"200": jsonResponse("Updated resume", resume),
+			"400": errorResponse("Body or path parameter is not valid"),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled, email address is not verified or the API key is missing the scope"),
+			"404": errorResponse("Resume does not exist"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+
+	doc.AddOperation(http.MethodDelete, "/api/v1/resumes/{resumeId}", &openapi.Operation{
+		OperationId: "deleteResume",
+		Summary:     "Deletes a resume",
+		Tags:        []string{"resumes"},
+		Security:    apiKeyOrBearerAuth(types.ScopeResumesWrite),
+		Parameters:  []*openapi.Parameter{resumeId},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Resume was deleted"},
+			"400": errorResponse("Path parameter is not valid"),
+			"401": errorResponse("Token is missing or not valid"),
+			"403": errorResponse("Account is disabled, email address is not verified or the API key is missing the scope"),
+			"500": errorResponse("Internal server error"),
+		},
+	})
+}
+
+func addAdminOperations(doc *openapi.Document, user *openapi.Schema) {
+	userId := pathParameter("userId", "Id of the user")
+	adminResponses := func(responses map[string]*openapi.Response) map[string]*openapi.Response {
+		responses["401"] = errorResponse("Token is missing or not valid")
+		responses["403"] = errorResponse("Account is disabled or the user is not an admin")
+		responses["500"] = errorResponse("Internal server error")
+		return responses
+	}
+
+	doc.AddOperation(http.MethodGet, "/api/v1/admin/usage", &openapi.Operation{
+		OperationId: "getUsage",
+		Summary:     "Counts users and records across the service",
+		Tags:        []string{"admin"},
+		Security:    bearerAuth,
+		Responses: adminResponses(map[string]*openapi.Response{
+			"200": jsonResponse("Usage", openapi.SchemaFor(types.Usage{})),
+		}),
+	})
+
+	doc.AddOperation(http.MethodGet, "/api/v1/admin/users", &openapi.Operation{
+		OperationId: "listUsers",
+		Summary:     "Lists users ordered by id, optionally filtered",
+		Tags:        []string{"admin"},
+		Security:    bearerAuth,
+		Parameters: append([]*openapi.Parameter{
+			{Name: "q", In: "query", Description: "Part of the email, first or last name", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "role", In: "query", Description: "Only users with the role", Schema: &openapi.Schema{Type: "string", Enum: []string{types.RoleUser, types.RoleAdmin}}},
+			{Name: "disabled", In: "query", Description: "Only disabled or only enabled users", Schema: &openapi.Schema{Type: "boolean"}},
+		}, paginationParameters()...),
+		Responses: adminResponses(map[string]*openapi.Response{
+			"200": jsonResponse("Page of users", &openapi.Schema{Type: "array", Items: user}),
+			"400": errorResponse("Query parameter is not valid"),
+		}),
+	})
+
+	userResponse := openapi.SchemaFor(admin.UserResponseData{})
+	userResponse.Properties["user"] = user
+
+	doc.AddOperation(http.MethodGet, "/api/v1/admin/users/{userId}", &openapi.Operation{
+		OperationId: "getUser",
+		Summary:     "Returns a user with their usage counts",
+		Tags:        []string{"admin"},
+		Security:    bearerAuth,
+		Parameters:  []*openapi.Parameter{userId},
+		Responses: adminResponses(map[string]*openapi.Response{
+			"200": jsonResponse("User", userResponse),
+			"400": errorResponse("Path parameter is not valid"),
+			"404": errorResponse("User does not exist"),
+		}),
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/admin/users/{userId}/disable", &openapi.Operation{
+		OperationId: "disableUser",
+		Summary:     "Disables a user, rejecting their tokens and API keys and revoking their refresh tokens",
+		Tags:        []string{"admin"},
+		Security:    bearerAuth,
+		Parameters:  []*openapi.Parameter{userId},
+		Responses: adminResponses(map[string]*openapi.Response{
+			"200": jsonResponse("User was disabled", user),
+			"400": errorResponse("Path parameter is not valid"),
+			"404": errorResponse("User does not exist"),
+			"409": errorResponse("Admins cannot disable themselves"),
+		}),
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/admin/users/{userId}/enable", &openapi.Operation{
+		OperationId: "enableUser",
+		Summary:     "Enables a disabled user, who then has to log in again",
+		Tags:        []string{"admin"},
+		Security:    bearerAuth,
+		Parameters:  []*openapi.Parameter{userId},
+		Responses: adminResponses(map[string]*openapi.Response{
+			"200": jsonResponse("User was enabled", user),
+			"400": errorResponse("Path parameter is not valid"),
+			"404": errorResponse("User does not exist"),
+		}),
+	})
+
+	doc.AddOperation(http.MethodPost, "/api/v1/admin/users/{userId}/logout", &openapi.Operation{
+		OperationId: "forceLogoutUser",
+		Summary:     "Revokes every access and refresh token issued to a user",
+		Tags:        []string{"admin"},
+		Security:    bearerAuth,
+		Parameters:  []*openapi.Parameter{userId},
+		Responses: adminResponses(map[string]*openapi.Response{
+			"200": {Description: "Tokens were revoked"},
+			"400": errorResponse("Path parameter is not valid"),
+			"404": errorResponse("User does not exist"),
+		}),
+	})
+
+	doc.AddOperation(http.MethodPut, "/api/v1/admin/users/{userId}/role", &openapi.Operation{
+		OperationId: "setUserRole",
+		Summary:     "Changes a user's role; their access tokens stop working until they refresh",
+		Tags:        []string{"admin"},
+		Security:    bearerAuth,
+		Parameters:  []*openapi.Parameter{userId},
+		RequestBody: openapi.JsonBody(doc.Register("SetRoleBody", admin.SetRoleBody{})),
+		Responses: adminResponses(map[string]*openapi.Response{
+			"200": jsonResponse("Role was changed", user),
+			"400": errorResponse("Body or path parameter is not valid"),
+			"404": errorResponse("User does not exist"),
+			"409": errorResponse("Admins cannot remove their own admin role"),
+		}),
+	})
+}
+
+// jsonResponse wraps the data schema in the JsonResponse envelope sent by
+// service.SendJsonResponse.
+func jsonResponse(description string, data *openapi.Schema) *openapi.Response {
+	envelope := openapi.SchemaFor(service.JsonResponse{})
+	envelope.Properties["data"] = data
+
+	return openapi.JsonContent(description, envelope)
+}
+
+func errorResponse(description string) *openapi.Response {
+	return openapi.JsonContent(description, openapi.Ref("ErrorResponse"))
+}
+
+func pathParameter(name string, description string) *openapi.Parameter {
+	return &openapi.Parameter{
+		Name:        name,
+		In:          "path",
+		Description: description,
+		Required:    true,
+		Schema:      &openapi.Schema{Type: "integer", Minimum: float(1)},
+	}
+}
+
+func paginationParameters() []*openapi.Parameter {
+	return []*openapi.Parameter{
+		{Name: "page", In: "query", Description: "Page number, starting at 1", Schema: &openapi.Schema{Type: "integer", Minimum: float(1)}},
+		{Name: "count", In: "query", Description: "Page size", Schema: &openapi.Schema{Type: "integer", Minimum: float(1), Maximum: float(100)}},
+		{Name: "offset", In: "query", Description: "Records to skip before the page", Schema: &openapi.Schema{Type: "integer", Minimum: float(0)}},
+	}
+}
+
+func float(f float64) *float64 {
+	return &f
+}
+
+func intPointer(i int) *int {
+	return &i
+}
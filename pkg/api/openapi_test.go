@@ -0,0 +1,79 @@
+package api
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/openapi"
+	"github.com/go-chi/chi/v5"
+)
+
+func setTestEnv(t *testing.T) {
+	t.Helper()
+
+	for key, value := range map[string]string{
+		"JWT_SECRET":                "test-jwt-secret",
+		"JWT_ALGORITHM":             "HS256",
+		"EMAIL_VERIFICATION_SECRET": "test-email-verification-secret",
+		"DATA_EXPORT_SECRET":        "test-data-export-secret",
+		"MAILER":                    "log",
+		"MAIL_FROM":                 "no-reply@localhost",
+		"RATE_LIMIT_STORE":          "memory",
+		"APP_URL":                   "http://localhost:3000",
+		"WEBAUTHN_RP_ID":            "localhost",
+		"WEBAUTHN_RP_NAME":          "JobApplicationTracker-test",
+	} {
+		t.Setenv(key, value)
+	}
+}
+
+func TestRouterRoutesAreDocumented(t *testing.T) {
+	r, ok := newTestRouter(t).(chi.Routes)
+	if !ok {
+		t.Fatal("router does not list its routes")
+	}
+
+	missing, err := openapi.MissingRoutes(newOpenAPIDocument(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) > 0 {
+		t.Errorf("routes missing from the OpenAPI document: %v", missing)
+	}
+}
+
+func TestDocumentSchemas(t *testing.T) {
+	doc := newOpenAPIDocument()
+
+	schema := func(name string) *openapi.Schema {
+		registered, ok := doc.Components.Schemas[name]
+		if !ok {
+			t.Fatalf("schema %s is not registered", name)
+		}
+		return registered
+	}
+
+	resume := schema("ResumePostBody")
+	if name := resume.Properties["name"]; name == nil || name.Type != "string" || name.MinLength == nil || *name.MinLength != 1 {
+		t.Errorf("ResumePostBody.name = %+v, want a string with minLength 1", name)
+	}
+	if !slices.Equal(resume.Required, []string{"name", "note"}) {
+		t.Errorf("ResumePostBody.required = %v", resume.Required)
+	}
+
+	user := schema("User")
+	if verifiedAt := user.Properties["verified_at"]; verifiedAt == nil || !verifiedAt.Nullable || verifiedAt.Format != "date-time" {
+		t.Errorf("User.verified_at = %+v, want a nullable date-time", verifiedAt)
+	}
+	if role := user.Properties["role"]; role == nil || !slices.Equal(role.Enum, []string{"user", "admin"}) {
+		t.Errorf("User.role = %+v, want an enum of user and admin", role)
+	}
+
+	operation, ok := doc.Operation("PUT", "/api/v1/resumes/{resumeId}")
+	if !ok {
+		t.Fatal("PUT /api/v1/resumes/{resumeId} is not documented")
+	}
+	if body := operation.RequestBody.Content["application/json"].Schema; body.Ref != "#/components/schemas/ResumePostBody" {
+		t.Errorf("request body schema = %+v", body)
+	}
+}
@@ -1,15 +1,20 @@
 package assert
 
-import "log"
+import (
+	"log/slog"
+	"os"
+)
 
 func Assert(condition bool, msg string) {
 	if !condition {
-		log.Fatal(msg)
+		slog.Error(msg)
+		os.Exit(1)
 	}
 }
 
 func AssertError(err error, msg string) {
 	if err != nil {
-		log.Fatal(msg, err)
+		slog.Error(msg, "error", err)
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// UpdateProfileRequest changes the fields that are set.
+type UpdateProfileRequest struct {
+	FirstName *string `json:"first_name,omitempty"`
+	LastName  *string `json:"last_name,omitempty"`
+}
+
+func (c *Client) GetMe(ctx context.Context) (types.User, error) {
+	var user types.User
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/me", authenticated: true}, &user)
+	return user, err
+}
+
+func (c *Client) UpdateMe(ctx context.Context, body UpdateProfileRequest) (types.User, error) {
+	var user types.User
+	err := c.do(ctx, request{method: http.MethodPatch, path: "/api/v1/me", body: body, authenticated: true}, &user)
+	return user, err
+}
+
+// ChangePassword changes the password, which signs the user out everywhere.
+// The client continues with the new token pair from the response.
+func (c *Client) ChangePassword(ctx context.Context, currentPassword string, password string) (TokenResponse, error) {
+	body := struct {
+		CurrentPassword string `json:"current_password"`
+		Password        string `json:"password"`
+		PasswordVerify  string `json:"password_verify"`
+	}{CurrentPassword: currentPassword, Password: password, PasswordVerify: password}
+
+	var response TokenResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/me/password", body: body, authenticated: true}, &response)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	c.setTokens(response)
+	return response, nil
+}
+
+// ChangeEmail asks the server to email a confirmation link to the new
+// address. The email changes once ConfirmEmailChange is called with it.
+func (c *Client) ChangeEmail(ctx context.Context, email string, password string) (MessageResponse, error) {
+	body := struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{Email: email, Password: password}
+
+	var response MessageResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/me/email", body: body, authenticated: true}, &response)
+	return response, err
+}
+
+// ConfirmEmailChange changes the email with the token from a confirmation
+// link.
+func (c *Client) ConfirmEmailChange(ctx context.Context, token string) (MessageResponse, error) {
+	body := struct {
+		Token string `json:"token"`
+	}{Token: token}
+
+	var response MessageResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/auth/email/change/confirm", body: body}, &response)
+	return response, err
+}
+
+// DeleteMe deletes the user and all of their data. The code is only needed
+// with 2FA enabled. The client is signed out afterwards.
+func (c *Client) DeleteMe(ctx context.Context, password string, code string) error {
+	body := struct {
+		Password string `json:"password"`
+		Code     string `json:"code,omitempty"`
+	}{Password: password, Code: code}
+
+	err := c.do(ctx, request{method: http.MethodDelete, path: "/api/v1/me", body: body, authenticated: true}, nil)
+	if err != nil {
+		return err
+	}
+
+	c.clearTokens()
+	return nil
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// UserFilter narrows ListUsers. Empty fields match every user.
+type UserFilter struct {
+	// Search matches part of the email, first or last name.
+	Search   string
+	Role     string
+	Disabled *bool
+}
+
+type AdminUserResponse struct {
+	User  types.User      `json:"user"`
+	Usage types.UserUsage `json:"usage"`
+}
+
+// ListUsers lists users ordered by id. It requires an admin.
+func (c *Client) ListUsers(ctx context.Context, filter UserFilter, page Page) ([]types.User, error) {
+	query := page.query()
+	if filter.Search != "" {
+		query.Set("q", filter.Search)
+	}
+	if filter.Role != "" {
+		query.Set("role", filter.Role)
+	}
+	if filter.Disabled != nil {
+		query.Set("disabled", strconv.FormatBool(*filter.Disabled))
+	}
+
+	var users []types.User
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/admin/users", query: query, authenticated: true}, &users)
+	return users, err
+}
+
+// GetUser returns a user with their usage counts. It requires an admin.
+func (c *Client) GetUser(ctx context.Context, id int) (AdminUserResponse, error) {
+	var response AdminUserResponse
+	path := fmt.Sprintf("/api/v1/admin/users/%d", id)
+	err := c.do(ctx, request{method: http.MethodGet, path: path, authenticated: true}, &response)
+	return response, err
+}
+
+// DisableUser rejects the user's tokens and API keys until EnableUser.
+func (c *Client) DisableUser(ctx context.Context, id int) (types.User, error) {
+	var user types.User
+	path := fmt.Sprintf("/api/v1/admin/users/%d/disable", id)
+	err := c.do(ctx, request{method: http.MethodPost, path: path, authenticated: true}, &user)
+	return user, err
+}
+
+func (c *Client) EnableUser(ctx context.Context, id int) (types.User, error) {
+	var user types.User
+	path := fmt.Sprintf("/api/v1/admin/users/%d/enable", id)
+	err := c.do(ctx, request{method: http.MethodPost, path: path, authenticated: true}, &user)
+	return user, err
+}
+
+// ForceLogoutUser revokes every token issued to the user.
+func (c *Client) ForceLogoutUser(ctx context.Context, id int) error {
+	path := fmt.Sprintf("/api/v1/admin/users/%d/logout", id)
+	return c.do(ctx, request{method: http.MethodPost, path: path, authenticated: true}, nil)
+}
+
+// SetUserRole sets the role to types.RoleUser or types.RoleAdmin.
+func (c *Client) SetUserRole(ctx context.Context, id int, role string) (types.User, error) {
+	body := struct {
+		Role string `json:"role"`
+	}{Role: role}
+
+	var user types.User
+	path := fmt.Sprintf("/api/v1/admin/users/%d/role", id)
+	err := c.do(ctx, request{method: http.MethodPut, path: path, body: body, authenticated: true}, &user)
+	return user, err
+}
+
+// GetUsage counts users and records across the service. It requires an admin.
+func (c *Client) GetUsage(ctx context.Context) (types.Usage, error) {
+	var usage types.Usage
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/admin/usage", authenticated: true}, &usage)
+	return usage, err
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+type CreateApiKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type CreateApiKeyResponse struct {
+	ApiKey types.ApiKey `json:"api_key"`
+	// Key is the secret to pass to WithApiKey. It cannot be retrieved again.
+	Key string `json:"key"`
+}
+
+// ListApiKeys lists the user's API keys that were not revoked.
+func (c *Client) ListApiKeys(ctx context.Context) ([]types.ApiKey, error) {
+	var response []types.ApiKey
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/api-keys", authenticated: true}, &response)
+	return response, err
+}
+
+// CreateApiKey creates an API key with the scopes, see types.Scopes.
+func (c *Client) CreateApiKey(ctx context.Context, body CreateApiKeyRequest) (CreateApiKeyResponse, error) {
+	var response CreateApiKeyResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/api-keys", body: body, authenticated: true}, &response)
+	return response, err
+}
+
+// RevokeApiKey revokes an API key. Requests made with it fail afterwards.
+func (c *Client) RevokeApiKey(ctx context.Context, id int) error {
+	path := fmt.Sprintf("/api/v1/api-keys/%d", id)
+	return c.do(ctx, request{method: http.MethodDelete, path: path, authenticated: true}, nil)
+}
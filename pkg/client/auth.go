@@ -0,0 +1,208 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+type RegisterRequest struct {
+	FirstName      string `json:"first_name"`
+	LastName       string `json:"last_name"`
+	Email          string `json:"email"`
+	Password       string `json:"password"`
+	PasswordVerify string `json:"password_verify"`
+}
+
+type TokenResponse struct {
+	Token                 string    `json:"auth_token"`
+	ExpiresAt             time.Time `json:"expires_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+type RegisterResponse struct {
+	User types.User `json:"user"`
+	TokenResponse
+}
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type LoginResponse struct {
+	TokenResponse
+}
+
+// Register creates a user and authenticates the client as that user.
+func (c *Client) Register(ctx context.Context, body RegisterRequest) (RegisterResponse, error) {
+	var response RegisterResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/auth/register", body: body}, &response)
+	if err != nil {
+		return RegisterResponse{}, err
+	}
+
+	c.setTokens(response.TokenResponse)
+	return response, nil
+}
+
+// Login authenticates the client. The credentials are kept so the client can
+// log in again once its tokens can no longer be refreshed. For users with
+// two-factor authentication a *TwoFactorRequiredError is returned instead.
+func (c *Client) Login(ctx context.Context, email string, password string) (LoginResponse, error) {
+	body := LoginRequest{Email: email, Password: password}
+
+	// The login response holds either tokens or a two-factor challenge,
+	// which shares the expires_at field.
+	var response struct {
+		LoginResponse
+		ChallengeToken string `json:"challenge_token"`
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/auth/login", body: body}, &response)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	if response.ChallengeToken != "" {
+		return LoginResponse{}, &TwoFactorRequiredError{
+			ChallengeToken: response.ChallengeToken,
+			ExpiresAt:      response.ExpiresAt,
+		}
+	}
+
+	c.setTokens(response.TokenResponse)
+	c.mu.Lock()
+	c.credentials = &body
+	c.mu.Unlock()
+
+	return response.LoginResponse, nil
+}
+
+// LoginTwoFactor completes a login started with Login using a TOTP code or a
+// recovery code.
+func (c *Client) LoginTwoFactor(ctx context.Context, challengeToken string, code string) (LoginResponse, error) {
+	body := struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+	}{ChallengeToken: challengeToken, Code: code}
+
+	var response LoginResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/auth/login/2fa", body: body}, &response)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	c.setTokens(response.TokenResponse)
+	return response, nil
+}
+
+// Refresh exchanges the client's refresh token for a new token pair. The
+// used refresh token is no longer valid afterwards.
+func (c *Client) Refresh(ctx context.Context) (TokenResponse, error) {
+	body := struct {
+		RefreshToken string `json:"refresh_token"`
+	}{RefreshToken: c.RefreshToken()}
+
+	var response TokenResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/auth/refresh", body: body}, &response)
+	if err != nil {
+		if IsStatus(err, http.StatusUnauthorized) {
+			c.clearTokens()
+		}
+		return TokenResponse{}, err
+	}
+
+	c.setTokens(response)
+	return response, nil
+}
+
+// CheckAuth returns nil when the server accepts the client's token.
+func (c *Client) CheckAuth(ctx context.Context) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/api/v1/auth/check", authenticated: true}, nil)
+}
+
+// Logout revokes the client's access and refresh tokens and forgets them.
+func (c *Client) Logout(ctx context.Context) error {
+	body := struct {
+		RefreshToken string `json:"refresh_token,omitempty"`
+	}{RefreshToken: c.RefreshToken()}
+
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/auth/logout", body: body, authenticated: true}, nil)
+	if err != nil {
+		return err
+	}
+
+	c.clearTokens()
+	c.mu.Lock()
+	c.credentials = nil
+	c.mu.Unlock()
+
+	return nil
+}
+
+// LogoutAll revokes every token issued to the user, on every device, and
+// forgets the client's tokens.
+func (c *Client) LogoutAll(ctx context.Context) error {
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/auth/logout-all", authenticated: true}, nil)
+	if err != nil {
+		return err
+	}
+
+	c.clearTokens()
+	c.mu.Lock()
+	c.credentials = nil
+	c.mu.Unlock()
+
+	return nil
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// ForgotPassword asks the server to email a password reset link.
+func (c *Client) ForgotPassword(ctx context.Context, email string) (MessageResponse, error) {
+	body := struct {
+		Email string `json:"email"`
+	}{Email: email}
+
+	var response MessageResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/auth/password/forgot", body: body}, &response)
+	return response, err
+}
+
+// ResetPassword sets a new password with the token from a reset email.
+func (c *Client) ResetPassword(ctx context.Context, token string, password string) (MessageResponse, error) {
+	body := struct {
+		Token          string `json:"token"`
+		Password       string `json:"password"`
+		PasswordVerify string `json:"password_verify"`
+	}{Token: token, Password: password, PasswordVerify: password}
+
+	var response MessageResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/auth/password/reset", body: body}, &response)
+	return response, err
+}
+
+// VerifyEmail confirms an email address with the token from a verification
+// link. Tokens issued afterwards carry the verified state.
+func (c *Client) VerifyEmail(ctx context.Context, token string) (MessageResponse, error) {
+	body := struct {
+		Token string `json:"token"`
+	}{Token: token}
+
+	var response MessageResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/auth/email/verify", body: body}, &response)
+	return response, err
+}
+
+// ResendVerificationEmail asks the server to email a new verification link
+// to the authenticated user.
+func (c *Client) ResendVerificationEmail(ctx context.Context) (MessageResponse, error) {
+	var response MessageResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/auth/email/resend", authenticated: true}, &response)
+	return response, err
+}
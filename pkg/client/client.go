@@ -0,0 +1,298 @@
+// Package client is a typed Go client for the job application tracker API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// renewBefore is how long before its expiry an access token is renewed.
+const renewBefore = 30 * time.Second
+
+var NotAuthenticatedErr = errors.New("client has no way to obtain a new auth token")
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+
+	mu             sync.Mutex
+	token          string
+	tokenExpiresAt time.Time
+	refreshToken   string
+	credentials    *LoginRequest
+
+	// renewMu serializes token renewals so concurrent requests do not spend
+	// the same refresh token twice, which the server treats as token theft.
+	renewMu sync.Mutex
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken authenticates requests with an existing auth token.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithApiKey authenticates requests with a personal API key. Keys only work
+// for the routes their scopes allow.
+func WithApiKey(key string) Option {
+	return WithToken(key)
+}
+
+// WithRefreshToken lets the client obtain new auth tokens with an existing
+// refresh token.
+func WithRefreshToken(refreshToken string) Option {
+	return func(c *Client) {
+		c.refreshToken = refreshToken
+	}
+}
+
+// WithCredentials makes the client log in on the first authenticated request
+// and again whenever it cannot refresh its token.
+func WithCredentials(email string, password string) Option {
+	return func(c *Client) {
+		c.credentials = &LoginRequest{Email: email, Password: password}
+	}
+}
+
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
+}
+
+// Token returns the auth token currently used by the client.
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// RefreshToken returns the refresh token currently held by the client.
+func (c *Client) RefreshToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.refreshToken
+}
+
+func (c *Client) setTokens(tokens TokenResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = tokens.Token
+	c.tokenExpiresAt = tokens.ExpiresAt
+	c.refreshToken = tokens.RefreshToken
+}
+
+func (c *Client) clearTokens() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = ""
+	c.tokenExpiresAt = time.Time{}
+	c.refreshToken = ""
+}
+
+// envelope is the JsonResponse wrapper around every successful response.
+// Data holds a pointer to the value the payload is decoded into.
+type envelope struct {
+	Data any `json:"data"`
+}
+
+type request struct {
+	method        string
+	path          string
+	query         url.Values
+	body          any
+	authenticated bool
+	header        http.Header
+}
+
+// do sends the request and decodes the data of the JsonResponse envelope
+// into out. Authenticated requests that are rejected with 401 are retried
+// once after renewing the token, when the client is able to.
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	token := c.Token()
+	response, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode == http.StatusUnauthorized && req.authenticated && c.canRenew() {
+		response.Body.Close()
+
+		if err := c.renew(ctx, token); err != nil {
+			return err
+		}
+
+		response, err = c.send(ctx, req)
+		if err != nil {
+			return err
+		}
+	}
+	defer response.Body.Close()
+
+	return decodeResponse(response, out)
+}
+
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	if req.authenticated && c.needsRenewal() && c.canRenew() {
+		if err := c.renew(ctx, c.Token()); err != nil {
+			return nil, err
+		}
+	}
+
+	var body io.Reader
+	if req.body != nil {
+		encoded, err := json.Marshal(req.body)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	u := c.baseURL + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, req.method, u, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range req.header {
+		httpRequest.Header[key] = values
+	}
+	if req.body != nil {
+		httpRequest.Header.Set("Content-Type", "application/json")
+	}
+	httpRequest.Header.Set("Accept", "application/json")
+	if token := c.Token(); req.authenticated && token != "" && httpRequest.Header.Get("Authorization") == "" {
+		httpRequest.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return c.httpClient.Do(httpRequest)
+}
+
+func (c *Client) needsRenewal() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token == "" {
+		return true
+	}
+	return !c.tokenExpiresAt.IsZero() && time.Until(c.tokenExpiresAt) < renewBefore
+}
+
+func (c *Client) canRenew() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.refreshToken != "" || c.credentials != nil
+}
+
+// renew obtains a new auth token, preferring the refresh token over logging
+// in again. staleToken is the token the caller found unusable; if another
+// request already replaced it, nothing is done.
+func (c *Client) renew(ctx context.Context, staleToken string) error {
+	c.renewMu.Lock()
+	defer c.renewMu.Unlock()
+
+	if token := c.Token(); token != staleToken && token != "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	refreshToken := c.refreshToken
+	credentials := c.credentials
+	c.mu.Unlock()
+
+	var err error
+	if refreshToken != "" {
+		if _, err = c.Refresh(ctx); err == nil {
+			return nil
+		}
+	}
+
+	if credentials == nil {
+		if err == nil {
+			err = NotAuthenticatedErr
+		}
+		return err
+	}
+
+	_, err = c.Login(ctx, credentials.Email, credentials.Password)
+	return err
+}
+
+func decodeResponse(response *http.Response, out any) error {
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return newAPIError(response, body)
+	}
+
+	if out == nil || len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	if raw, ok := out.(*[]byte); ok {
+		*raw = body
+		return nil
+	}
+
+	if err := json.Unmarshal(body, &envelope{Data: out}); err != nil {
+		return fmt.Errorf("could not decode response: %w", err)
+	}
+
+	return nil
+}
+
+func newAPIError(response *http.Response, body []byte) error {
+	apiError := &APIError{
+		StatusCode: response.StatusCode,
+		RequestId:  response.Header.Get("X-Request-ID"),
+		body:       body,
+	}
+
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil {
+		apiError.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	if err := json.Unmarshal(body, apiError); err != nil || len(apiError.Errors) == 0 {
+		apiError.Errors = []string{http.StatusText(response.StatusCode)}
+	}
+
+	return apiError
+}
+
+// IsStatus reports whether err is an APIError with the status code.
+func IsStatus(err error, statusCode int) bool {
+	var apiError *APIError
+	return errors.As(err, &apiError) && apiError.StatusCode == statusCode
+}
@@ -0,0 +1,238 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/api"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db/dbtest"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+)
+
+// testPassword satisfies the default password policy.
+const testPassword = "correct horse battery staple"
+
+var testEnv = map[string]string{
+	"JWT_SECRET":                "test-jwt-secret",
+	"JWT_ALGORITHM":             "HS256",
+	"EMAIL_VERIFICATION_SECRET": "test-email-verification-secret",
+	"DATA_EXPORT_SECRET":        "test-data-export-secret",
+	"MAILER":                    "log",
+	"MAIL_FROM":                 "no-reply@localhost",
+	"RATE_LIMIT_STORE":          "memory",
+	"APP_URL":                   "http://localhost:3000",
+	"WEBAUTHN_RP_ID":            "localhost",
+	"WEBAUTHN_RP_NAME":          "JobApplicationTracker-test",
+	"ARGON2_MEMORY":             "1024",
+	"ARGON2_ITERATIONS":         "1",
+}
+
+var (
+	// testServer serves the API's router. Without a test database it can
+	// only answer requests that are rejected before reaching the database.
+	testServer  *httptest.Server
+	hasDatabase bool
+)
+
+func TestMain(m *testing.M) {
+	for key, value := range testEnv {
+		os.Setenv(key, value)
+	}
+
+	if err := service.JwtClient.InitJwtAuth(); err != nil {
+		log.Fatal(err)
+	}
+
+	url := os.Getenv(dbtest.DatabaseUrlEnv)
+	hasDatabase = url != ""
+	if !hasDatabase {
+		url = "postgres://localhost:1/unused?sslmode=disable"
+	}
+
+	connection := db.NewDbConnection(url)
+	if hasDatabase {
+		if err := connection.Migrate(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	handler, err := api.NewAPIServer("0", connection).Handler()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	testServer = httptest.NewServer(handler)
+	code := m.Run()
+	testServer.Close()
+	connection.DB.Close()
+
+	os.Exit(code)
+}
+
+func requireDatabase(t *testing.T) {
+	t.Helper()
+	if !hasDatabase {
+		t.Skip(dbtest.DatabaseUrlEnv + " is not set")
+	}
+}
+
+// registerTestUser registers a user with a unique email and returns a client
+// authenticated as them.
+func registerTestUser(t *testing.T) (*Client, RegisterRequest) {
+	t.Helper()
+	requireDatabase(t)
+
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+
+	body := RegisterRequest{
+		FirstName:      "Test",
+		LastName:       "User",
+		Email:          "client-" + hex.EncodeToString(suffix) + "@example.com",
+		Password:       testPassword,
+		PasswordVerify: testPassword,
+	}
+
+	c := New(testServer.URL)
+	if _, err := c.Register(context.Background(), body); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	return c, body
+}
+
+func TestLoginAndRefresh(t *testing.T) {
+	_, user := registerTestUser(t)
+	ctx := context.Background()
+
+	c := New(testServer.URL)
+	login, err := c.Login(ctx, user.Email, user.Password)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if login.Token == "" || login.RefreshToken == "" {
+		t.Fatalf("Login returned %+v", login)
+	}
+	if err := c.CheckAuth(ctx); err != nil {
+		t.Fatalf("CheckAuth: %v", err)
+	}
+
+	// A client holding only a refresh token obtains an access token before
+	// its first request.
+	refreshing := New(testServer.URL, WithRefreshToken(c.RefreshToken()))
+	if err := refreshing.CheckAuth(ctx); err != nil {
+		t.Fatalf("CheckAuth with a refresh token: %v", err)
+	}
+	if refreshing.Token() == "" || refreshing.RefreshToken() == login.RefreshToken {
+		t.Error("the client did not rotate its tokens")
+	}
+
+	// The refresh token was spent, so the first client logs in again with
+	// its credentials when its token is rejected.
+	c.mu.Lock()
+	c.token = "not-a-token"
+	c.mu.Unlock()
+	if err := c.CheckAuth(ctx); err != nil {
+		t.Fatalf("CheckAuth after the token was rejected: %v", err)
+	}
+	if c.Token() == "not-a-token" {
+		t.Error("the client did not renew its token")
+	}
+}
+
+func TestLoginWrongPassword(t *testing.T) {
+	_, user := registerTestUser(t)
+
+	_, err := New(testServer.URL).Login(context.Background(), user.Email, "wrong "+user.Password)
+	if !IsStatus(err, http.StatusUnauthorized) {
+		t.Fatalf("Login error = %v, want 401", err)
+	}
+}
+
+// tokenServer issues a new access token for every refresh and rejects all
+// other tokens, counting the refreshes.
+type tokenServer struct {
+	mu        sync.Mutex
+	token     string
+	refreshes atomic.Int32
+}
+
+func (s *tokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/api/v1/auth/refresh":
+		// Let concurrent requests pile up behind the renewal.
+		time.Sleep(10 * time.Millisecond)
+		n := s.refreshes.Add(1)
+
+		s.mu.Lock()
+		s.token = "token-" + strconv.Itoa(int(n))
+		token := s.token
+		s.mu.Unlock()
+
+		service.SendJsonResponse(w, TokenResponse{
+			Token:        token,
+			ExpiresAt:    time.Now().Add(time.Hour),
+			RefreshToken: "refresh-" + token,
+		}, http.StatusOK)
+	default:
+		s.mu.Lock()
+		valid := s.token != "" && r.Header.Get("Authorization") == "Bearer "+s.token
+		s.mu.Unlock()
+
+		if !valid {
+			service.SendErrorsResponse(w, []string{"Token is not valid"}, http.StatusUnauthorized)
+			return
+		}
+		service.SendJsonResponse(w, nil, http.StatusOK)
+	}
+}
+
+func TestConcurrentRequestsRefreshOnce(t *testing.T) {
+	tokens := &tokenServer{}
+	server := httptest.NewServer(tokens)
+	defer server.Close()
+
+	c := New(server.URL, WithToken("expired"), WithRefreshToken("refresh"))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- c.CheckAuth(context.Background())
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("CheckAuth: %v", err)
+		}
+	}
+	if n := tokens.refreshes.Load(); n != 1 {
+		t.Errorf("refreshed %d times, want once", n)
+	}
+	if c.RefreshToken() != "refresh-token-1" {
+		t.Errorf("refresh token = %q", c.RefreshToken())
+	}
+}
+
+func TestNotAuthenticated(t *testing.T) {
+	err := New(testServer.URL).CheckAuth(context.Background())
+	if !IsStatus(err, http.StatusUnauthorized) {
+		t.Fatalf("CheckAuth error = %v, want 401", err)
+	}
+}
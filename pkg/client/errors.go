@@ -0,0 +1,44 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// APIError is returned for every response with a non 2xx status code and
+// holds the decoded ErrorResponse body.
+type APIError struct {
+	StatusCode  int                `json:"status_code"`
+	Errors      []string           `json:"errors"`
+	FieldErrors []types.FieldError `json:"field_errors"`
+	RequestId   string             `json:"request_id"`
+	// RetryAfter is how long to wait before retrying responses with 429,
+	// from the Retry-After header.
+	RetryAfter time.Duration `json:"-"`
+
+	body []byte
+}
+
+func (e *APIError) Error() string {
+	message := fmt.Sprintf("api error %d: %s", e.StatusCode, strings.Join(e.Errors, "; "))
+	if e.RequestId != "" {
+		message += fmt.Sprintf(" (request id %s)", e.RequestId)
+	}
+
+	return message
+}
+
+// TwoFactorRequiredError is returned by Login for users with two-factor
+// authentication enabled. Pass the challenge token and a code to
+// LoginTwoFactor before it expires.
+type TwoFactorRequiredError struct {
+	ChallengeToken string
+	ExpiresAt      time.Time
+}
+
+func (e *TwoFactorRequiredError) Error() string {
+	return "two-factor authentication code is required"
+}
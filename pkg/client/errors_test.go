@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/middleware"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/ratelimit"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+)
+
+func TestAPIErrorFromErrorResponse(t *testing.T) {
+	_, err := New(testServer.URL).ListResumes(context.Background(), Page{})
+
+	var apiError *APIError
+	if !errors.As(err, &apiError) {
+		t.Fatalf("error = %v, want an *APIError", err)
+	}
+	if apiError.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status code = %d, want 401", apiError.StatusCode)
+	}
+	if len(apiError.Errors) == 0 || apiError.Errors[0] == http.StatusText(http.StatusUnauthorized) {
+		t.Errorf("errors = %v, want the messages of the response", apiError.Errors)
+	}
+	if apiError.RequestId == "" {
+		t.Error("request id was not decoded")
+	}
+}
+
+func TestAPIErrorFieldErrors(t *testing.T) {
+	_, err := New(testServer.URL).Register(context.Background(), RegisterRequest{
+		FirstName:      "Test",
+		LastName:       "User",
+		Email:          "not an email",
+		Password:       testPassword,
+		PasswordVerify: testPassword,
+	})
+
+	var apiError *APIError
+	if !errors.As(err, &apiError) {
+		t.Fatalf("error = %v, want an *APIError", err)
+	}
+	if apiError.StatusCode != http.StatusBadRequest {
+		t.Errorf("status code = %d, want 400", apiError.StatusCode)
+	}
+	if len(apiError.FieldErrors) != 1 || apiError.FieldErrors[0].Pointer != "/email" {
+		t.Errorf("field errors = %+v, want one for /email", apiError.FieldErrors)
+	}
+}
+
+func TestAPIErrorWithoutBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	err := New(server.URL).Liveness(context.Background())
+
+	var apiError *APIError
+	if !errors.As(err, &apiError) {
+		t.Fatalf("error = %v, want an *APIError", err)
+	}
+	if len(apiError.Errors) != 1 || apiError.Errors[0] != "Bad Gateway" {
+		t.Errorf("errors = %v, want the status text", apiError.Errors)
+	}
+}
+
+func TestAPIErrorRetryAfter(t *testing.T) {
+	limited := middleware.RateLimit(ratelimit.NewMemoryLimiter(), ratelimit.Rule{Name: "test", Limit: 1, Period: time.Minute})
+	server := httptest.NewServer(limited(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		service.SendJsonResponse(w, nil, http.StatusOK)
+	})))
+	defer server.Close()
+
+	c := New(server.URL)
+	if err := c.Liveness(context.Background()); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+
+	err := c.Liveness(context.Background())
+
+	var apiError *APIError
+	if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("error = %v, want a 429 *APIError", err)
+	}
+	if apiError.RetryAfter <= 0 || apiError.RetryAfter > time.Minute {
+		t.Errorf("retry after = %v", apiError.RetryAfter)
+	}
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+type DataExportResponse struct {
+	types.DataExport
+	// DownloadUrl is a path relative to the API's base URL, set once the
+	// export is ready. Pass it to DownloadDataExport.
+	DownloadUrl       *string    `json:"download_url"`
+	DownloadExpiresAt *time.Time `json:"download_expires_at"`
+}
+
+// CreateDataExport starts building an archive of the user's data. Poll
+// GetDataExport until its status is no longer pending.
+func (c *Client) CreateDataExport(ctx context.Context) (DataExportResponse, error) {
+	var response DataExportResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/me/export", authenticated: true}, &response)
+	return response, err
+}
+
+func (c *Client) GetDataExport(ctx context.Context, id int) (DataExportResponse, error) {
+	var response DataExportResponse
+	path := fmt.Sprintf("/api/v1/me/export/%d", id)
+	err := c.do(ctx, request{method: http.MethodGet, path: path, authenticated: true}, &response)
+	return response, err
+}
+
+// DownloadDataExport returns the ZIP archive of a ready export.
+func (c *Client) DownloadDataExport(ctx context.Context, export DataExportResponse) ([]byte, error) {
+	if export.DownloadUrl == nil {
+		return nil, errors.New("data export is not ready")
+	}
+
+	downloadUrl, err := url.Parse(*export.DownloadUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	var archive []byte
+	err = c.do(ctx, request{method: http.MethodGet, path: downloadUrl.Path, query: downloadUrl.Query()}, &archive)
+	return archive, err
+}
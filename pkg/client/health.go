@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+type Check struct {
+	Status  string   `json:"status"`
+	Error   string   `json:"error,omitempty"`
+	Pending []string `json:"pending,omitempty"`
+}
+
+type Readiness struct {
+	Status string           `json:"status"`
+	Checks map[string]Check `json:"checks"`
+}
+
+// Liveness returns nil when the server process is alive.
+func (c *Client) Liveness(ctx context.Context) error {
+	return c.do(ctx, request{method: http.MethodGet, path: "/healthz"}, nil)
+}
+
+// Readiness returns the readiness report. The report is also returned when
+// the server is not ready, together with an APIError carrying status 503.
+func (c *Client) Readiness(ctx context.Context) (Readiness, error) {
+	var body []byte
+	err := c.do(ctx, request{method: http.MethodGet, path: "/readyz"}, &body)
+
+	var apiError *APIError
+	if err != nil && !(errors.As(err, &apiError) && apiError.StatusCode == http.StatusServiceUnavailable) {
+		return Readiness{}, err
+	}
+
+	var readiness Readiness
+	if apiError != nil {
+		body = apiError.body
+	}
+	if decodeErr := json.Unmarshal(body, &envelope{Data: &readiness}); decodeErr != nil {
+		return Readiness{}, decodeErr
+	}
+
+	return readiness, err
+}
+
+// Metrics returns the Prometheus metrics exposed by the server.
+func (c *Client) Metrics(ctx context.Context, metricsToken string) (string, error) {
+	var body []byte
+	header := http.Header{"Authorization": {"Bearer " + metricsToken}}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/metrics", header: header}, &body)
+	return string(body), err
+}
+
+// OpenAPI returns the server's OpenAPI document.
+func (c *Client) OpenAPI(ctx context.Context) (json.RawMessage, error) {
+	var body []byte
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/openapi.json"}, &body)
+	return body, err
+}
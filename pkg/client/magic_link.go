@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// magicLinkDeviceHeader matches auth.MagicLinkDeviceHeader.
+const magicLinkDeviceHeader = "X-Magic-Link-Device"
+
+type MagicLinkResponse struct {
+	Message     string    `json:"message"`
+	DeviceToken string    `json:"device_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// RequestMagicLink asks the server to email a sign-in link. Keep the device
+// token of the response, the link only works together with it.
+func (c *Client) RequestMagicLink(ctx context.Context, email string) (MagicLinkResponse, error) {
+	body := struct {
+		Email string `json:"email"`
+	}{Email: email}
+
+	var response MagicLinkResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/auth/magic-link", body: body}, &response)
+	return response, err
+}
+
+// LoginWithMagicLink authenticates the client with the token from a sign-in
+// link and the device token returned by RequestMagicLink. For users with
+// two-factor authentication a *TwoFactorRequiredError is returned instead.
+func (c *Client) LoginWithMagicLink(ctx context.Context, token string, deviceToken string) (LoginResponse, error) {
+	var response struct {
+		LoginResponse
+		ChallengeToken string `json:"challenge_token"`
+	}
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/v1/auth/magic-link/verify",
+		query:  url.Values{"token": {token}},
+		header: http.Header{magicLinkDeviceHeader: {deviceToken}},
+	}, &response)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	if response.ChallengeToken != "" {
+		return LoginResponse{}, &TwoFactorRequiredError{
+			ChallengeToken: response.ChallengeToken,
+			ExpiresAt:      response.ExpiresAt,
+		}
+	}
+
+	c.setTokens(response.TokenResponse)
+	return response.LoginResponse, nil
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+type OAuthProvider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+type OAuthAuthorizeResponse struct {
+	AuthorizationUrl string    `json:"authorization_url"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+// OAuthProviders lists the providers users can sign in with.
+func (c *Client) OAuthProviders(ctx context.Context) ([]OAuthProvider, error) {
+	var response []OAuthProvider
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/auth/oauth/providers"}, &response)
+	return response, err
+}
+
+// AuthorizeOAuth starts signing in with the provider. Send the user to the
+// returned url and pass the code and state the provider redirects back with
+// to OAuthCallback.
+func (c *Client) AuthorizeOAuth(ctx context.Context, provider string) (OAuthAuthorizeResponse, error) {
+	var response OAuthAuthorizeResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: oauthPath(provider, "authorize")}, &response)
+	return response, err
+}
+
+// OAuthCallback finishes signing in with the provider and authenticates the
+// client. For users with two-factor authentication a
+// *TwoFactorRequiredError is returned instead.
+func (c *Client) OAuthCallback(ctx context.Context, provider string, code string, state string) (LoginResponse, error) {
+	body := struct {
+		Code  string `json:"code"`
+		State string `json:"state"`
+	}{Code: code, State: state}
+
+	var response struct {
+		LoginResponse
+		ChallengeToken string `json:"challenge_token"`
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: oauthPath(provider, "callback"), body: body}, &response)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	if response.ChallengeToken != "" {
+		return LoginResponse{}, &TwoFactorRequiredError{
+			ChallengeToken: response.ChallengeToken,
+			ExpiresAt:      response.ExpiresAt,
+		}
+	}
+
+	c.setTokens(response.TokenResponse)
+	return response.LoginResponse, nil
+}
+
+func oauthPath(provider string, action string) string {
+	return fmt.Sprintf("/api/v1/auth/oauth/%s/%s", url.PathEscape(provider), action)
+}
+
+// Identities lists the provider identities linked to the user.
+func (c *Client) Identities(ctx context.Context) ([]types.Identity, error) {
+	var response []types.Identity
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/auth/identities", authenticated: true}, &response)
+	return response, err
+}
+
+// DeleteIdentity unlinks a provider identity from the user.
+func (c *Client) DeleteIdentity(ctx context.Context, id int) error {
+	path := fmt.Sprintf("/api/v1/auth/identities/%d", id)
+	return c.do(ctx, request{method: http.MethodDelete, path: path, authenticated: true}, nil)
+}
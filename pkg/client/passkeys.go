@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/webauthn"
+)
+
+// BeginPasskeyRegistration returns the options to create a passkey with, on
+// an authenticator like webauthntest.Authenticator. The resulting credential
+// is passed to FinishPasskeyRegistration.
+func (c *Client) BeginPasskeyRegistration(ctx context.Context) (webauthn.CreationOptions, error) {
+	var response webauthn.CreationOptions
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/me/passkeys/register/begin", authenticated: true}, &response)
+	return response, err
+}
+
+// FinishPasskeyRegistration adds the credential as a passkey of the user. An
+// empty name lets the server name it after the client.
+func (c *Client) FinishPasskeyRegistration(ctx context.Context, name string, credential webauthn.RegistrationCredential) (types.Passkey, error) {
+	body := struct {
+		Name       *string                         `json:"name,omitempty"`
+		Credential webauthn.RegistrationCredential `json:"credential"`
+	}{Credential: credential}
+	if name != "" {
+		body.Name = &name
+	}
+
+	var response types.Passkey
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/me/passkeys/register/finish", body: body, authenticated: true}, &response)
+	return response, err
+}
+
+func (c *Client) Passkeys(ctx context.Context) ([]types.Passkey, error) {
+	var response []types.Passkey
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/me/passkeys", authenticated: true}, &response)
+	return response, err
+}
+
+func (c *Client) DeletePasskey(ctx context.Context, id int) error {
+	path := fmt.Sprintf("/api/v1/me/passkeys/%d", id)
+	return c.do(ctx, request{method: http.MethodDelete, path: path, authenticated: true}, nil)
+}
+
+// BeginPasskeyLogin returns the options to sign in with a passkey. The
+// credential the authenticator returns is passed to LoginWithPasskey.
+func (c *Client) BeginPasskeyLogin(ctx context.Context) (webauthn.RequestOptions, error) {
+	var response webauthn.RequestOptions
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/auth/passkeys/login/begin"}, &response)
+	return response, err
+}
+
+// LoginWithPasskey authenticates the client with a passkey credential.
+func (c *Client) LoginWithPasskey(ctx context.Context, credential webauthn.AuthenticationCredential) (LoginResponse, error) {
+	body := struct {
+		Credential webauthn.AuthenticationCredential `json:"credential"`
+	}{Credential: credential}
+
+	var response LoginResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/auth/passkeys/login/finish", body: body}, &response)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	c.setTokens(response.TokenResponse)
+	return response, nil
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+type ResumeRequest struct {
+	Name string `json:"name"`
+	Note string `json:"note"`
+}
+
+// Page selects a page of a list endpoint. Zero values use the server
+// defaults.
+type Page struct {
+	Page   int
+	Count  int
+	Offset int
+}
+
+func (p Page) query() url.Values {
+	query := url.Values{}
+	if p.Page > 0 {
+		query.Set("page", strconv.Itoa(p.Page))
+	}
+	if p.Count > 0 {
+		query.Set("count", strconv.Itoa(p.Count))
+	}
+	if p.Offset > 0 {
+		query.Set("offset", strconv.Itoa(p.Offset))
+	}
+	return query
+}
+
+func (c *Client) ListResumes(ctx context.Context, page Page) ([]types.Resume, error) {
+	var resumes []types.Resume
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/resumes", query: page.query(), authenticated: true}, &resumes)
+	return resumes, err
+}
+
+// AllResumes iterates over every resume of the user, fetching pageSize
+// resumes, at most 100, per request. Iteration stops at the first error.
+func (c *Client) AllResumes(ctx context.Context, pageSize int) iter.Seq2[types.Resume, error] {
+	return paginate(ctx, pageSize, c.ListResumes)
+}
+
+func (c *Client) GetResume(ctx context.Context, id int) (types.Resume, error) {
+	var resume types.Resume
+	err := c.do(ctx, request{method: http.MethodGet, path: resumePath(id), authenticated: true}, &resume)
+	return resume, err
+}
+
+func (c *Client) CreateResume(ctx context.Context, body ResumeRequest) (types.Resume, error) {
+	var resume types.Resume
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/resumes", body: body, authenticated: true}, &resume)
+	return resume, err
+}
+
+func (c *Client) UpdateResume(ctx context.Context, id int, body ResumeRequest) (types.Resume, error) {
+	var resume types.Resume
+	err := c.do(ctx, request{method: http.MethodPut, path: resumePath(id), body: body, authenticated: true}, &resume)
+	return resume, err
+}
+
+func (c *Client) DeleteResume(ctx context.Context, id int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: resumePath(id), authenticated: true}, nil)
+}
+
+func resumePath(id int) string {
+	return fmt.Sprintf("/api/v1/resumes/%d", id)
+}
+
+// maxPageSize is the largest count the API returns in a page.
+const maxPageSize = 100
+
+// paginate turns a list method into an iterator over all of its records.
+// Page sizes are clamped like the API does, so a short page is the last one.
+func paginate[T any](ctx context.Context, pageSize int, list func(context.Context, Page) ([]T, error)) iter.Seq2[T, error] {
+	if pageSize <= 0 || pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	return func(yield func(T, error) bool) {
+		for page := 1; ; page++ {
+			records, err := list(ctx, Page{Page: page, Count: pageSize})
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			for _, record := range records {
+				if !yield(record, nil) {
+					return
+				}
+			}
+
+			if len(records) < pageSize {
+				return
+			}
+		}
+	}
+}
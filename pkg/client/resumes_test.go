@@ -0,0 +1,171 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+func TestResumes(t *testing.T) {
+	c, _ := registerTestUser(t)
+	ctx := context.Background()
+
+	created, err := c.CreateResume(ctx, ResumeRequest{Name: "Backend", Note: "Go"})
+	if err != nil {
+		t.Fatalf("CreateResume: %v", err)
+	}
+	if created.Id == 0 || created.Name != "Backend" || created.Note != "Go" {
+		t.Fatalf("CreateResume returned %+v", created)
+	}
+
+	updated, err := c.UpdateResume(ctx, created.Id, ResumeRequest{Name: "Frontend", Note: "React"})
+	if err != nil {
+		t.Fatalf("UpdateResume: %v", err)
+	}
+	if updated.Id != created.Id || updated.Name != "Frontend" || updated.Note != "React" {
+		t.Errorf("UpdateResume returned %+v", updated)
+	}
+
+	fetched, err := c.GetResume(ctx, created.Id)
+	if err != nil {
+		t.Fatalf("GetResume: %v", err)
+	}
+	if fetched.Name != "Frontend" {
+		t.Errorf("GetResume returned %+v", fetched)
+	}
+
+	if err := c.DeleteResume(ctx, created.Id); err != nil {
+		t.Fatalf("DeleteResume: %v", err)
+	}
+
+	_, err = c.GetResume(ctx, created.Id)
+	if !IsStatus(err, http.StatusNotFound) {
+		t.Errorf("GetResume after delete error = %v, want 404", err)
+	}
+
+	_, err = c.UpdateResume(ctx, created.Id, ResumeRequest{Name: "Gone", Note: ""})
+	if !IsStatus(err, http.StatusNotFound) {
+		t.Errorf("UpdateResume after delete error = %v, want 404", err)
+	}
+}
+
+func TestResumePagination(t *testing.T) {
+	c, _ := registerTestUser(t)
+	ctx := context.Background()
+
+	for i := range 5 {
+		if _, err := c.CreateResume(ctx, ResumeRequest{Name: "Resume " + strconv.Itoa(i), Note: ""}); err != nil {
+			t.Fatalf("CreateResume: %v", err)
+		}
+	}
+
+	page, err := c.ListResumes(ctx, Page{Page: 2, Count: 2})
+	if err != nil {
+		t.Fatalf("ListResumes: %v", err)
+	}
+	if len(page) != 2 || page[0].Name != "Resume 2" || page[1].Name != "Resume 1" {
+		t.Errorf("second page = %+v, want resumes 2 and 1", page)
+	}
+
+	names := make([]string, 0)
+	for resume, err := range c.AllResumes(ctx, 2) {
+		if err != nil {
+			t.Fatalf("AllResumes: %v", err)
+		}
+		names = append(names, resume.Name)
+	}
+	if len(names) != 5 || names[0] != "Resume 4" || names[4] != "Resume 0" {
+		t.Errorf("AllResumes = %v, want all five, newest first", names)
+	}
+}
+
+func TestPaginateStopsAtShortPage(t *testing.T) {
+	requested := make([]Page, 0)
+	list := func(ctx context.Context, page Page) ([]int, error) {
+		requested = append(requested, page)
+		if page.Page < 3 {
+			return []int{1, 2}, nil
+		}
+		return []int{3}, nil
+	}
+
+	count := 0
+	for _, err := range paginate(context.Background(), 2, list) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		count++
+	}
+
+	if count != 5 || len(requested) != 3 {
+		t.Errorf("got %d records in %d requests, want 5 in 3", count, len(requested))
+	}
+	if requested[2] != (Page{Page: 3, Count: 2}) {
+		t.Errorf("last page = %+v", requested[2])
+	}
+}
+
+func TestAllResumesWithPagesLargerThanTheServerReturns(t *testing.T) {
+	const total = 250
+
+	// The server clamps the count of a page to 100 like the API.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+		count = min(count, 100)
+
+		resumes := make([]types.Resume, 0, count)
+		for id := (page-1)*count + 1; id <= min(page*count, total); id++ {
+			resume := types.Resume{}
+			resume.Id = id
+			resumes = append(resumes, resume)
+		}
+		service.SendJsonResponse(w, resumes, http.StatusOK)
+	}))
+	defer server.Close()
+
+	count := 0
+	for resume, err := range New(server.URL).AllResumes(context.Background(), 500) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		count++
+		if resume.Id != count {
+			t.Fatalf("resume %d has id %d", count, resume.Id)
+		}
+	}
+
+	if count != total {
+		t.Errorf("got %d resumes, want %d", count, total)
+	}
+}
+
+func TestPaginateStopsAtError(t *testing.T) {
+	failure := errors.New("failure")
+	list := func(ctx context.Context, page Page) ([]int, error) {
+		if page.Page == 2 {
+			return nil, failure
+		}
+		return []int{1, 2}, nil
+	}
+
+	var last error
+	count := 0
+	for _, err := range paginate(context.Background(), 2, list) {
+		if err != nil {
+			last = err
+			continue
+		}
+		count++
+	}
+
+	if count != 2 || !errors.Is(last, failure) {
+		t.Errorf("got %d records and error %v, want 2 and the list error", count, last)
+	}
+}
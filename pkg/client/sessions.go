@@ -0,0 +1,24 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// Sessions lists the devices the user is logged in on. The session of the
+// client's token is marked as current.
+func (c *Client) Sessions(ctx context.Context) ([]types.Session, error) {
+	var response []types.Session
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/v1/me/sessions", authenticated: true}, &response)
+	return response, err
+}
+
+// RevokeSession signs a session out. Revoking the current session logs the
+// client out like Logout does, without clearing its tokens.
+func (c *Client) RevokeSession(ctx context.Context, id int) error {
+	path := fmt.Sprintf("/api/v1/me/sessions/%d", id)
+	return c.do(ctx, request{method: http.MethodDelete, path: path, authenticated: true}, nil)
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+type TotpSetupResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthUri string `json:"otpauth_uri"`
+	// QrCode is a PNG data URI of OtpauthUri.
+	QrCode string `json:"qr_code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// SetupTotp creates a new TOTP secret for the user. It is only used for
+// logins after it is confirmed with EnableTotp.
+func (c *Client) SetupTotp(ctx context.Context) (TotpSetupResponse, error) {
+	var response TotpSetupResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/auth/2fa/setup", authenticated: true}, &response)
+	return response, err
+}
+
+// EnableTotp enables two-factor authentication with a code generated from
+// the secret returned by SetupTotp. The recovery codes are only returned once.
+func (c *Client) EnableTotp(ctx context.Context, code string) (RecoveryCodesResponse, error) {
+	body := struct {
+		Code string `json:"code"`
+	}{Code: code}
+
+	var response RecoveryCodesResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/auth/2fa/enable", body: body, authenticated: true}, &response)
+	return response, err
+}
+
+// DisableTotp disables two-factor authentication. The code can be a TOTP
+// code or a recovery code.
+func (c *Client) DisableTotp(ctx context.Context, password string, code string) (MessageResponse, error) {
+	body := struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}{Password: password, Code: code}
+
+	var response MessageResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/auth/2fa/disable", body: body, authenticated: true}, &response)
+	return response, err
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the user.
+func (c *Client) RegenerateRecoveryCodes(ctx context.Context, code string) (RecoveryCodesResponse, error) {
+	body := struct {
+		Code string `json:"code"`
+	}{Code: code}
+
+	var response RecoveryCodesResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/v1/auth/2fa/recovery-codes", body: body, authenticated: true}, &response)
+	return response, err
+}
@@ -0,0 +1,60 @@
+// Package dbtest connects tests to the Postgres database named by
+// TEST_DATABASE_URL. Tests using it are skipped when it is not set.
+package dbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+)
+
+const DatabaseUrlEnv = "TEST_DATABASE_URL"
+
+// Connect returns a connection to the migrated test database, closed when the
+// test ends.
+func Connect(t testing.TB) *db.DbConnection {
+	t.Helper()
+
+	url := os.Getenv(DatabaseUrlEnv)
+	if url == "" {
+		t.Skip(DatabaseUrlEnv + " is not set")
+	}
+
+	connection := db.NewDbConnection(url)
+	t.Cleanup(func() { connection.DB.Close() })
+
+	if err := connection.Migrate(context.Background()); err != nil {
+		t.Fatalf("migrating the test database: %v", err)
+	}
+
+	return connection
+}
+
+// CreateUser inserts a user with a unique email, deleted with everything it
+// owns when the test ends, and returns its id.
+func CreateUser(t testing.TB, connection *db.DbConnection) int {
+	t.Helper()
+
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+
+	var id int
+	err := connection.DB.QueryRowContext(context.Background(), `
+        INSERT INTO users (first_name, last_name, email, password_hash)
+        VALUES ('Test', 'User', $1, '') RETURNING id`,
+		"test-"+hex.EncodeToString(suffix)+"@example.com",
+	).Scan(&id)
+	if err != nil {
+		t.Fatalf("creating a test user: %v", err)
+	}
+
+	t.Cleanup(func() {
+		connection.DB.ExecContext(context.Background(), `DELETE FROM users WHERE id = $1`, id)
+	})
+
+	return id
+}
@@ -1,7 +1,10 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
 )
 
 type Scannable interface {
@@ -13,17 +16,19 @@ type ScannableFunction[T any] interface {
 }
 
 type GenericStoreFunctions[T any] interface {
-	GetRecords(args ...any) ([]T, error)
-	GetRecord(args ...any) (T, error)
-	CreateRecord(args ...any) (T, error)
-	UpdateRecord(args ...any) (T, error)
-	DeleteRecord(id int) error
+	GetRecords(ctx context.Context, args ...any) ([]T, error)
+	GetRecord(ctx context.Context, args ...any) (T, error)
+	CreateRecord(ctx context.Context, args ...any) (T, error)
+	UpdateRecord(ctx context.Context, args ...any) (T, error)
+	DeleteRecord(ctx context.Context, args ...any) error
 }
 
 type GenericStore[T any] struct {
 	GenericStoreFunctions[T]
 	Scanner ScannableFunction[T]
 
+	TableName string
+
 	SelectManyQuery string
 	SelectQuery     string
 	CreateQuery     string
@@ -33,40 +38,59 @@ type GenericStore[T any] struct {
 	Db *sql.DB
 }
 
-func (s *GenericStore[T]) GetRecords(args ...any) ([]T, error) {
-	rows, err := s.Db.Query(s.SelectManyQuery, args...)
+func (s *GenericStore[T]) GetRecords(ctx context.Context, args ...any) (records []T, err error) {
+	ctx, span := StartQuerySpan(ctx, s.TableName+".select_many", s.SelectManyQuery)
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.Db.QueryContext(ctx, s.SelectManyQuery, args...)
 	if err != nil {
 		return []T{}, err
 	}
+	defer rows.Close()
 
-	var records = make([]T, 0)
+	records = make([]T, 0)
 	for rows.Next() {
-		r, _ := s.Scanner.Scan(rows)
+		r, err := s.Scanner.Scan(rows)
+		if err != nil {
+			return []T{}, err
+		}
 		records = append(records, r)
 	}
 
-	return records, nil
+	return records, rows.Err()
 }
 
-func (s *GenericStore[T]) GetRecord(args ...any) (T, error) {
-	row := s.Db.QueryRow(s.SelectQuery, args...)
-	record, err := s.Scanner.Scan(row)
+func (s *GenericStore[T]) GetRecord(ctx context.Context, args ...any) (record T, err error) {
+	ctx, span := StartQuerySpan(ctx, s.TableName+".select", s.SelectQuery)
+	defer func() { tracing.End(span, err) }()
+
+	row := s.Db.QueryRowContext(ctx, s.SelectQuery, args...)
+	record, err = s.Scanner.Scan(row)
 	return record, err
 }
 
-func (s *GenericStore[T]) CreateRecord(args ...any) (T, error) {
-	row := s.Db.QueryRow(s.CreateQuery, args...)
-	record, err := s.Scanner.Scan(row)
+func (s *GenericStore[T]) CreateRecord(ctx context.Context, args ...any) (record T, err error) {
+	ctx, span := StartQuerySpan(ctx, s.TableName+".insert", s.CreateQuery)
+	defer func() { tracing.End(span, err) }()
+
+	row := s.Db.QueryRowContext(ctx, s.CreateQuery, args...)
+	record, err = s.Scanner.Scan(row)
 	return record, err
 }
 
-func (s *GenericStore[T]) UpdateRecord(args ...any) (T, error) {
-	row := s.Db.QueryRow(s.UpdateQuery, args...)
-	record, err := s.Scanner.Scan(row)
+func (s *GenericStore[T]) UpdateRecord(ctx context.Context, args ...any) (record T, err error) {
+	ctx, span := StartQuerySpan(ctx, s.TableName+".update", s.UpdateQuery)
+	defer func() { tracing.End(span, err) }()
+
+	row := s.Db.QueryRowContext(ctx, s.UpdateQuery, args...)
+	record, err = s.Scanner.Scan(row)
 	return record, err
 }
 
-func (s *GenericStore[T]) DeleteRecord(id int) error {
-	_, err := s.Db.Exec(s.DeleteQuery, id)
+func (s *GenericStore[T]) DeleteRecord(ctx context.Context, args ...any) (err error) {
+	ctx, span := StartQuerySpan(ctx, s.TableName+".delete", s.DeleteQuery)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.Db.ExecContext(ctx, s.DeleteQuery, args...)
 	return err
 }
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+type migration struct {
+	Version string
+	Query   string
+}
+
+func loadMigrations() ([]migration, error) {
+	names, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	migrations := make([]migration, 0, len(names))
+	for _, name := range names {
+		query, err := migrationFiles.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		version := strings.TrimSuffix(strings.TrimPrefix(name, "migrations/"), ".sql")
+		migrations = append(migrations, migration{Version: version, Query: string(query)})
+	}
+
+	return migrations, nil
+}
+
+func (c *DbConnection) ensureMigrationsTable(ctx context.Context) error {
+	_, err := c.DB.ExecContext(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version TEXT PRIMARY KEY,
+            applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+        )`)
+	return err
+}
+
+func (c *DbConnection) appliedMigrations(ctx context.Context) (map[string]bool, error) {
+	rows, err := c.DB.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+
+	return applied, rows.Err()
+}
+
+// Migrate applies every embedded migration that has not been applied yet,
+// each one in its own transaction.
+func (c *DbConnection) Migrate(ctx context.Context) error {
+	if err := c.ensureMigrationsTable(ctx); err != nil {
+		return err
+	}
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	applied, err := c.appliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+
+		transaction, err := c.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		if _, err := transaction.ExecContext(ctx, m.Query); err != nil {
+			transaction.Rollback()
+			return err
+		}
+
+		if _, err := transaction.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, m.Version); err != nil {
+			transaction.Rollback()
+			return err
+		}
+
+		if err := transaction.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PendingMigrations returns the versions of embedded migrations that have not
+// been applied to the database yet.
+func (c *DbConnection) PendingMigrations(ctx context.Context) ([]string, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	err = c.DB.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]bool)
+	if exists {
+		applied, err = c.appliedMigrations(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pending := make([]string, 0)
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			pending = append(pending, m.Version)
+		}
+	}
+
+	return pending, nil
+}
@@ -0,0 +1,6 @@
+-- The first admin has to be promoted by hand:
+--   UPDATE users SET role = 'admin' WHERE email = '...';
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin'));
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMPTZ;
+
+CREATE INDEX users_role_idx ON users (role);
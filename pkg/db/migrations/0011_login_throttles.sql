@@ -0,0 +1,8 @@
+-- Failed logins per key, "account:<email>" or "ip:<address>". Emails are
+-- tracked whether or not a user has them, so responses do not differ.
+CREATE TABLE login_throttles (
+    key TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    blocked_until TIMESTAMPTZ
+);
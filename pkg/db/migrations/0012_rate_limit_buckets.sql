@@ -0,0 +1,11 @@
+-- Token buckets of the Postgres rate limiter, keyed by "<rule>:<key>".
+-- full_at is a bound on when the bucket is full again, after which the row
+-- can be removed.
+CREATE TABLE rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    full_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX rate_limit_buckets_full_at_idx ON rate_limit_buckets (full_at);
@@ -0,0 +1,13 @@
+-- Keys access tokens are signed with. A key is published in the JWKS from
+-- its creation, signs tokens from activates_at until retires_at and is kept
+-- for verification until expires_at. Private keys are encrypted.
+CREATE TABLE signing_keys (
+    kid TEXT PRIMARY KEY,
+    algorithm TEXT NOT NULL,
+    private_key BYTEA NOT NULL,
+    public_key BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    activates_at TIMESTAMPTZ NOT NULL UNIQUE,
+    retires_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
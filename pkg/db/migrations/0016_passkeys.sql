@@ -0,0 +1,36 @@
+-- Users get a random WebAuthn user handle when they register their first
+-- passkey. Authenticators return it on login, and keep one passkey per
+-- handle, so registering again on the same device replaces the old passkey.
+ALTER TABLE users ADD COLUMN webauthn_user_handle BYTEA UNIQUE;
+
+CREATE TABLE passkeys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    credential_id BYTEA NOT NULL UNIQUE,
+    -- COSE encoded.
+    public_key BYTEA NOT NULL,
+    algorithm INTEGER NOT NULL,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    aaguid BYTEA NOT NULL,
+    attestation_format TEXT NOT NULL,
+    transports TEXT[] NOT NULL DEFAULT '{}',
+    backup_eligible BOOLEAN NOT NULL DEFAULT FALSE,
+    backed_up BOOLEAN NOT NULL DEFAULT FALSE,
+    last_used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX passkeys_user_id_idx ON passkeys (user_id);
+
+-- A challenge is issued at the start of a ceremony and spent when it
+-- finishes. Registration challenges belong to the user registering; login
+-- challenges to nobody, as the passkey picked tells who logs in.
+CREATE TABLE webauthn_challenges (
+    id SERIAL PRIMARY KEY,
+    challenge_hash TEXT NOT NULL UNIQUE,
+    ceremony TEXT NOT NULL,
+    user_id INTEGER REFERENCES users (id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
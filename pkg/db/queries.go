@@ -34,8 +34,8 @@ func CreateCreateQuery(table string, insertFields []string, allFields []string)
 func CreateUpdateQuery(table string, fields []string, allFields []string, whereClause string) string {
 	return fmt.Sprintf(`
         UPDATE %s
-        SET %s, updated_at = DEFAULT,
-		%s $1 RETURNING %s`,
+        SET %s, updated_at = DEFAULT
+        %s RETURNING %s`,
 		table,
 		strings.Join(getSetArgs(2, fields), ", "),
 		whereClause,
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+// normalize collapses the whitespace the query templates are indented with.
+func normalize(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+func TestCreateUpdateQuery(t *testing.T) {
+	query := CreateUpdateQuery("resumes", []string{"name", "note"}, []string{"id", "name", "note"}, "WHERE id = $1 AND user_id = $4")
+
+	want := "UPDATE resumes SET name = $2, note = $3, updated_at = DEFAULT WHERE id = $1 AND user_id = $4 RETURNING id, name, note"
+	if got := normalize(query); got != want {
+		t.Errorf("query = %q, want %q", got, want)
+	}
+}
+
+func TestCreateCreateQuery(t *testing.T) {
+	query := CreateCreateQuery("resumes", []string{"user_id", "name"}, []string{"id", "user_id", "name"})
+
+	want := "INSERT INTO resumes (user_id, name) VALUES ($1, $2) RETURNING id, user_id, name"
+	if got := normalize(query); got != want {
+		t.Errorf("query = %q, want %q", got, want)
+	}
+}
+
+func TestCreateSelectQuery(t *testing.T) {
+	query := CreateSelectQuery("resumes", []string{"id", "name"}, "WHERE id = $1 AND user_id = $2")
+
+	want := "SELECT id, name FROM resumes WHERE id = $1 AND user_id = $2"
+	if got := normalize(query); got != want {
+		t.Errorf("query = %q, want %q", got, want)
+	}
+}
@@ -1,11 +1,131 @@
 package db
 
 import (
+	"context"
+	"time"
+
 	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
 )
 
 type AuthStore interface {
-	GetInternalUserById(id int) (types.InternalUser, error)
-	GetInternalUserByEmail(email string) (types.InternalUser, error)
-	CreateUser(user types.InternalUser) (types.InternalUser, error)
+	GetInternalUserById(ctx context.Context, id int) (types.InternalUser, error)
+	GetInternalUserByEmail(ctx context.Context, email string) (types.InternalUser, error)
+	CreateUser(ctx context.Context, user types.InternalUser) (types.InternalUser, error)
+	UpdateUserProfile(ctx context.Context, id int, firstName string, lastName string) (types.InternalUser, error)
+	UpdatePassword(ctx context.Context, id int, passwordHash string) error
+	RehashPassword(ctx context.Context, id int, oldHash string, newHash string) error
+	ChangeEmail(ctx context.Context, id int, oldEmail string, newEmail string) (bool, error)
+	DeleteUser(ctx context.Context, id int) (bool, error)
+
+	CreateRefreshToken(ctx context.Context, token types.RefreshToken) (types.RefreshToken, error)
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (types.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, usedId int, next types.RefreshToken) (types.RefreshToken, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyId string) error
+	RevokeUserRefreshTokens(ctx context.Context, userId int) error
+
+	TouchSession(ctx context.Context, session types.Session) (types.Session, error)
+	GetUserSessions(ctx context.Context, userId int) ([]types.Session, error)
+	RevokeSession(ctx context.Context, userId int, id int) (bool, error)
+
+	CreatePasswordResetToken(ctx context.Context, token types.PasswordResetToken) error
+	GetPasswordResetUser(ctx context.Context, tokenHash string) (types.InternalUser, error)
+	ResetPassword(ctx context.Context, tokenHash string, passwordHash string) (int, error)
+
+	CreateMagicLink(ctx context.Context, link types.MagicLink) error
+	ConsumeMagicLink(ctx context.Context, tokenHash string, deviceHash string) (int, error)
+
+	GetWebAuthnUserHandle(ctx context.Context, userId int, newHandle []byte) ([]byte, error)
+	CreateWebAuthnChallenge(ctx context.Context, challenge types.WebAuthnChallenge) error
+	ConsumeWebAuthnChallenge(ctx context.Context, challengeHash string, ceremony string, userId *int) error
+	CreatePasskey(ctx context.Context, passkey types.Passkey) (types.Passkey, error)
+	GetUserPasskeys(ctx context.Context, userId int) ([]types.Passkey, error)
+	GetPasskeyByCredentialId(ctx context.Context, credentialId []byte) (types.Passkey, []byte, error)
+	UsePasskey(ctx context.Context, id int, oldSignCount uint32, newSignCount uint32, backedUp bool) (bool, error)
+	DeletePasskey(ctx context.Context, userId int, id int) (bool, error)
+
+	MarkEmailVerified(ctx context.Context, userId int, email string) (bool, error)
+	ClaimVerificationEmail(ctx context.Context, userId int, interval time.Duration) (bool, error)
+
+	SetPendingTotpSecret(ctx context.Context, userId int, secret string) (bool, error)
+	EnableTotp(ctx context.Context, userId int, step int64, recoveryCodeHashes []string) (bool, error)
+	DisableTotp(ctx context.Context, userId int) error
+	ClaimTotpStep(ctx context.Context, userId int, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userId int, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userId int, codeHash string) (bool, error)
+	CreateTwoFactorChallenge(ctx context.Context, challenge types.TwoFactorChallenge) error
+	AttemptTwoFactorChallenge(ctx context.Context, tokenHash string, maxAttempts int) (types.TwoFactorChallenge, error)
+	CompleteTwoFactorChallenge(ctx context.Context, id int) (bool, error)
+
+	GetLoginBlockedUntil(ctx context.Context, keys []string) (time.Time, error)
+	RecordLoginFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	BlockLogin(ctx context.Context, key string, until time.Time) error
+	ClearLoginFailures(ctx context.Context, key string) error
+
+	CreateOAuthState(ctx context.Context, state types.OAuthState) error
+	ConsumeOAuthState(ctx context.Context, stateHash string, provider string) (types.OAuthState, error)
+	GetIdentity(ctx context.Context, provider string, subject string) (types.Identity, error)
+	GetUserIdentities(ctx context.Context, userId int) ([]types.Identity, error)
+	CreateIdentity(ctx context.Context, identity types.Identity) (types.Identity, error)
+	CreateUserWithIdentity(ctx context.Context, user types.InternalUser, identity types.Identity, emailVerified bool) (types.InternalUser, error)
+	TouchIdentity(ctx context.Context, id int) error
+	DeleteIdentity(ctx context.Context, userId int, id int) (bool, error)
+}
+
+// RevocationStore keeps access tokens revoked before their expiry, by jti,
+// revoked sessions and per user cutoffs before which every issued token is
+// rejected.
+type RevocationStore interface {
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	IsSessionRevoked(ctx context.Context, id int) (bool, error)
+	RevokeToken(ctx context.Context, jti string, userId int, expiresAt time.Time) error
+	GetTokensValidAfter(ctx context.Context, userId int) (time.Time, error)
+	SetTokensValidAfter(ctx context.Context, userId int, validAfter time.Time) error
+}
+
+// SigningKeyStore shares the access token signing keys between replicas.
+type SigningKeyStore interface {
+	GetSigningKeys(ctx context.Context) ([]types.SigningKey, error)
+	CreateSigningKey(ctx context.Context, key types.SigningKey) (bool, error)
+}
+
+type ApiKeyStore interface {
+	CreateApiKey(ctx context.Context, key types.ApiKey) (types.ApiKey, error)
+	GetUserApiKeys(ctx context.Context, userId int) ([]types.ApiKey, error)
+	GetApiKeyByPrefix(ctx context.Context, prefix string) (types.ApiKey, error)
+	RevokeApiKey(ctx context.Context, userId int, id int) (bool, error)
+	TouchApiKey(ctx context.Context, id int) error
+}
+
+// UserFilter narrows AdminStore.ListUsers. Empty fields match every user.
+type UserFilter struct {
+	// Search matches part of the email, first or last name.
+	Search   string
+	Role     string
+	Disabled *bool
+}
+
+// AdminStore changes of users also set tokens_valid_after to at, so access
+// tokens issued before the change are rejected.
+type AdminStore interface {
+	ListUsers(ctx context.Context, filter UserFilter, offset int, count int) ([]types.User, error)
+	GetUser(ctx context.Context, id int) (types.User, error)
+	GetUserUsage(ctx context.Context, id int) (types.UserUsage, error)
+	GetUsage(ctx context.Context) (types.Usage, error)
+	SetUserDisabled(ctx context.Context, id int, disabled bool, at time.Time) (types.User, error)
+	SetUserRole(ctx context.Context, id int, role string, at time.Time) (types.User, error)
+	ForceLogout(ctx context.Context, id int, at time.Time) error
+}
+
+type DataExportStore interface {
+	// CreateDataExport starts a new export unless the user has one pending
+	// that is younger than staleAfter, which is returned instead. It reports
+	// whether the export was created.
+	CreateDataExport(ctx context.Context, userId int, staleAfter time.Duration) (types.DataExport, bool, error)
+	GetDataExport(ctx context.Context, userId int, id int) (types.DataExport, error)
+	CompleteDataExport(ctx context.Context, id int, archive []byte, expiresAt time.Time) error
+	FailDataExport(ctx context.Context, id int) error
+	// GetDataExportArchive returns the archive of a ready export that has not
+	// expired.
+	GetDataExportArchive(ctx context.Context, id int) ([]byte, error)
+	GetAccountData(ctx context.Context, userId int) (types.AccountData, error)
 }
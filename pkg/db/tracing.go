@@ -0,0 +1,23 @@
+package db
+
+import (
+	"context"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StartQuerySpan starts a client span for a single SQL statement. Close it
+// with tracing.End once the statement and its scanning are done.
+func StartQuerySpan(ctx context.Context, operation string, query string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBQueryText(query),
+			attribute.String("db.operation.name", operation),
+		),
+	)
+}
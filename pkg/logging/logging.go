@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type contextKey struct{}
+
+type requestFields struct {
+	requestId string
+	userId    atomic.Int64
+}
+
+// Init replaces the default slog logger with a JSON logger writing to stdout.
+// Every record logged with a request context gets the request id and, once
+// authenticated, the user id attached.
+func Init() {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(os.Getenv("LOG_LEVEL"))); err != nil {
+		level = slog.LevelInfo
+	}
+
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+	slog.SetDefault(slog.New(&contextHandler{Handler: handler}))
+}
+
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, contextKey{}, &requestFields{requestId: requestId})
+}
+
+func RequestId(ctx context.Context) string {
+	if fields, ok := ctx.Value(contextKey{}).(*requestFields); ok {
+		return fields.requestId
+	}
+
+	return ""
+}
+
+// SetUserId records the authenticated user on the request. The fields are
+// shared by pointer so outer middleware sees ids set further down the chain.
+func SetUserId(ctx context.Context, userId int) {
+	if fields, ok := ctx.Value(contextKey{}).(*requestFields); ok {
+		fields.userId.Store(int64(userId))
+	}
+}
+
+func UserId(ctx context.Context) int {
+	if fields, ok := ctx.Value(contextKey{}).(*requestFields); ok {
+		return int(fields.userId.Load())
+	}
+
+	return 0
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestId := RequestId(ctx); requestId != "" {
+		record.AddAttrs(slog.String("request_id", requestId))
+	}
+	if userId := UserId(ctx); userId != 0 {
+		record.AddAttrs(slog.Int("user_id", userId))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(slog.String("trace_id", spanContext.TraceID().String()))
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes every message as an .eml file into a directory instead
+// of sending it.
+type FileMailer struct {
+	directory string
+	from      string
+}
+
+func NewFileMailer(directory string, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &FileMailer{directory: directory, from: from}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, message Message) error {
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), filepath.Base(message.To))
+	return os.WriteFile(filepath.Join(m.directory, name), formatMessage(m.from, message), 0o644)
+}
+
+// LogMailer logs messages instead of sending them. It is meant for local
+// development, where the logged bodies contain usable links and tokens.
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (m *LogMailer) Send(ctx context.Context, message Message) error {
+	slog.InfoContext(ctx, "email",
+		"to", message.To,
+		"subject", message.Subject,
+		"body", message.Body,
+	)
+	return nil
+}
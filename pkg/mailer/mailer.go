@@ -0,0 +1,55 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// FromEnv creates the mailer selected with MAILER: "smtp", "file" or "log".
+// There is no default, as the log mailer writes the links and tokens of the
+// emails to the logs.
+func FromEnv() (Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+
+	switch os.Getenv("MAILER") {
+	case "":
+		return nil, fmt.Errorf("MAILER not provided as an env variable")
+	case "log":
+		return NewLogMailer(), nil
+	case "file":
+		directory := os.Getenv("MAIL_DIR")
+		if directory == "" {
+			return nil, fmt.Errorf("MAIL_DIR not provided as an env variable")
+		}
+		return NewFileMailer(directory, from)
+	case "smtp":
+		port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if err != nil {
+			return nil, fmt.Errorf("SMTP_PORT is not a valid port: %w", err)
+		}
+		return NewSMTPMailer(SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown MAILER %q", os.Getenv("MAILER"))
+	}
+}
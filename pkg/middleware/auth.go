@@ -2,25 +2,145 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"slices"
+	"strings"
 
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/logging"
 	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
 )
 
+// JwtAuthenticator only accepts access tokens. It guards routes that API
+// keys must not reach, like managing credentials.
 func JwtAuthenticator() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		hfn := func(w http.ResponseWriter, r *http.Request) {
-			userId, err := service.JwtClient.VerifyToken(r)
-			if err != nil {
-				service.SendErrorsResponse(w, []string{"Athorization header is not valid"}, http.StatusUnauthorized)
+			authenticateJwt(w, r, next)
+		}
+		return http.HandlerFunc(hfn)
+	}
+}
+
+// Authenticator accepts access tokens like JwtAuthenticator as well as
+// personal API keys sent as bearer tokens. Requests made with an API key are
+// limited to its scopes, see RequireScope.
+func Authenticator() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		hfn := func(w http.ResponseWriter, r *http.Request) {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !service.IsApiKey(token) {
+				authenticateJwt(w, r, next)
+				return
+			}
+
+			if service.ApiKeys == nil {
+				service.SendErrorsResponse(w, []string{"API keys are not supported"}, http.StatusUnauthorized)
+				return
+			}
+
+			key, err := service.ApiKeys.VerifyApiKey(r.Context(), token)
+			if errors.Is(err, types.InvalidApiKeyErr) {
+				service.SendErrorsResponse(w, []string{"API key is not valid"}, http.StatusUnauthorized)
+				return
+			} else if err != nil {
+				service.SendInternalServerError(w, r, err)
 				return
 			}
 
+			scopes := key.Scopes
+			if scopes == nil {
+				scopes = []string{}
+			}
+
 			ctx := r.Context()
-			ctx = context.WithValue(ctx, service.UserIdKey, userId)
+			ctx = context.WithValue(ctx, service.UserIdKey, key.UserId)
+			ctx = context.WithValue(ctx, service.ScopesKey, scopes)
+			logging.SetUserId(ctx, key.UserId)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		}
 		return http.HandlerFunc(hfn)
 	}
 }
+
+func authenticateJwt(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	claims, err := service.JwtClient.ParseToken(r)
+	if errors.Is(err, types.RevocationCheckFailedErr) || errors.Is(err, types.SigningKeyLookupFailedErr) {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+	if errors.Is(err, types.UserDisabledErr) {
+		service.SendErrorsResponse(w, []string{"Account is disabled"}, http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		service.SendErrorsResponse(w, []string{"Athorization header is not valid"}, http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	ctx = context.WithValue(ctx, service.UserIdKey, claims.UserId)
+	ctx = context.WithValue(ctx, service.ClaimsKey, claims)
+	logging.SetUserId(ctx, claims.UserId)
+
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// RequireScope rejects requests made with an API key that was not granted
+// the scope. Requests made with an access token are not limited.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		hfn := func(w http.ResponseWriter, r *http.Request) {
+			scopes := service.ScopesFromContext(r.Context())
+			if scopes != nil && !slices.Contains(scopes, scope) {
+				service.SendErrorsResponse(w, []string{"API key is missing the " + scope + " scope"}, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(hfn)
+	}
+}
+
+// RequireRole only lets through users whose access token carries the role.
+// Requests made with an API key are always rejected.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		hfn := func(w http.ResponseWriter, r *http.Request) {
+			claims := service.ClaimsFromContext(r.Context())
+			if claims == nil || claims.Role != role {
+				service.SendErrorsResponse(w, []string{"Insufficient permissions"}, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(hfn)
+	}
+}
+
+// RequireVerifiedEmail rejects users whose access token was issued before
+// they verified their email address. It must run after JwtAuthenticator or
+// Authenticator. API keys pass, since creating one already required it.
+func RequireVerifiedEmail() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		hfn := func(w http.ResponseWriter, r *http.Request) {
+			if service.ScopesFromContext(r.Context()) != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims := service.ClaimsFromContext(r.Context())
+			if claims == nil || !claims.EmailVerified {
+				service.SendErrorsResponse(w, []string{"Email address is not verified"}, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(hfn)
+	}
+}
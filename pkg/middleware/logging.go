@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/logging"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+const maxRequestIdLength = 128
+
+// RequestLogger assigns every request an id, taken from the X-Request-ID
+// header when the client provides a usable one, echoes it back in the
+// response and logs the request once it has been handled.
+func RequestLogger() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		hfn := func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestId := r.Header.Get(service.RequestIdHeader)
+			if !isValidRequestId(requestId) {
+				requestId = newRequestId()
+			}
+			w.Header().Set(service.RequestIdHeader, requestId)
+
+			ctx := logging.WithRequestId(r.Context(), requestId)
+			ww := chiMiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			slog.InfoContext(ctx, "request handled",
+				"method", r.Method,
+				"route", routePattern(r),
+				"path", r.URL.Path,
+				"status", status,
+				"latency_ms", float64(time.Since(start).Microseconds())/1000,
+			)
+		}
+		return http.HandlerFunc(hfn)
+	}
+}
+
+func isValidRequestId(requestId string) bool {
+	if requestId == "" || len(requestId) > maxRequestIdLength {
+		return false
+	}
+
+	for _, c := range requestId {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/metrics"
+	"github.com/go-chi/chi/v5"
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+func Metrics() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		hfn := func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := chiMiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			labels := []string{r.Method, routePattern(r), strconv.Itoa(status)}
+			metrics.HttpRequests.WithLabelValues(labels...).Inc()
+			metrics.HttpRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+		}
+		return http.HandlerFunc(hfn)
+	}
+}
+
+// routePattern returns the matched chi route pattern so that label values
+// stay bounded regardless of path parameters.
+func routePattern(r *http.Request) string {
+	routeContext := chi.RouteContext(r.Context())
+	if routeContext == nil || routeContext.RoutePattern() == "" {
+		return "unmatched"
+	}
+
+	return routeContext.RoutePattern()
+}
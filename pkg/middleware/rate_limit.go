@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/metrics"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/ratelimit"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+)
+
+// RateLimit limits requests of authenticated users by their id and of
+// everyone else by their address, so it has to come after the authenticator
+// of the group for per user limits. Requests are let through when the
+// limiter fails, a broken limiter should not take the API down.
+func RateLimit(limiter ratelimit.Limiter, rule ratelimit.Rule) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !rule.Enabled() {
+			return next
+		}
+
+		policy := fmt.Sprintf("%d;w=%d", rule.Limit, int(rule.Period.Seconds()))
+
+		hfn := func(w http.ResponseWriter, r *http.Request) {
+			key := "ip:" + service.ClientIP(r)
+			if userId := service.UserIdFromContext(r.Context()); userId != 0 {
+				key = "user:" + strconv.Itoa(userId)
+			}
+
+			result, err := limiter.Allow(r.Context(), rule, key)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "could not check rate limit", "rule", rule.Name, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Set("RateLimit-Policy", policy)
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", seconds(result.ResetAfter))
+
+			if !result.Allowed {
+				header.Set("Retry-After", seconds(result.RetryAfter))
+				metrics.RateLimited(rule.Name)
+				service.SendErrorsResponse(w, []string{"Too many requests, please try again later"}, http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(hfn)
+	}
+}
+
+// seconds rounds up, so clients that wait as long are not limited again.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrustedProxies parses a comma separated list of addresses and CIDR
+// ranges, e.g. "10.0.0.0/8, 127.0.0.1".
+func ParseTrustedProxies(value string) ([]netip.Prefix, error) {
+	proxies := make([]netip.Prefix, 0)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an address or CIDR range", entry)
+		}
+		proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return proxies, nil
+}
+
+// RealIP replaces the remote address of requests sent by a trusted proxy
+// with the client's address from X-Forwarded-For. The header is read from
+// the right, skipping trusted proxies, as clients can put anything in front.
+// Requests from other addresses keep their remote address.
+func RealIP(trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	trusted := func(value string) (netip.Addr, bool) {
+		addr, err := netip.ParseAddr(strings.TrimSpace(value))
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		addr = addr.Unmap()
+
+		for _, prefix := range trustedProxies {
+			if prefix.Contains(addr) {
+				return addr, true
+			}
+		}
+		return addr, false
+	}
+
+	return func(next http.Handler) http.Handler {
+		hfn := func(w http.ResponseWriter, r *http.Request) {
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+
+			if _, ok := trusted(host); ok {
+				hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+				for i := len(hops) - 1; i >= 0; i-- {
+					addr, isProxy := trusted(hops[i])
+					if !addr.IsValid() {
+						break
+					}
+
+					r.RemoteAddr = addr.String()
+					if !isProxy {
+						break
+					}
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(hfn)
+	}
+}
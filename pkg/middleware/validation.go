@@ -0,0 +1,221 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/openapi"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+const maxBodySize = 1 << 20
+
+// ValidateRequests checks path and query parameters and JSON bodies against
+// the operation documented for the matched route before the handler runs, and
+// responds with every violation in a single 400 response. With
+// validateResponses set, responses are buffered and checked as well, and
+// undocumented responses are replaced with a 500; this is meant for tests and
+// development only. Bodies over 1 MiB are rejected with a 413.
+func ValidateRequests(doc *openapi.Document, validateResponses bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		hfn := func(w http.ResponseWriter, r *http.Request) {
+			operation, params, ok := matchOperation(doc, r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			fieldErrors := validateParameters(doc, operation, params, r)
+
+			var maxBytesError *http.MaxBytesError
+			bodyErrors, err := validateRequestBody(doc, operation, w, r)
+			if errors.As(err, &maxBytesError) {
+				service.SendErrorsResponse(w, []string{types.BodyTooLargeErr.Error()}, http.StatusRequestEntityTooLarge)
+				return
+			} else if err != nil {
+				service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+				return
+			}
+			fieldErrors = append(fieldErrors, bodyErrors...)
+
+			if len(fieldErrors) > 0 {
+				service.SendFieldErrorsResponse(w, fieldErrors)
+				return
+			}
+
+			if !validateResponses {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			if responseErrors := validateResponse(doc, operation, recorder); len(responseErrors) > 0 {
+				slog.ErrorContext(r.Context(), "response does not match the OpenAPI document",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"status", recorder.statusCode,
+					"errors", responseErrors,
+				)
+				service.SendErrorsResponse(w, []string{"Response does not match the API specification"}, http.StatusInternalServerError)
+				return
+			}
+
+			w.WriteHeader(recorder.statusCode)
+			w.Write(recorder.body.Bytes())
+		}
+		return http.HandlerFunc(hfn)
+	}
+}
+
+// matchOperation finds the operation for the route the request will be
+// routed to. Routing has not happened yet when middleware runs, so the
+// route is matched on a separate context.
+func matchOperation(doc *openapi.Document, r *http.Request) (*openapi.Operation, *chi.Context, bool) {
+	routeContext := chi.RouteContext(r.Context())
+	if routeContext == nil || routeContext.Routes == nil {
+		return nil, nil, false
+	}
+
+	path := routeContext.RoutePath
+	if path == "" {
+		path = r.URL.Path
+	}
+
+	match := chi.NewRouteContext()
+	if !routeContext.Routes.Match(match, r.Method, path) {
+		return nil, nil, false
+	}
+
+	operation, ok := doc.Operation(r.Method, openapi.NormalizePath(match.RoutePattern()))
+	return operation, match, ok
+}
+
+func validateParameters(doc *openapi.Document, operation *openapi.Operation, params *chi.Context, r *http.Request) []types.FieldError {
+	fieldErrors := make([]types.FieldError, 0)
+	query := r.URL.Query()
+
+	for _, parameter := range operation.Parameters {
+		var raw string
+		var present bool
+
+		switch parameter.In {
+		case "path":
+			raw = params.URLParam(parameter.Name)
+			present = raw != ""
+		case "query":
+			present = query.Has(parameter.Name)
+			raw = query.Get(parameter.Name)
+		default:
+			continue
+		}
+
+		pointer := "/" + parameter.Name
+		if !present {
+			if parameter.Required {
+				fieldErrors = append(fieldErrors, types.FieldError{In: parameter.In, Pointer: pointer, Message: "is required"})
+			}
+			continue
+		}
+
+		value := doc.ParseParameter(parameter.Schema, raw)
+		fieldErrors = append(fieldErrors, doc.Validate(parameter.Schema, value, parameter.In, pointer)...)
+	}
+
+	return fieldErrors
+}
+
+// validateRequestBody reads and validates the JSON body, leaving a copy in
+// r.Body for the handler. An error is only returned when the body could not
+// be read, which is an *http.MaxBytesError when it is too large.
+func validateRequestBody(doc *openapi.Document, operation *openapi.Operation, w http.ResponseWriter, r *http.Request) ([]types.FieldError, error) {
+	if operation.RequestBody == nil {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if operation.RequestBody.Required {
+			return []types.FieldError{{In: "body", Pointer: "", Message: "is required"}}, nil
+		}
+		return nil, nil
+	}
+
+	mediaType, ok := operation.RequestBody.Content["application/json"]
+	if !ok {
+		return nil, nil
+	}
+
+	value, err := decodeJson(body)
+	if err != nil {
+		return []types.FieldError{{In: "body", Pointer: "", Message: "is not valid JSON"}}, nil
+	}
+
+	return doc.Validate(mediaType.Schema, value, "body", ""), nil
+}
+
+func validateResponse(doc *openapi.Document, operation *openapi.Operation, recorder *responseRecorder) []types.FieldError {
+	response, ok := operation.Responses[strconv.Itoa(recorder.statusCode)]
+	if !ok {
+		response, ok = operation.Responses["default"]
+	}
+	if !ok {
+		return []types.FieldError{{In: "response", Pointer: "", Message: "status code " + strconv.Itoa(recorder.statusCode) + " is not documented"}}
+	}
+
+	mediaType, ok := response.Content["application/json"]
+	if !ok || recorder.body.Len() == 0 {
+		return nil
+	}
+
+	value, err := decodeJson(recorder.body.Bytes())
+	if err != nil {
+		return []types.FieldError{{In: "response", Pointer: "", Message: "is not valid JSON"}}
+	}
+
+	return doc.Validate(mediaType.Schema, value, "response", "")
+}
+
+func decodeJson(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if !r.wroteHeader {
+		r.statusCode = statusCode
+		r.wroteHeader = true
+	}
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.body.Write(b)
+}
@@ -0,0 +1,213 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/openapi"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+type testItemBody struct {
+	Name string `json:"name" openapi:"minLength=1"`
+}
+
+type testItem struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func newValidationTestDocument() *openapi.Document {
+	doc := openapi.NewDocument(openapi.Info{Title: "Test", Version: "1"})
+	body := doc.Register("ItemBody", testItemBody{})
+	item := doc.Register("Item", testItem{})
+
+	doc.AddOperation(http.MethodPost, "/api/items", &openapi.Operation{
+		OperationId: "createItem",
+		RequestBody: openapi.JsonBody(body),
+		Responses: map[string]*openapi.Response{
+			"201": openapi.JsonContent("Created", item),
+		},
+	})
+	doc.AddOperation(http.MethodGet, "/api/items/{itemId}", &openapi.Operation{
+		OperationId: "getItem",
+		Parameters: []*openapi.Parameter{
+			{Name: "itemId", In: "path", Required: true, Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "fields", In: "query", Required: true, Schema: &openapi.Schema{Type: "string", Enum: []string{"all", "name"}}},
+			{Name: "limit", In: "query", Schema: &openapi.Schema{Type: "integer", Minimum: floatPointer(1)}},
+		},
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JsonContent("Item", item),
+		},
+	})
+
+	return doc
+}
+
+func floatPointer(f float64) *float64 {
+	return &f
+}
+
+// newValidationTestRouter serves the documented routes from a sub-router, as
+// the API does. respond writes the response of every route.
+func newValidationTestRouter(validateResponses bool, respond http.HandlerFunc) http.Handler {
+	r := chi.NewRouter()
+	r.Use(ValidateRequests(newValidationTestDocument(), validateResponses))
+
+	r.Route("/api", func(r chi.Router) {
+		r.Post("/items", respond)
+		r.Get("/items/{itemId}", respond)
+		r.Get("/undocumented", respond)
+	})
+
+	return r
+}
+
+func serve(handler http.Handler, method string, target string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return w
+}
+
+func decodeFieldErrors(t *testing.T, w *httptest.ResponseRecorder) []types.FieldError {
+	t.Helper()
+
+	var response service.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	return response.FieldErrors
+}
+
+func TestValidateRequests(t *testing.T) {
+	var handled bool
+	var handledBody string
+	router := newValidationTestRouter(false, func(w http.ResponseWriter, r *http.Request) {
+		handled = true
+		body, _ := io.ReadAll(r.Body)
+		handledBody = string(body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   []types.FieldError
+	}{
+		{"valid body", http.MethodPost, "/api/items", `{"name": "Item"}`, nil},
+		{"invalid body", http.MethodPost, "/api/items", `{"name": ""}`, []types.FieldError{
+			{In: "body", Pointer: "/name", Message: "must be at least 1 characters long"},
+		}},
+		{"missing body", http.MethodPost, "/api/items", ``, []types.FieldError{
+			{In: "body", Pointer: "", Message: "is required"},
+		}},
+		{"invalid JSON", http.MethodPost, "/api/items", `{"name":`, []types.FieldError{
+			{In: "body", Pointer: "", Message: "is not valid JSON"},
+		}},
+		{"valid parameters", http.MethodGet, "/api/items/1?fields=all&limit=5", ``, nil},
+		{"invalid parameters", http.MethodGet, "/api/items/abc?limit=0", ``, []types.FieldError{
+			{In: "path", Pointer: "/itemId", Message: "must be of type integer"},
+			{In: "query", Pointer: "/fields", Message: "is required"},
+			{In: "query", Pointer: "/limit", Message: "must be at least 1"},
+		}},
+		{"undocumented route", http.MethodGet, "/api/undocumented?limit=0", ``, nil},
+		{"unknown route", http.MethodGet, "/api/unknown", ``, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handled, handledBody = false, ""
+			w := serve(router, test.method, test.target, test.body)
+
+			if test.want == nil {
+				if w.Code == http.StatusBadRequest {
+					t.Fatalf("request was rejected: %s", w.Body)
+				}
+				if handled && handledBody != test.body {
+					t.Errorf("handler read body %q, want %q", handledBody, test.body)
+				}
+				return
+			}
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400", w.Code)
+			}
+			if handled {
+				t.Error("handler ran for an invalid request")
+			}
+			if errors := decodeFieldErrors(t, w); !slices.Equal(errors, test.want) {
+				t.Errorf("field errors = %+v, want %+v", errors, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateRequestsBodyTooLarge(t *testing.T) {
+	router := newValidationTestRouter(false, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler ran for a body that is too large")
+	})
+
+	body := `{"name": "` + strings.Repeat("x", maxBodySize) + `"}`
+	w := serve(router, http.MethodPost, "/api/items", body)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", w.Code)
+	}
+}
+
+func TestValidateRequestsBodyReadError(t *testing.T) {
+	router := newValidationTestRouter(false, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler ran for a body that could not be read")
+	})
+
+	r := httptest.NewRequest(http.MethodPost, "/api/items", iotest.ErrReader(errors.New("connection reset")))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}
+
+func TestValidateResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   int
+	}{
+		{"documented", http.StatusCreated, `{"id": 1, "name": "Item"}`, http.StatusCreated},
+		{"does not match the schema", http.StatusCreated, `{"id": "1", "name": "Item"}`, http.StatusInternalServerError},
+		{"undocumented status", http.StatusAccepted, `{"id": 1, "name": "Item"}`, http.StatusInternalServerError},
+		{"not JSON", http.StatusCreated, `Item`, http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := newValidationTestRouter(true, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.body))
+			})
+
+			w := serve(router, http.MethodPost, "/api/items", `{"name": "Item"}`)
+			if w.Code != test.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, test.want, w.Body)
+			}
+			if test.want == test.status && w.Body.String() != test.body {
+				t.Errorf("body = %q, want %q", w.Body, test.body)
+			}
+		})
+	}
+}
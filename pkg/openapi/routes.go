@@ -0,0 +1,57 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler serves the document as JSON. The document is encoded once, so it
+// must not be modified after the handler is created.
+func Handler(doc *Document) (http.HandlerFunc, error) {
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(encoded)
+	}, nil
+}
+
+// MissingRoutes returns every route registered on the router that has no
+// matching operation in the document, formatted as "METHOD /path".
+func MissingRoutes(doc *Document, routes chi.Routes) ([]string, error) {
+	missing := make([]string, 0)
+
+	err := chi.Walk(routes, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		path := NormalizePath(route)
+		if _, ok := doc.Operation(method, path); !ok {
+			missing = append(missing, fmt.Sprintf("%s %s", method, path))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(missing)
+	return missing, nil
+}
+
+// NormalizePath converts a chi route pattern to the form used as a key in
+// the document's paths.
+func NormalizePath(route string) string {
+	route = strings.ReplaceAll(route, "/*/", "/")
+	if len(route) > 1 {
+		route = strings.TrimSuffix(route, "/")
+	}
+
+	return route
+}
@@ -0,0 +1,140 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaFor generates a JSON schema from the type of v using its json tags.
+// Fields are required unless tagged omitempty. Additional constraints can be
+// given with an openapi tag, e.g. `openapi:"format=email,maxLength=320"`;
+// fields that may be null are tagged `openapi:"nullable"`.
+func SchemaFor(v any) *Schema {
+	return schemaForType(reflect.TypeOf(v))
+}
+
+func schemaForType(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: schemaForType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaForType(t.Elem())}
+	case reflect.Struct:
+		schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		addStructFields(schema, t)
+		return schema
+	default:
+		return &Schema{}
+	}
+}
+
+func addStructFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				addStructFields(schema, embedded)
+			}
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := schemaForType(field.Type)
+		applyTag(property, field.Tag.Get("openapi"))
+		schema.Properties[name] = property
+
+		if !strings.Contains(options, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+func applyTag(schema *Schema, tag string) {
+	if tag == "" {
+		return
+	}
+
+	for _, option := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "nullable":
+			schema.Nullable = true
+		case "format":
+			schema.Format = value
+		case "description":
+			schema.Description = value
+		case "pattern":
+			schema.Pattern = value
+		case "enum":
+			schema.Enum = strings.Split(value, "|")
+		case "minLength":
+			schema.MinLength = intPointer(value)
+		case "maxLength":
+			schema.MaxLength = intPointer(value)
+		case "minItems":
+			schema.MinItems = intPointer(value)
+		case "maxItems":
+			schema.MaxItems = intPointer(value)
+		case "minimum":
+			schema.Minimum = floatPointer(value)
+		case "maximum":
+			schema.Maximum = floatPointer(value)
+		}
+	}
+}
+
+func intPointer(value string) *int {
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return nil
+	}
+	return &i
+}
+
+func floatPointer(value string) *float64 {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
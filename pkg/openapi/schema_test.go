@@ -0,0 +1,81 @@
+package openapi
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+type testEmbedded struct {
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type testBody struct {
+	testEmbedded
+	Name     *string        `json:"name" openapi:"minLength=1,maxLength=100"`
+	Email    string         `json:"email,omitempty" openapi:"format=email"`
+	Tags     []string       `json:"tags" openapi:"minItems=1,maxItems=5"`
+	Score    float64        `json:"score" openapi:"minimum=0,maximum=1"`
+	Kind     string         `json:"kind" openapi:"enum=a|b"`
+	Data     []byte         `json:"data"`
+	Labels   map[string]int `json:"labels"`
+	Note     *string        `json:"note" openapi:"nullable"`
+	Ignored  string         `json:"-"`
+	internal string
+}
+
+func TestSchemaFor(t *testing.T) {
+	schema := SchemaFor(testBody{})
+
+	if schema.Type != "object" {
+		t.Fatalf("type = %q, want object", schema.Type)
+	}
+
+	wantRequired := []string{"created_at", "name", "tags", "score", "kind", "data", "labels", "note"}
+	if !slices.Equal(schema.Required, wantRequired) {
+		t.Errorf("required = %v, want %v", schema.Required, wantRequired)
+	}
+
+	for _, name := range []string{"Ignored", "internal", "testEmbedded"} {
+		if _, ok := schema.Properties[name]; ok {
+			t.Errorf("property %s should not be generated", name)
+		}
+	}
+
+	if p := schema.Properties["created_at"]; p.Type != "string" || p.Format != "date-time" {
+		t.Errorf("created_at = %+v, want an embedded date-time", p)
+	}
+	if p := schema.Properties["name"]; p.Type != "string" || *p.MinLength != 1 || *p.MaxLength != 100 {
+		t.Errorf("name = %+v", p)
+	}
+	if p := schema.Properties["email"]; p.Format != "email" {
+		t.Errorf("email = %+v", p)
+	}
+	if p := schema.Properties["tags"]; p.Type != "array" || p.Items.Type != "string" || *p.MinItems != 1 || *p.MaxItems != 5 {
+		t.Errorf("tags = %+v", p)
+	}
+	if p := schema.Properties["score"]; p.Type != "number" || *p.Minimum != 0 || *p.Maximum != 1 {
+		t.Errorf("score = %+v", p)
+	}
+	if p := schema.Properties["kind"]; !slices.Equal(p.Enum, []string{"a", "b"}) {
+		t.Errorf("kind = %+v", p)
+	}
+	if p := schema.Properties["data"]; p.Type != "string" || p.Format != "byte" {
+		t.Errorf("data = %+v, want base64 bytes", p)
+	}
+	if p := schema.Properties["labels"]; p.Type != "object" || p.AdditionalProperties.Type != "integer" {
+		t.Errorf("labels = %+v", p)
+	}
+}
+
+func TestSchemaMarshalNullable(t *testing.T) {
+	encoded, err := json.Marshal(SchemaFor(testBody{}).Properties["note"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `{"type":["string","null"]}`; string(encoded) != want {
+		t.Errorf("nullable schema = %s, want %s", encoded, want)
+	}
+}
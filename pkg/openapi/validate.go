@@ -0,0 +1,178 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// Validate checks a value decoded with json.Decoder.UseNumber against the
+// schema and returns every violation found. in and pointer locate the value
+// within the request and are copied into the returned errors.
+func (d *Document) Validate(schema *Schema, value any, in string, pointer string) []types.FieldError {
+	schema = d.Resolve(schema)
+	if schema == nil {
+		return nil
+	}
+
+	fail := func(format string, args ...any) []types.FieldError {
+		return []types.FieldError{{In: in, Pointer: pointer, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	if value == nil && schema.Nullable {
+		return nil
+	}
+
+	if schema.Type != "" && !hasType(value, schema.Type) {
+		return fail("must be of type %s", schema.Type)
+	}
+
+	var errors []types.FieldError
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			errors = append(errors, fail("must be at least %d characters long", *schema.MinLength)...)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			errors = append(errors, fail("must be at most %d characters long", *schema.MaxLength)...)
+		}
+		if schema.Pattern != "" {
+			if matched, err := regexp.MatchString(schema.Pattern, v); err == nil && !matched {
+				errors = append(errors, fail("must match pattern %s", schema.Pattern)...)
+			}
+		}
+		if !hasFormat(v, schema.Format) {
+			errors = append(errors, fail("must be a valid %s", schema.Format)...)
+		}
+		if len(schema.Enum) > 0 && !contains(schema.Enum, v) {
+			errors = append(errors, fail("must be one of %s", strings.Join(schema.Enum, ", "))...)
+		}
+
+	case json.Number:
+		number, _ := v.Float64()
+		if schema.Minimum != nil && number < *schema.Minimum {
+			errors = append(errors, fail("must be at least %v", *schema.Minimum)...)
+		}
+		if schema.Maximum != nil && number > *schema.Maximum {
+			errors = append(errors, fail("must be at most %v", *schema.Maximum)...)
+		}
+
+	case []any:
+		if schema.MinItems != nil && len(v) < *schema.MinItems {
+			errors = append(errors, fail("must contain at least %d items", *schema.MinItems)...)
+		}
+		if schema.MaxItems != nil && len(v) > *schema.MaxItems {
+			errors = append(errors, fail("must contain at most %d items", *schema.MaxItems)...)
+		}
+		for i, item := range v {
+			errors = append(errors, d.Validate(schema.Items, item, in, pointer+"/"+strconv.Itoa(i))...)
+		}
+
+	case map[string]any:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				errors = append(errors, types.FieldError{In: in, Pointer: pointer + "/" + escapePointer(name), Message: "is required"})
+			}
+		}
+
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			property, ok := schema.Properties[name]
+			if !ok {
+				property = schema.AdditionalProperties
+			}
+			errors = append(errors, d.Validate(property, v[name], in, pointer+"/"+escapePointer(name))...)
+		}
+	}
+
+	return errors
+}
+
+// ParseParameter converts a path or query parameter to the JSON value its
+// schema describes so it can be passed to Validate.
+func (d *Document) ParseParameter(schema *Schema, raw string) any {
+	schema = d.Resolve(schema)
+	if schema == nil {
+		return raw
+	}
+
+	switch schema.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(raw, 64); err == nil {
+			return json.Number(raw)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	}
+
+	return raw
+}
+
+func hasType(value any, schemaType string) bool {
+	switch v := value.(type) {
+	case nil:
+		return schemaType == "null"
+	case bool:
+		return schemaType == "boolean"
+	case string:
+		return schemaType == "string"
+	case json.Number:
+		if schemaType == "number" {
+			return true
+		}
+		_, err := v.Int64()
+		return schemaType == "integer" && err == nil
+	case []any:
+		return schemaType == "array"
+	case map[string]any:
+		return schemaType == "object"
+	default:
+		return false
+	}
+}
+
+func hasFormat(value string, format string) bool {
+	switch format {
+	case "email":
+		address, err := mail.ParseAddress(value)
+		return err == nil && address.Address == value
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	case "date":
+		_, err := time.Parse(time.DateOnly, value)
+		return err == nil
+	default:
+		return true
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,141 @@
+package openapi
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+type testItem struct {
+	Title string `json:"title" openapi:"minLength=1"`
+}
+
+type testRequest struct {
+	Name     string            `json:"name" openapi:"minLength=2,maxLength=5"`
+	Email    string            `json:"email,omitempty" openapi:"format=email"`
+	Code     string            `json:"code,omitempty" openapi:"pattern=^[A-Z]{3}$"`
+	Kind     string            `json:"kind,omitempty" openapi:"enum=a|b"`
+	Count    int               `json:"count,omitempty" openapi:"minimum=1,maximum=10"`
+	Ratio    float64           `json:"ratio,omitempty"`
+	Active   bool              `json:"active,omitempty"`
+	Note     *string           `json:"note,omitempty" openapi:"nullable"`
+	StartsAt time.Time         `json:"starts_at,omitempty"`
+	Tags     []string          `json:"tags,omitempty" openapi:"minItems=1,maxItems=2"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+func newTestDocument() (*Document, *Schema) {
+	doc := NewDocument(Info{Title: "Test", Version: "1"})
+	schema := doc.Register("TestRequest", testRequest{})
+	doc.Register("TestItem", testItem{})
+	doc.Components.Schemas["TestRequest"].Properties["items"] = &Schema{Type: "array", Items: Ref("TestItem")}
+
+	return doc, schema
+}
+
+// decode decodes JSON the way requests are decoded before validation.
+func decode(t *testing.T, data string) any {
+	t.Helper()
+
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		t.Fatal(err)
+	}
+
+	return value
+}
+
+func TestValidate(t *testing.T) {
+	doc, schema := newTestDocument()
+
+	tests := []struct {
+		name string
+		body string
+		want []types.FieldError
+	}{
+		{"valid", `{
+			"name": "Jane", "email": "jane@example.com", "code": "ABC", "kind": "a", "count": 10,
+			"ratio": 0.5, "active": true, "note": null, "starts_at": "2024-01-02T03:04:05Z",
+			"tags": ["x"], "labels": {"a": "b"}, "items": [{"title": "t"}]
+		}`, nil},
+		{"missing required", `{}`, []types.FieldError{{In: "body", Pointer: "/name", Message: "is required"}}},
+		{"not an object", `[]`, []types.FieldError{{In: "body", Pointer: "", Message: "must be of type object"}}},
+		{"too short", `{"name": "J"}`, []types.FieldError{{In: "body", Pointer: "/name", Message: "must be at least 2 characters long"}}},
+		{"length counts characters", `{"name": "Žužek"}`, nil},
+		{"too long", `{"name": "Jane Doe"}`, []types.FieldError{{In: "body", Pointer: "/name", Message: "must be at most 5 characters long"}}},
+		{"wrong type", `{"name": 5}`, []types.FieldError{{In: "body", Pointer: "/name", Message: "must be of type string"}}},
+		{"email", `{"name": "Jane", "email": "Jane <jane@example.com>"}`, []types.FieldError{{In: "body", Pointer: "/email", Message: "must be a valid email"}}},
+		{"pattern", `{"name": "Jane", "code": "abc"}`, []types.FieldError{{In: "body", Pointer: "/code", Message: "must match pattern ^[A-Z]{3}$"}}},
+		{"enum", `{"name": "Jane", "kind": "c"}`, []types.FieldError{{In: "body", Pointer: "/kind", Message: "must be one of a, b"}}},
+		{"not an integer", `{"name": "Jane", "count": 1.5}`, []types.FieldError{{In: "body", Pointer: "/count", Message: "must be of type integer"}}},
+		{"minimum", `{"name": "Jane", "count": 0}`, []types.FieldError{{In: "body", Pointer: "/count", Message: "must be at least 1"}}},
+		{"maximum", `{"name": "Jane", "count": 11}`, []types.FieldError{{In: "body", Pointer: "/count", Message: "must be at most 10"}}},
+		{"integer is a number", `{"name": "Jane", "ratio": 2}`, nil},
+		{"null", `{"name": null}`, []types.FieldError{{In: "body", Pointer: "/name", Message: "must be of type string"}}},
+		{"date-time", `{"name": "Jane", "starts_at": "2024-01-02"}`, []types.FieldError{{In: "body", Pointer: "/starts_at", Message: "must be a valid date-time"}}},
+		{"min items", `{"name": "Jane", "tags": []}`, []types.FieldError{{In: "body", Pointer: "/tags", Message: "must contain at least 1 items"}}},
+		{"max items", `{"name": "Jane", "tags": ["a", "b", 3]}`, []types.FieldError{
+			{In: "body", Pointer: "/tags", Message: "must contain at most 2 items"},
+			{In: "body", Pointer: "/tags/2", Message: "must be of type string"},
+		}},
+		{"additional properties", `{"name": "Jane", "labels": {"a/b": 1}}`, []types.FieldError{{In: "body", Pointer: "/labels/a~1b", Message: "must be of type string"}}},
+		{"referenced items", `{"name": "Jane", "items": [{"title": "t"}, {"title": ""}, {}]}`, []types.FieldError{
+			{In: "body", Pointer: "/items/1/title", Message: "must be at least 1 characters long"},
+			{In: "body", Pointer: "/items/2/title", Message: "is required"},
+		}},
+		{"every violation", `{"name": "J", "kind": "c", "count": 0}`, []types.FieldError{
+			{In: "body", Pointer: "/count", Message: "must be at least 1"},
+			{In: "body", Pointer: "/kind", Message: "must be one of a, b"},
+			{In: "body", Pointer: "/name", Message: "must be at least 2 characters long"},
+		}},
+		{"unknown properties", `{"name": "Jane", "unknown": 1}`, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errors := doc.Validate(schema, decode(t, test.body), "body", "")
+			if !slices.Equal(errors, test.want) {
+				t.Errorf("errors = %+v, want %+v", errors, test.want)
+			}
+		})
+	}
+}
+
+func TestParseParameter(t *testing.T) {
+	doc := NewDocument(Info{Title: "Test", Version: "1"})
+
+	tests := []struct {
+		schema *Schema
+		raw    string
+		want   any
+	}{
+		{&Schema{Type: "integer"}, "42", json.Number("42")},
+		{&Schema{Type: "number"}, "1.5", json.Number("1.5")},
+		{&Schema{Type: "integer"}, "abc", "abc"},
+		{&Schema{Type: "boolean"}, "true", true},
+		{&Schema{Type: "boolean"}, "yes", "yes"},
+		{&Schema{Type: "string"}, "42", "42"},
+		{nil, "42", "42"},
+	}
+
+	for _, test := range tests {
+		if value := doc.ParseParameter(test.schema, test.raw); value != test.want {
+			t.Errorf("ParseParameter(%+v, %q) = %#v, want %#v", test.schema, test.raw, value, test.want)
+		}
+	}
+
+	// Values that could not be parsed fail validation with a type error.
+	schema := &Schema{Type: "integer"}
+	errors := doc.Validate(schema, doc.ParseParameter(schema, "abc"), "path", "/resumeId")
+	want := []types.FieldError{{In: "path", Pointer: "/resumeId", Message: "must be of type integer"}}
+	if !slices.Equal(errors, want) {
+		t.Errorf("errors = %+v, want %+v", errors, want)
+	}
+}
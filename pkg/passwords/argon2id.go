@@ -0,0 +1,163 @@
+package passwords
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2idSaltLength = 16
+	argon2idKeyLength  = 32
+)
+
+// Argon2idParams are the cost parameters of Argon2id. The defaults are the
+// minimum OWASP recommends.
+type Argon2idParams struct {
+	// Memory is in KiB.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// Argon2id hashes passwords with Argon2id into PHC strings like
+// "$argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>", which carry the parameters
+// needed to verify them.
+type Argon2id struct {
+	params Argon2idParams
+}
+
+func NewArgon2id(params Argon2idParams) *Argon2id {
+	return &Argon2id{params: params}
+}
+
+func argon2idFromEnv() (*Argon2id, error) {
+	memory, err := service.IntFromEnv("ARGON2_MEMORY", 19*1024)
+	if err != nil {
+		return nil, err
+	}
+	if memory < 8 || memory > math.MaxUint32 {
+		return nil, errors.New("ARGON2_MEMORY is not valid")
+	}
+
+	iterations, err := service.IntFromEnv("ARGON2_ITERATIONS", 2)
+	if err != nil {
+		return nil, err
+	}
+	if iterations < 1 || iterations > math.MaxUint32 {
+		return nil, errors.New("ARGON2_ITERATIONS is not valid")
+	}
+
+	parallelism, err := service.IntFromEnv("ARGON2_PARALLELISM", 1)
+	if err != nil {
+		return nil, err
+	}
+	if parallelism < 1 || parallelism > math.MaxUint8 {
+		return nil, errors.New("ARGON2_PARALLELISM is not valid")
+	}
+
+	return NewArgon2id(Argon2idParams{
+		Memory:      uint32(memory),
+		Iterations:  uint32(iterations),
+		Parallelism: uint8(parallelism),
+	}), nil
+}
+
+func (a *Argon2id) Name() string {
+	return AlgorithmArgon2id
+}
+
+func (a *Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, argon2idSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.params.Iterations, a.params.Memory, a.params.Parallelism, argon2idKeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, a.params.Memory, a.params.Iterations, a.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (a *Argon2id) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+func (a *Argon2id) Compare(hash string, password string) error {
+	parsed, err := parseArgon2idHash(hash)
+	if err != nil {
+		return err
+	}
+
+	key := argon2.IDKey([]byte(password), parsed.salt, parsed.params.Iterations, parsed.params.Memory, parsed.params.Parallelism, uint32(len(parsed.key)))
+	if subtle.ConstantTimeCompare(key, parsed.key) != 1 {
+		return types.PasswordMismatchErr
+	}
+
+	return nil
+}
+
+func (a *Argon2id) Outdated(hash string) bool {
+	parsed, err := parseArgon2idHash(hash)
+	if err != nil {
+		return true
+	}
+
+	return parsed.version != argon2.Version ||
+		parsed.params != a.params ||
+		len(parsed.salt) != argon2idSaltLength ||
+		len(parsed.key) != argon2idKeyLength
+}
+
+type argon2idHash struct {
+	version int
+	params  Argon2idParams
+	salt    []byte
+	key     []byte
+}
+
+func parseArgon2idHash(hash string) (argon2idHash, error) {
+	fields := strings.Split(hash, "$")
+	if len(fields) != 6 || fields[0] != "" || fields[1] != AlgorithmArgon2id {
+		return argon2idHash{}, errors.New("argon2id hash is not in the PHC format")
+	}
+
+	var parsed argon2idHash
+	if _, err := fmt.Sscanf(fields[2], "v=%d", &parsed.version); err != nil {
+		return argon2idHash{}, fmt.Errorf("argon2id hash version is not valid: %w", err)
+	}
+	if parsed.version != argon2.Version {
+		return argon2idHash{}, fmt.Errorf("argon2id hash version %d is not supported", parsed.version)
+	}
+
+	_, err := fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &parsed.params.Memory, &parsed.params.Iterations, &parsed.params.Parallelism)
+	if err != nil {
+		return argon2idHash{}, fmt.Errorf("argon2id hash parameters are not valid: %w", err)
+	}
+	if parsed.params.Iterations < 1 || parsed.params.Parallelism < 1 {
+		return argon2idHash{}, errors.New("argon2id hash parameters are not valid")
+	}
+
+	parsed.salt, err = base64.RawStdEncoding.DecodeString(fields[4])
+	if err != nil {
+		return argon2idHash{}, fmt.Errorf("argon2id hash salt is not valid: %w", err)
+	}
+
+	parsed.key, err = base64.RawStdEncoding.DecodeString(fields[5])
+	if err != nil || len(parsed.key) == 0 {
+		return argon2idHash{}, errors.New("argon2id hash key is not valid")
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,144 @@
+package passwords
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	hashLength   = 2 * sha1.Size
+	prefixLength = 5
+)
+
+// BreachedList finds passwords in a local copy of a breached password list,
+// like Pwned Passwords, stored as SHA-1 hashes. Hashes are grouped in ranges
+// by their first five characters, as in the k-anonymity range API, so a
+// lookup never needs more than one range.
+//
+// The list is either a directory of range files, named after their prefix
+// and holding lines of "SUFFIX:COUNT", which is read on demand, or a single
+// file of "HASH:COUNT" lines, which is loaded into memory. Counts are
+// optional.
+type BreachedList struct {
+	dir    string
+	ranges map[string]map[string]int
+}
+
+// LoadBreachedList opens the list at path, a directory or a file.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return &BreachedList{dir: path}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	list := &BreachedList{ranges: make(map[string]map[string]int)}
+	err = readHashes(file, func(hash string, count int) error {
+		if len(hash) != hashLength {
+			return fmt.Errorf("%q is not a SHA-1 hash", hash)
+		}
+
+		prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+		if list.ranges[prefix] == nil {
+			list.ranges[prefix] = make(map[string]int)
+		}
+		list.ranges[prefix][suffix] += count
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read breached password list %s: %w", path, err)
+	}
+
+	return list, nil
+}
+
+// Count returns how often the password was seen in breaches, zero when it
+// is not in the list.
+func (l *BreachedList) Count(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	if l.dir == "" {
+		return l.ranges[prefix][suffix], nil
+	}
+
+	file, err := l.openRange(prefix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	count := 0
+	err = readHashes(file, func(rangeSuffix string, rangeCount int) error {
+		if rangeSuffix == suffix {
+			count += rangeCount
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("could not read breached password range %s: %w", prefix, err)
+	}
+
+	return count, nil
+}
+
+// openRange opens the range file of the prefix, with or without a ".txt"
+// extension.
+func (l *BreachedList) openRange(prefix string) (*os.File, error) {
+	file, err := os.Open(filepath.Join(l.dir, prefix))
+	if errors.Is(err, fs.ErrNotExist) {
+		return os.Open(filepath.Join(l.dir, prefix+".txt"))
+	}
+
+	return file, err
+}
+
+// readHashes calls fn with the upper case hash and count of every line.
+// Lines without a count count once.
+func readHashes(reader io.Reader, fn func(hash string, count int) error) error {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		hash, countValue, hasCount := strings.Cut(line, ":")
+		count := 1
+		if hasCount {
+			var err error
+			count, err = strconv.Atoi(countValue)
+			if err != nil {
+				return fmt.Errorf("count %q is not a number", countValue)
+			}
+		}
+
+		if err := fn(strings.ToUpper(hash), count); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
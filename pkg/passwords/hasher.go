@@ -0,0 +1,93 @@
+package passwords
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+// Algorithm is one format of password hashes.
+type Algorithm interface {
+	Name() string
+	Hash(password string) (string, error)
+	// Recognizes reports whether the hash is in the algorithm's format.
+	Recognizes(hash string) bool
+	// Compare returns types.PasswordMismatchErr when the password does not
+	// match the hash.
+	Compare(hash string, password string) error
+	// Outdated reports whether the hash was created with other parameters
+	// than the ones new hashes are created with.
+	Outdated(hash string) bool
+}
+
+// Hasher creates hashes with its current algorithm and verifies hashes of
+// every algorithm it knows. Hashes of another algorithm, or of the current
+// one with old parameters, are replaced after the next successful login, so
+// costs can be raised without making users reset their password.
+type Hasher struct {
+	current    Algorithm
+	algorithms []Algorithm
+}
+
+func NewHasher(current Algorithm, others ...Algorithm) *Hasher {
+	return &Hasher{
+		current:    current,
+		algorithms: append([]Algorithm{current}, others...),
+	}
+}
+
+// HasherFromEnv creates new hashes with PASSWORD_HASH_ALGORITHM, "argon2id"
+// (the default) or "bcrypt", and verifies hashes of both.
+func HasherFromEnv() (*Hasher, error) {
+	argon2id, err := argon2idFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	bcrypt, err := bcryptFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	switch algorithm := os.Getenv("PASSWORD_HASH_ALGORITHM"); algorithm {
+	case "", AlgorithmArgon2id:
+		return NewHasher(argon2id, bcrypt), nil
+	case AlgorithmBcrypt:
+		return NewHasher(bcrypt, argon2id), nil
+	default:
+		return nil, fmt.Errorf("unknown PASSWORD_HASH_ALGORITHM %q", algorithm)
+	}
+}
+
+// Algorithm returns the algorithm new hashes are created with.
+func (h *Hasher) Algorithm() Algorithm {
+	return h.current
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+// Verify compares the password with the hash, returning
+// types.PasswordMismatchErr when it does not match. needsRehash reports
+// whether the hash should be replaced with a new one of the password.
+func (h *Hasher) Verify(hash string, password string) (needsRehash bool, err error) {
+	for _, algorithm := range h.algorithms {
+		if !algorithm.Recognizes(hash) {
+			continue
+		}
+
+		if err := algorithm.Compare(hash, password); err != nil {
+			return false, err
+		}
+
+		return algorithm != h.current || algorithm.Outdated(hash), nil
+	}
+
+	return false, errors.New("password hash has an unknown format")
+}
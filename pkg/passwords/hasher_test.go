@@ -0,0 +1,191 @@
+package passwords
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testArgon2idParams = Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func TestArgon2id(t *testing.T) {
+	argon2id := NewArgon2id(testArgon2idParams)
+
+	hash, err := argon2id.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("hash = %s", hash)
+	}
+	if !argon2id.Recognizes(hash) {
+		t.Error("hash is not recognized")
+	}
+
+	if err := argon2id.Compare(hash, "correct horse"); err != nil {
+		t.Errorf("Compare = %v", err)
+	}
+	if err := argon2id.Compare(hash, "wrong horse"); !errors.Is(err, types.PasswordMismatchErr) {
+		t.Errorf("Compare with the wrong password = %v", err)
+	}
+
+	other, err := argon2id.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == hash {
+		t.Error("hashes of the same password share a salt")
+	}
+
+	if argon2id.Outdated(hash) {
+		t.Error("a new hash is outdated")
+	}
+	if !NewArgon2id(Argon2idParams{Memory: 128, Iterations: 1, Parallelism: 1}).Outdated(hash) {
+		t.Error("a hash with less memory is not outdated")
+	}
+}
+
+func TestArgon2idInvalidHashes(t *testing.T) {
+	argon2id := NewArgon2id(testArgon2idParams)
+
+	for _, hash := range []string{
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+		"$argon2id$v=16$m=64,t=1,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$not base64$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$",
+	} {
+		if err := argon2id.Compare(hash, "password"); err == nil || errors.Is(err, types.PasswordMismatchErr) {
+			t.Errorf("Compare(%q) = %v, want a format error", hash, err)
+		}
+		if !argon2id.Outdated(hash) {
+			t.Errorf("invalid hash %q is not outdated", hash)
+		}
+	}
+}
+
+func TestBcrypt(t *testing.T) {
+	b := NewBcrypt(bcrypt.MinCost)
+
+	hash, err := b.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.Recognizes(hash) {
+		t.Errorf("hash %s is not recognized", hash)
+	}
+	if err := b.Compare(hash, "correct horse"); err != nil {
+		t.Errorf("Compare = %v", err)
+	}
+	if err := b.Compare(hash, "wrong horse"); !errors.Is(err, types.PasswordMismatchErr) {
+		t.Errorf("Compare with the wrong password = %v", err)
+	}
+
+	if b.Outdated(hash) {
+		t.Error("a new hash is outdated")
+	}
+	if !NewBcrypt(bcrypt.MinCost + 1).Outdated(hash) {
+		t.Error("a cheaper hash is not outdated")
+	}
+
+	if _, err := b.Hash(strings.Repeat("x", 73)); err == nil {
+		t.Error("a password longer than 72 bytes was hashed")
+	}
+}
+
+func TestHasherVerify(t *testing.T) {
+	argon2id := NewArgon2id(testArgon2idParams)
+	b := NewBcrypt(bcrypt.MinCost)
+	hasher := NewHasher(argon2id, b)
+
+	current, err := hasher.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !argon2id.Recognizes(current) {
+		t.Fatalf("hash %s is not created with the current algorithm", current)
+	}
+
+	legacy, err := b.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outdated, err := NewArgon2id(Argon2idParams{Memory: 32, Iterations: 1, Parallelism: 1}).Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		hash        string
+		password    string
+		needsRehash bool
+		err         error
+	}{
+		{"current", current, "password", false, nil},
+		{"other algorithm", legacy, "password", true, nil},
+		{"outdated parameters", outdated, "password", true, nil},
+		{"wrong password", current, "wrong", false, types.PasswordMismatchErr},
+		{"wrong password for other algorithm", legacy, "wrong", false, types.PasswordMismatchErr},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			needsRehash, err := hasher.Verify(test.hash, test.password)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("err = %v, want %v", err, test.err)
+			}
+			if needsRehash != test.needsRehash {
+				t.Errorf("needsRehash = %v, want %v", needsRehash, test.needsRehash)
+			}
+		})
+	}
+
+	if _, err := hasher.Verify("$1$md5$hash", "password"); err == nil {
+		t.Error("a hash of an unknown algorithm was verified")
+	}
+}
+
+func TestHasherFromEnv(t *testing.T) {
+	t.Setenv("ARGON2_MEMORY", "64")
+	t.Setenv("ARGON2_ITERATIONS", "1")
+	t.Setenv("ARGON2_PARALLELISM", "1")
+	t.Setenv("BCRYPT_COST", "4")
+
+	for algorithm, want := range map[string]string{"": AlgorithmArgon2id, "argon2id": AlgorithmArgon2id, "bcrypt": AlgorithmBcrypt} {
+		t.Setenv("PASSWORD_HASH_ALGORITHM", algorithm)
+
+		hasher, err := HasherFromEnv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name := hasher.Algorithm().Name(); name != want {
+			t.Errorf("PASSWORD_HASH_ALGORITHM=%q uses %s, want %s", algorithm, name, want)
+		}
+	}
+
+	invalid := []map[string]string{
+		{"PASSWORD_HASH_ALGORITHM": "md5"},
+		{"ARGON2_MEMORY": "4"},
+		{"ARGON2_ITERATIONS": "0"},
+		{"ARGON2_PARALLELISM": "256"},
+		{"BCRYPT_COST": "32"},
+	}
+
+	for _, env := range invalid {
+		t.Run("", func(t *testing.T) {
+			t.Setenv("PASSWORD_HASH_ALGORITHM", "")
+			for key, value := range env {
+				t.Setenv(key, value)
+			}
+
+			if _, err := HasherFromEnv(); err == nil {
+				t.Errorf("expected an error for %v", env)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Package passwords decides whether a new password is good enough: long
+// enough, hard to guess and not known from a data breach, and hashes
+// passwords for storage.
+package passwords
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+)
+
+type Policy struct {
+	// MinLength is counted in characters.
+	MinLength int
+	// MaxLength is counted in bytes. It cannot be more than 72 when passwords
+	// are hashed with bcrypt.
+	MaxLength int
+	// MinScore is the lowest Strength.Score accepted, 0 turns the strength
+	// check off.
+	MinScore int
+
+	// Breached is nil when no breached password list is configured.
+	Breached *BreachedList
+	// BreachedMinCount is how often a password has to have been seen in
+	// breaches to be rejected.
+	BreachedMinCount int
+}
+
+// PolicyFromEnv reads the policy from PASSWORD_* variables. The breached
+// password list at BREACHED_PASSWORDS_PATH is loaded right away, the check
+// is off when the variable is not set.
+func PolicyFromEnv() (Policy, error) {
+	var policy Policy
+	var err error
+
+	policy.MinLength, err = service.IntFromEnv("PASSWORD_MIN_LENGTH", 8)
+	if err != nil {
+		return Policy{}, err
+	}
+	if policy.MinLength < 1 {
+		return Policy{}, errors.New("PASSWORD_MIN_LENGTH must be at least 1")
+	}
+
+	policy.MaxLength, err = service.IntFromEnv("PASSWORD_MAX_LENGTH", 256)
+	if err != nil {
+		return Policy{}, err
+	}
+	if policy.MaxLength < policy.MinLength {
+		return Policy{}, errors.New("PASSWORD_MAX_LENGTH must not be less than PASSWORD_MIN_LENGTH")
+	}
+
+	policy.MinScore, err = service.IntFromEnv("PASSWORD_MIN_SCORE", 3)
+	if err != nil {
+		return Policy{}, err
+	}
+	if policy.MinScore < 0 || policy.MinScore > 4 {
+		return Policy{}, errors.New("PASSWORD_MIN_SCORE must be between 0 and 4")
+	}
+
+	policy.BreachedMinCount, err = service.IntFromEnv("PASSWORD_BREACHED_MIN_COUNT", 1)
+	if err != nil {
+		return Policy{}, err
+	}
+
+	if path := os.Getenv("BREACHED_PASSWORDS_PATH"); path != "" {
+		policy.Breached, err = LoadBreachedList(path)
+		if err != nil {
+			return Policy{}, fmt.Errorf("BREACHED_PASSWORDS_PATH is not valid: %w", err)
+		}
+	}
+
+	return policy, nil
+}
+
+// Check returns what is wrong with the password, nothing when it satisfies
+// the policy. userInputs are values of the account an attacker would try
+// first, like the name and email.
+func (p Policy) Check(password string, userInputs []string) ([]string, error) {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return []string{fmt.Sprintf("must be at least %d characters long", p.MinLength)}, nil
+	}
+	if len(password) > p.MaxLength {
+		return []string{fmt.Sprintf("must be at most %d bytes long", p.MaxLength)}, nil
+	}
+
+	problems := make([]string, 0)
+
+	if p.Breached != nil {
+		count, err := p.Breached.Count(password)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 && count >= p.BreachedMinCount {
+			problems = append(problems, "appeared in a data breach and must not be used")
+		}
+	}
+
+	if p.MinScore > 0 {
+		strength := Estimate(password, userInputs)
+		if strength.Score < p.MinScore {
+			message := "is too easy to guess."
+			if strength.Warning != "" {
+				message += " " + strength.Warning
+			}
+			if len(strength.Suggestions) > 0 {
+				message += " " + strings.Join(strength.Suggestions, " ")
+			}
+			problems = append(problems, message)
+		}
+	}
+
+	return problems, nil
+}
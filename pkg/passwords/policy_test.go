@@ -0,0 +1,235 @@
+package passwords
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// passwordHash is the SHA-1 hash of "password".
+const passwordHash = "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+
+func TestPolicyCheck(t *testing.T) {
+	policy := Policy{MinLength: 8, MaxLength: 64, MinScore: 3}
+
+	tests := []struct {
+		name     string
+		password string
+		inputs   []string
+		problem  string
+	}{
+		{"too short", "a1b2c3", nil, "must be at least 8 characters long"},
+		{"length counts characters", "žžžžžžžž", nil, "is too easy to guess."},
+		{"too long", strings.Repeat("x", 65), nil, "must be at most 64 bytes long"},
+		{"common", "password1", nil, "is too easy to guess. This is a top-100 common password."},
+		{"user input", "janedoe1985", []string{"Jane", "jane.doe@example.com"}, "is too easy to guess. Passwords based on your name or email are easy to guess."},
+		{"strong", "correct horse battery staple", nil, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problems, err := policy.Check(test.password, test.inputs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.problem == "" {
+				if len(problems) != 0 {
+					t.Errorf("problems = %q, want none", problems)
+				}
+				return
+			}
+			if len(problems) != 1 || !strings.HasPrefix(problems[0], test.problem) {
+				t.Errorf("problems = %q, want one starting with %q", problems, test.problem)
+			}
+		})
+	}
+}
+
+func TestPolicyCheckWithoutStrength(t *testing.T) {
+	policy := Policy{MinLength: 8, MaxLength: 64}
+
+	problems, err := policy.Check("password", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("problems = %q, want none when MinScore is 0", problems)
+	}
+}
+
+func TestPolicyCheckBreached(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	content := passwordHash + ":3\n" + strings.ToLower(passwordHash) + ":2\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := LoadBreachedList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		minCount int
+		breached bool
+	}{
+		{1, true},
+		{5, true},
+		{6, false},
+	}
+
+	for _, test := range tests {
+		policy := Policy{MinLength: 8, MaxLength: 64, Breached: list, BreachedMinCount: test.minCount}
+		problems, err := policy.Check("password", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		breached := slices.Contains(problems, "appeared in a data breach and must not be used")
+		if breached != test.breached {
+			t.Errorf("with BreachedMinCount %d problems = %q", test.minCount, problems)
+		}
+	}
+}
+
+func TestBreachedListDirectory(t *testing.T) {
+	dir := t.TempDir()
+	prefix, suffix := passwordHash[:prefixLength], passwordHash[prefixLength:]
+	if err := os.WriteFile(filepath.Join(dir, prefix+".txt"), []byte("0000000000000000000000000000000000A:9\n"+suffix+":42\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := LoadBreachedList(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := list.Count("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 42 {
+		t.Errorf("count = %d, want 42", count)
+	}
+
+	count, err = list.Count("a password with a range file that does not exist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("count = %d, want 0", count)
+	}
+}
+
+func TestLoadBreachedListInvalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"short hash":   "5BAA61E4:1\n",
+		"count is NaN": passwordHash + ":many\n",
+	} {
+		path := filepath.Join(t.TempDir(), "breached.txt")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadBreachedList(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestPolicyFromEnv(t *testing.T) {
+	t.Setenv("PASSWORD_MIN_LENGTH", "")
+	t.Setenv("PASSWORD_MAX_LENGTH", "")
+	t.Setenv("PASSWORD_MIN_SCORE", "")
+	t.Setenv("PASSWORD_BREACHED_MIN_COUNT", "")
+	t.Setenv("BREACHED_PASSWORDS_PATH", "")
+
+	policy, err := PolicyFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.MinLength != 8 || policy.MaxLength != 256 || policy.MinScore != 3 || policy.BreachedMinCount != 1 || policy.Breached != nil {
+		t.Errorf("default policy = %+v", policy)
+	}
+
+	invalid := []map[string]string{
+		{"PASSWORD_MIN_LENGTH": "0"},
+		{"PASSWORD_MIN_LENGTH": "12", "PASSWORD_MAX_LENGTH": "10"},
+		{"PASSWORD_MIN_SCORE": "5"},
+		{"PASSWORD_MIN_SCORE": "-1"},
+		{"BREACHED_PASSWORDS_PATH": filepath.Join(t.TempDir(), "missing")},
+	}
+
+	for _, env := range invalid {
+		t.Run("", func(t *testing.T) {
+			for key, value := range env {
+				t.Setenv(key, value)
+			}
+
+			if _, err := PolicyFromEnv(); err == nil {
+				t.Errorf("expected an error for %v", env)
+			}
+		})
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		password string
+		maxScore int
+		warning  string
+	}{
+		{"password", 0, "This is a top-10 common password."},
+		{"drowssap", 1, "This is a top-10 common password."},
+		{"P@ssw0rd", 1, "This is a top-100 common password."},
+		{"hjkl;'", 1, "Straight rows of keys are easy to guess."},
+		{"aaaaaaaaaa", 1, `Repeats like "aaa" are easy to guess.`},
+		{"abcdefghij", 1, "Sequences like abc or 6543 are easy to guess."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.password, func(t *testing.T) {
+			strength := Estimate(test.password, nil)
+			if strength.Score > test.maxScore {
+				t.Errorf("score = %d, want at most %d", strength.Score, test.maxScore)
+			}
+			if strength.Warning != test.warning {
+				t.Errorf("warning = %q, want %q", strength.Warning, test.warning)
+			}
+			if len(strength.Suggestions) == 0 {
+				t.Error("expected suggestions")
+			}
+		})
+	}
+}
+
+func TestEstimateStrong(t *testing.T) {
+	for _, password := range []string{"correct horse battery staple", "vT8#qLw2!zR9pX"} {
+		strength := Estimate(password, nil)
+		if strength.Score < 3 {
+			t.Errorf("%q score = %d, want at least 3", password, strength.Score)
+		}
+		if strength.Warning != "" || len(strength.Suggestions) != 0 {
+			t.Errorf("%q has feedback %q %q", password, strength.Warning, strength.Suggestions)
+		}
+	}
+}
+
+// Long repeats used to take seconds, as the units of every repeat were
+// estimated again for every position they repeat at.
+func TestEstimateLongPassword(t *testing.T) {
+	for _, unit := range []string{"a", "ab", "abc1", "pass"} {
+		start := time.Now()
+		strength := Estimate(strings.Repeat(unit, 10_000), nil)
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("estimating repeats of %q took %s", unit, elapsed)
+		}
+		if strength.Score > 1 {
+			t.Errorf("repeats of %q score %d, want at most 1", unit, strength.Score)
+		}
+	}
+}
@@ -0,0 +1,558 @@
+package passwords
+
+import (
+	_ "embed"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// maxEstimatedLength bounds the work of estimating long passwords. The
+	// rest of the password only adds to its strength.
+	maxEstimatedLength = 100
+
+	// bruteforceCardinality is the number of guesses per character that no
+	// pattern explains.
+	bruteforceCardinality = 10
+	// minSubmatchGuesses keeps a weak part of a longer password from making
+	// the whole password look weaker than it is.
+	minSubmatchGuesses = 50
+
+	minYear = 1900
+	maxYear = 2050
+	// minYearSpace is how many years around the current one attackers try
+	// first.
+	minYearSpace = 20
+)
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// commonPasswords maps passwords to their rank by popularity, starting at 1.
+var commonPasswords = rankedDictionary(strings.Fields(commonPasswordList))
+
+var l33tSubstitutions = map[rune]rune{
+	'4': 'a',
+	'@': 'a',
+	'8': 'b',
+	'(': 'c',
+	'3': 'e',
+	'6': 'g',
+	'1': 'i',
+	'!': 'i',
+	'|': 'l',
+	'0': 'o',
+	'$': 's',
+	'5': 's',
+	'7': 't',
+	'+': 't',
+	'2': 'z',
+}
+
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+}
+
+// keyboardNeighbours maps every key of a QWERTY keyboard to the keys next to
+// it. Rows are shifted by half a key, so a key touches two keys of the row
+// above and below it.
+var keyboardNeighbours = func() map[rune]map[rune]bool {
+	position := make(map[rune][2]int)
+	for row, keys := range keyboardRows {
+		for column, key := range keys {
+			position[key] = [2]int{row, column}
+		}
+	}
+
+	neighbours := make(map[rune]map[rune]bool)
+	for key, p := range position {
+		neighbours[key] = make(map[rune]bool)
+		for other, q := range position {
+			rowDistance, columnDistance := q[0]-p[0], q[1]-p[1]
+			if (rowDistance == 0 && (columnDistance == -1 || columnDistance == 1)) ||
+				(rowDistance == -1 && (columnDistance == 0 || columnDistance == 1)) ||
+				(rowDistance == 1 && (columnDistance == 0 || columnDistance == -1)) {
+				neighbours[key][other] = true
+			}
+		}
+	}
+
+	return neighbours
+}()
+
+// Strength estimates how many guesses an attacker needs to find a password,
+// in the spirit of zxcvbn: the password is split into the known patterns,
+// like common passwords, names, sequences and keyboard rows, that explain it
+// with the fewest guesses.
+type Strength struct {
+	// Score is 0 (too guessable) to 4 (very unguessable).
+	Score   int
+	Guesses float64
+	// Warning explains what makes a weak password easy to guess.
+	Warning     string
+	Suggestions []string
+}
+
+type patternKind string
+
+const (
+	patternCommon    patternKind = "common"
+	patternUserInput patternKind = "user_input"
+	patternSequence  patternKind = "sequence"
+	patternRepeat    patternKind = "repeat"
+	patternKeyboard  patternKind = "keyboard"
+	patternYear      patternKind = "year"
+)
+
+type match struct {
+	kind patternKind
+	// start and end are the rune offsets of the match, end is exclusive.
+	start, end int
+	guesses    float64
+
+	rank     int
+	reversed bool
+	l33t     bool
+	// uppercase is set when the matched word had capital letters.
+	uppercase bool
+	// repeatedUnit is the repeated part of repeat matches.
+	repeatedUnit string
+}
+
+func (m match) length() int {
+	return m.end - m.start
+}
+
+// Estimate returns the strength of the password. userInputs are values an
+// attacker would try first, like the user's name and email.
+func Estimate(password string, userInputs []string) Strength {
+	runes := []rune(password)
+	if len(runes) > maxEstimatedLength {
+		runes = runes[:maxEstimatedLength]
+	}
+
+	matches := findMatches(runes, rankedDictionary(splitUserInputs(userInputs)), make(map[string]float64))
+	guesses, sequence := mostGuessableSequence(runes, matches)
+
+	strength := Strength{Score: score(guesses), Guesses: guesses}
+	strength.Warning, strength.Suggestions = feedback(strength.Score, sequence)
+
+	return strength
+}
+
+func rankedDictionary(words []string) map[string]int {
+	dictionary := make(map[string]int, len(words))
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if _, ok := dictionary[word]; !ok {
+			dictionary[word] = i + 1
+		}
+	}
+
+	return dictionary
+}
+
+// splitUserInputs returns the inputs along with the words they consist of, so
+// that "jane.doe@example.com" also matches "jane" and "doe".
+func splitUserInputs(userInputs []string) []string {
+	words := make([]string, 0, len(userInputs))
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		if len([]rune(input)) >= 3 {
+			words = append(words, input)
+		}
+
+		for _, word := range strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if len([]rune(word)) >= 3 && word != input {
+				words = append(words, word)
+			}
+		}
+	}
+
+	return words
+}
+
+// findMatches finds every pattern in the password. unitGuesses caches the
+// guesses of repeated units, which repeat matches of the units themselves
+// would otherwise estimate over and over.
+func findMatches(password []rune, userInputs map[string]int, unitGuesses map[string]float64) []match {
+	matches := dictionaryMatches(password, commonPasswords, patternCommon)
+	matches = append(matches, dictionaryMatches(password, userInputs, patternUserInput)...)
+	matches = append(matches, sequenceMatches(password)...)
+	matches = append(matches, repeatMatches(password, unitGuesses)...)
+	matches = append(matches, keyboardMatches(password)...)
+	matches = append(matches, yearMatches(password)...)
+
+	return matches
+}
+
+func dictionaryMatches(password []rune, dictionary map[string]int, kind patternKind) []match {
+	lower := []rune(strings.ToLower(string(password)))
+	unl33t := make([]rune, len(lower))
+	for i, r := range lower {
+		if substitute, ok := l33tSubstitutions[r]; ok {
+			unl33t[i] = substitute
+		} else {
+			unl33t[i] = r
+		}
+	}
+
+	matches := make([]match, 0)
+	for start := 0; start < len(password); start++ {
+		for end := start + 1; end <= len(password); end++ {
+			word := string(lower[start:end])
+			candidates := []struct {
+				word           string
+				reversed, l33t bool
+			}{
+				{word, false, false},
+				{reverse(word), true, false},
+				{string(unl33t[start:end]), false, true},
+			}
+
+			for _, candidate := range candidates {
+				if candidate.l33t && candidate.word == word {
+					continue
+				}
+
+				rank, ok := dictionary[candidate.word]
+				if !ok {
+					continue
+				}
+
+				m := match{
+					kind:      kind,
+					start:     start,
+					end:       end,
+					rank:      rank,
+					reversed:  candidate.reversed,
+					l33t:      candidate.l33t,
+					uppercase: string(password[start:end]) != word,
+				}
+				m.guesses = float64(rank) * uppercaseVariations(password[start:end])
+				if m.reversed {
+					m.guesses *= 2
+				}
+				if m.l33t {
+					m.guesses *= 2
+				}
+				matches = append(matches, m)
+				break
+			}
+		}
+	}
+
+	return matches
+}
+
+// uppercaseVariations is how many ways of capitalizing a word an attacker
+// tries before the one used. Capitalizing the first or every letter is tried
+// first.
+func uppercaseVariations(word []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range word {
+		if unicode.IsUpper(r) {
+			upper++
+		} else if unicode.IsLower(r) {
+			lower++
+		}
+	}
+
+	switch {
+	case upper == 0:
+		return 1
+	case lower == 0, upper == 1 && unicode.IsUpper(word[0]):
+		return 2
+	}
+
+	variations := 0.0
+	for i := 1; i <= min(upper, lower); i++ {
+		variations += binomial(upper+lower, i)
+	}
+
+	return variations
+}
+
+func binomial(n, k int) float64 {
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+
+	return result
+}
+
+// sequenceMatches finds runs like "abc", "7531" and "zyx" whose characters
+// are the same distance apart.
+func sequenceMatches(password []rune) []match {
+	matches := make([]match, 0)
+	for start := 0; start < len(password)-2; {
+		delta := password[start+1] - password[start]
+		end := start + 2
+		for end < len(password) && password[end]-password[end-1] == delta {
+			end++
+		}
+
+		if end-start >= 3 && delta != 0 && delta >= -5 && delta <= 5 {
+			var base float64
+			switch first := unicode.ToLower(password[start]); {
+			case strings.ContainsRune("a19z0", first):
+				base = 4
+			case unicode.IsDigit(first):
+				base = 10
+			default:
+				base = 26
+			}
+			if delta < 0 {
+				base *= 2
+			}
+			matches = append(matches, match{
+				kind:    patternSequence,
+				start:   start,
+				end:     end,
+				guesses: base * float64(end-start),
+			})
+		}
+
+		start = end - 1
+	}
+
+	return matches
+}
+
+// repeatMatches finds characters and groups of characters that are repeated
+// right after each other, like "aaa" and "abcabc".
+func repeatMatches(password []rune, unitGuesses map[string]float64) []match {
+	matches := make([]match, 0)
+	for start := 0; start < len(password); start++ {
+		for unit := 1; start+2*unit <= len(password); unit++ {
+			count := 1
+			for start+(count+1)*unit <= len(password) &&
+				string(password[start+count*unit:start+(count+1)*unit]) == string(password[start:start+unit]) {
+				count++
+			}
+
+			// A single character has to repeat three times to stand out.
+			if count < 2 || (unit == 1 && count < 3) {
+				continue
+			}
+
+			repeated := string(password[start : start+unit])
+			guesses, ok := unitGuesses[repeated]
+			if !ok {
+				guesses = math.Pow(bruteforceCardinality, float64(unit))
+				if unit > 1 {
+					guesses, _ = mostGuessableSequence(password[start:start+unit], findMatches(password[start:start+unit], nil, unitGuesses))
+				}
+				unitGuesses[repeated] = guesses
+			}
+			matches = append(matches, match{
+				kind:         patternRepeat,
+				start:        start,
+				end:          start + count*unit,
+				guesses:      guesses * float64(count),
+				repeatedUnit: repeated,
+			})
+		}
+	}
+
+	return matches
+}
+
+// keyboardMatches finds runs of keys next to each other, like "qwerty" and
+// "zaqwsx".
+func keyboardMatches(password []rune) []match {
+	lower := []rune(strings.ToLower(string(password)))
+
+	matches := make([]match, 0)
+	for start := 0; start < len(lower)-2; {
+		end := start + 1
+		for end < len(lower) && keyboardNeighbours[lower[end-1]][lower[end]] {
+			end++
+		}
+
+		if end-start >= 3 {
+			matches = append(matches, match{
+				kind:    patternKeyboard,
+				start:   start,
+				end:     end,
+				guesses: float64(len(keyboardNeighbours)) * math.Pow(4, float64(end-start-1)),
+			})
+		}
+
+		start = max(end-1, start+1)
+	}
+
+	return matches
+}
+
+// yearMatches finds years that are likely to be birthdays or anniversaries.
+func yearMatches(password []rune) []match {
+	matches := make([]match, 0)
+	currentYear := time.Now().Year()
+	for start := 0; start+4 <= len(password); start++ {
+		year, err := strconv.Atoi(string(password[start : start+4]))
+		if err != nil || year < minYear || year > maxYear {
+			continue
+		}
+
+		yearSpace := currentYear - year
+		if yearSpace < 0 {
+			yearSpace = -yearSpace
+		}
+		matches = append(matches, match{
+			kind:    patternYear,
+			start:   start,
+			end:     start + 4,
+			guesses: float64(max(yearSpace, minYearSpace)),
+		})
+	}
+
+	return matches
+}
+
+// mostGuessableSequence finds the matches covering the password that
+// together need the fewest guesses. Characters no match covers are
+// bruteforced.
+func mostGuessableSequence(password []rune, matches []match) (float64, []match) {
+	n := len(password)
+	if n == 0 {
+		return 1, nil
+	}
+
+	guesses := make([]float64, n+1)
+	last := make([]*match, n+1)
+	guesses[0] = 1
+	for end := 1; end <= n; end++ {
+		guesses[end] = guesses[end-1] * bruteforceCardinality
+
+		for i := range matches {
+			m := &matches[i]
+			if m.end != end {
+				continue
+			}
+
+			matchGuesses := m.guesses
+			if m.length() < n {
+				matchGuesses = max(matchGuesses, minSubmatchGuesses)
+			}
+			if total := guesses[m.start] * matchGuesses; total < guesses[end] {
+				guesses[end] = total
+				last[end] = m
+			}
+		}
+	}
+
+	sequence := make([]match, 0)
+	for end := n; end > 0; {
+		if last[end] == nil {
+			end--
+			continue
+		}
+		sequence = append(sequence, *last[end])
+		end = last[end].start
+	}
+	slices.Reverse(sequence)
+
+	return guesses[n], sequence
+}
+
+// score uses the thresholds of zxcvbn, which put online attacks with
+// throttling at 10^3 guesses and offline attacks on slow hashes at 10^10.
+func score(guesses float64) int {
+	const delta = 5
+	switch {
+	case guesses < 1e3+delta:
+		return 0
+	case guesses < 1e6+delta:
+		return 1
+	case guesses < 1e8+delta:
+		return 2
+	case guesses < 1e10+delta:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func feedback(score int, sequence []match) (string, []string) {
+	if score > 2 {
+		return "", nil
+	}
+
+	if len(sequence) == 0 {
+		return "", []string{
+			"Use a few words, avoid common phrases.",
+			"No need for symbols, digits, or uppercase letters.",
+		}
+	}
+
+	longest := sequence[0]
+	for _, m := range sequence[1:] {
+		if m.length() > longest.length() {
+			longest = m
+		}
+	}
+
+	suggestions := []string{"Add another word or two. Uncommon words are better."}
+	switch longest.kind {
+	case patternCommon, patternUserInput:
+		var warning string
+		switch {
+		case longest.kind == patternUserInput:
+			warning = "Passwords based on your name or email are easy to guess."
+		case longest.rank <= 10:
+			warning = "This is a top-10 common password."
+		case longest.rank <= 100:
+			warning = "This is a top-100 common password."
+		default:
+			warning = "This is a very common password."
+		}
+		if longest.uppercase {
+			suggestions = append(suggestions, "Capitalization doesn't help very much.")
+		}
+		if longest.reversed {
+			suggestions = append(suggestions, "Reversed words aren't much harder to guess.")
+		}
+		if longest.l33t {
+			suggestions = append(suggestions, "Predictable substitutions like '@' instead of 'a' don't help very much.")
+		}
+		return warning, suggestions
+
+	case patternSequence:
+		return "Sequences like abc or 6543 are easy to guess.", append(suggestions, "Avoid sequences.")
+
+	case patternRepeat:
+		suggestions = append(suggestions, "Avoid repeated words and characters.")
+		if len([]rune(longest.repeatedUnit)) == 1 {
+			return `Repeats like "aaa" are easy to guess.`, suggestions
+		}
+		return `Repeats like "abcabcabc" are only slightly harder to guess than "abc".`, suggestions
+
+	case patternKeyboard:
+		return "Straight rows of keys are easy to guess.", append(suggestions, "Use a longer keyboard pattern with more turns.")
+
+	case patternYear:
+		return "Recent years are easy to guess.", append(suggestions, "Avoid recent years and years that are associated with you.")
+	}
+
+	return "", suggestions
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+
+	return string(runes)
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+// MemoryLimiter keeps buckets in the process.
+type MemoryLimiter struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	prunedAt time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{buckets: make(map[string]*bucket)}
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, rule Rule, key string) (Result, error) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune(now)
+
+	id := rule.Name + ":" + key
+	b, ok := l.buckets[id]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Limit)}
+		l.buckets[id] = b
+	}
+
+	tokens := min(b.tokens+now.Sub(b.updatedAt).Seconds()*rule.rate(), float64(rule.Limit))
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+
+	result := newResult(rule, tokens, allowed)
+	b.tokens = tokens
+	b.updatedAt = now
+	b.fullAt = now.Add(result.ResetAfter)
+
+	return result, nil
+}
+
+func (l *MemoryLimiter) prune(now time.Time) {
+	if now.Sub(l.prunedAt) < pruneInterval {
+		return
+	}
+	l.prunedAt = now
+
+	for id, b := range l.buckets {
+		if b.fullAt.Before(now) {
+			delete(l.buckets, id)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
+)
+
+// PostgresLimiter keeps buckets in the rate_limit_buckets table, so every
+// replica shares them.
+type PostgresLimiter struct {
+	db *sql.DB
+	// prunedAt is the unix time buckets were last pruned by this process.
+	prunedAt atomic.Int64
+}
+
+func NewPostgresLimiter(connection *db.DbConnection) *PostgresLimiter {
+	return &PostgresLimiter{db: connection.DB}
+}
+
+// Allow refills and takes from the bucket in one statement. The update is
+// skipped when the bucket has no token left, so no row is returned.
+func (l *PostgresLimiter) Allow(ctx context.Context, rule Rule, key string) (result Result, err error) {
+	query := `
+        INSERT INTO rate_limit_buckets AS bucket (key, tokens, updated_at, full_at)
+        VALUES ($1, $2::double precision - 1, now(), now() + make_interval(secs => $4))
+        ON CONFLICT (key) DO UPDATE SET
+            tokens = least($2, bucket.tokens + extract(epoch FROM now() - bucket.updated_at) * $3::double precision) - 1,
+            updated_at = now(),
+            full_at = now() + make_interval(secs => $4)
+        WHERE least($2, bucket.tokens + extract(epoch FROM now() - bucket.updated_at) * $3) >= 1
+        RETURNING tokens`
+
+	if err := l.prune(ctx); err != nil {
+		return Result{}, err
+	}
+
+	ctx, span := db.StartQuerySpan(ctx, "rate_limit_buckets.upsert", query)
+	defer func() { tracing.End(span, err) }()
+
+	id := rule.Name + ":" + key
+	limit := float64(rule.Limit)
+
+	var tokens float64
+	err = l.db.QueryRowContext(ctx, query, id, limit, rule.rate(), rule.Period.Seconds()).Scan(&tokens)
+	if err == nil {
+		return newResult(rule, tokens, true), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Result{}, err
+	}
+
+	err = l.db.QueryRowContext(ctx, `
+        SELECT least($2, tokens + extract(epoch FROM now() - updated_at) * $3)
+        FROM rate_limit_buckets WHERE key = $1`,
+		id, limit, rule.rate(),
+	).Scan(&tokens)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Pruned by another replica in the meantime, so it is full.
+		return newResult(rule, limit, false), nil
+	}
+	if err != nil {
+		return Result{}, err
+	}
+
+	return newResult(rule, tokens, false), nil
+}
+
+// prune removes buckets that are full again, at most once per pruneInterval
+// in every process.
+func (l *PostgresLimiter) prune(ctx context.Context) (err error) {
+	now := time.Now()
+	prunedAt := l.prunedAt.Load()
+	if now.Sub(time.Unix(prunedAt, 0)) < pruneInterval || !l.prunedAt.CompareAndSwap(prunedAt, now.Unix()) {
+		return nil
+	}
+
+	query := `DELETE FROM rate_limit_buckets WHERE full_at < now()`
+
+	ctx, span := db.StartQuerySpan(ctx, "rate_limit_buckets.delete", query)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = l.db.ExecContext(ctx, query)
+	return err
+}
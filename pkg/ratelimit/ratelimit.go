@@ -0,0 +1,115 @@
+// Package ratelimit limits how often a key, like a user or an address, can
+// make requests using token buckets.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+)
+
+// pruneInterval is how often buckets that filled up again are removed.
+const pruneInterval = time.Minute
+
+// Rule allows Limit requests at once, refilling the bucket at a rate of Limit
+// requests per Period.
+type Rule struct {
+	// Name keeps the buckets of rules that limit the same key apart.
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+func (r Rule) Enabled() bool {
+	return r.Limit > 0
+}
+
+// rate is how many tokens are added to the bucket every second.
+func (r Rule) rate() float64 {
+	return float64(r.Limit) / r.Period.Seconds()
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until the next request is allowed, zero when
+	// this one was.
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again.
+	ResetAfter time.Duration
+}
+
+// newResult describes the bucket holding tokens after the request.
+func newResult(rule Rule, tokens float64, allowed bool) Result {
+	result := Result{
+		Allowed:    allowed,
+		Limit:      rule.Limit,
+		Remaining:  max(int(math.Floor(tokens)), 0),
+		ResetAfter: time.Duration((float64(rule.Limit) - tokens) / rule.rate() * float64(time.Second)),
+	}
+
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / rule.rate() * float64(time.Second))
+	}
+
+	return result
+}
+
+type Limiter interface {
+	// Allow takes a token from the key's bucket of the rule and reports
+	// whether there was one.
+	Allow(ctx context.Context, rule Rule, key string) (Result, error)
+}
+
+// FromEnv creates the limiter selected with RATE_LIMIT_STORE: "memory" (the
+// default) or "postgres". Memory buckets are per process, so deployments
+// with more than one replica should share them in Postgres.
+func FromEnv(connection *db.DbConnection) (Limiter, error) {
+	switch os.Getenv("RATE_LIMIT_STORE") {
+	case "", "memory":
+		return NewMemoryLimiter(), nil
+	case "postgres":
+		return NewPostgresLimiter(connection), nil
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_STORE %q", os.Getenv("RATE_LIMIT_STORE"))
+	}
+}
+
+// RuleFromEnv reads a rule written as "<limit>/<period>", e.g. "30/1m". The
+// value "off" disables the rule.
+func RuleFromEnv(name string, defaultRule Rule) (Rule, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultRule, nil
+	}
+
+	rule := Rule{Name: defaultRule.Name}
+	if value == "off" {
+		return rule, nil
+	}
+
+	limit, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return Rule{}, fmt.Errorf("%s must be written as <limit>/<period>", name)
+	}
+
+	var err error
+	rule.Limit, err = strconv.Atoi(limit)
+	if err != nil || rule.Limit < 1 {
+		return Rule{}, fmt.Errorf("%s has an invalid limit %q", name, limit)
+	}
+
+	rule.Period, err = time.ParseDuration(period)
+	if err != nil || rule.Period <= 0 {
+		return Rule{}, fmt.Errorf("%s has an invalid period %q", name, period)
+	}
+
+	return rule, nil
+}
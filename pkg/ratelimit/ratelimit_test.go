@@ -0,0 +1,169 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db/dbtest"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	testLimiter(t, NewMemoryLimiter())
+}
+
+func TestPostgresLimiter(t *testing.T) {
+	testLimiter(t, NewPostgresLimiter(dbtest.Connect(t)))
+}
+
+// testLimiter checks the behaviour every limiter shares. Keys are random, so
+// buckets left over in a shared database do not interfere.
+func testLimiter(t *testing.T, limiter Limiter) {
+	ctx := context.Background()
+	rule := Rule{Name: "test", Limit: 3, Period: time.Hour}
+	key := randomKey()
+
+	for i := range rule.Limit {
+		result, err := limiter.Allow(ctx, rule, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Allowed || result.Limit != rule.Limit || result.Remaining != rule.Limit-i-1 || result.RetryAfter != 0 {
+			t.Fatalf("request %d: %+v", i+1, result)
+		}
+	}
+
+	result, err := limiter.Allow(ctx, rule, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Allowed || result.Remaining != 0 {
+		t.Fatalf("request over the limit: %+v", result)
+	}
+	// A token is added every 20 minutes.
+	if result.RetryAfter <= 19*time.Minute || result.RetryAfter > 20*time.Minute {
+		t.Errorf("RetryAfter = %s, want about 20m", result.RetryAfter)
+	}
+	if result.ResetAfter <= 59*time.Minute || result.ResetAfter > time.Hour {
+		t.Errorf("ResetAfter = %s, want about 1h", result.ResetAfter)
+	}
+
+	if result, err := limiter.Allow(ctx, rule, randomKey()); err != nil || !result.Allowed {
+		t.Errorf("another key shares the bucket: %+v %v", result, err)
+	}
+
+	other := Rule{Name: "other", Limit: 1, Period: time.Hour}
+	if result, err := limiter.Allow(ctx, other, key); err != nil || !result.Allowed {
+		t.Errorf("another rule shares the bucket: %+v %v", result, err)
+	}
+}
+
+func TestMemoryLimiterRefills(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewMemoryLimiter()
+	rule := Rule{Name: "test", Limit: 2, Period: time.Minute}
+
+	for range rule.Limit {
+		limiter.Allow(ctx, rule, "key")
+	}
+	if result, _ := limiter.Allow(ctx, rule, "key"); result.Allowed {
+		t.Fatal("request over the limit was allowed")
+	}
+
+	// Half the period refills one token.
+	limiter.buckets["test:key"].updatedAt = time.Now().Add(-rule.Period / 2)
+
+	result, _ := limiter.Allow(ctx, rule, "key")
+	if !result.Allowed || result.Remaining != 0 {
+		t.Errorf("after refilling one token: %+v", result)
+	}
+
+	// Buckets never hold more than the limit.
+	limiter.buckets["test:key"].updatedAt = time.Now().Add(-24 * time.Hour)
+
+	result, _ = limiter.Allow(ctx, rule, "key")
+	if !result.Allowed || result.Remaining != rule.Limit-1 {
+		t.Errorf("after refilling the bucket: %+v", result)
+	}
+}
+
+func TestMemoryLimiterPrunes(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewMemoryLimiter()
+	rule := Rule{Name: "test", Limit: 2, Period: time.Minute}
+
+	limiter.Allow(ctx, rule, "full")
+	limiter.Allow(ctx, rule, "empty")
+	limiter.Allow(ctx, rule, "empty")
+
+	limiter.buckets["test:full"].fullAt = time.Now().Add(-time.Second)
+	limiter.prunedAt = time.Now().Add(-pruneInterval)
+
+	limiter.Allow(ctx, rule, "another")
+
+	if _, ok := limiter.buckets["test:full"]; ok {
+		t.Error("a full bucket was not pruned")
+	}
+	if _, ok := limiter.buckets["test:empty"]; !ok {
+		t.Error("a bucket that is not full was pruned")
+	}
+}
+
+func TestRuleFromEnv(t *testing.T) {
+	defaultRule := Rule{Name: "login", Limit: 5, Period: time.Minute}
+
+	tests := []struct {
+		value string
+		rule  Rule
+		err   bool
+	}{
+		{"", defaultRule, false},
+		{"30/1m", Rule{Name: "login", Limit: 30, Period: time.Minute}, false},
+		{"100/1h30m", Rule{Name: "login", Limit: 100, Period: 90 * time.Minute}, false},
+		{"off", Rule{Name: "login"}, false},
+		{"30", Rule{}, true},
+		{"0/1m", Rule{}, true},
+		{"many/1m", Rule{}, true},
+		{"30/minute", Rule{}, true},
+		{"30/0s", Rule{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			t.Setenv("TEST_RATE_LIMIT", test.value)
+
+			rule, err := RuleFromEnv("TEST_RATE_LIMIT", defaultRule)
+			if (err != nil) != test.err {
+				t.Fatalf("err = %v", err)
+			}
+			if rule != test.rule {
+				t.Errorf("rule = %+v, want %+v", rule, test.rule)
+			}
+			if rule.Enabled() != (test.rule.Limit > 0) {
+				t.Errorf("Enabled = %v", rule.Enabled())
+			}
+		})
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	for value, ok := range map[string]bool{"": true, "memory": true, "redis": false} {
+		t.Setenv("RATE_LIMIT_STORE", value)
+
+		limiter, err := FromEnv(nil)
+		if ok && (err != nil || limiter == nil) {
+			t.Errorf("RATE_LIMIT_STORE=%q: %v", value, err)
+		}
+		if !ok && err == nil {
+			t.Errorf("RATE_LIMIT_STORE=%q: expected an error", value)
+		}
+	}
+}
+
+func randomKey() string {
+	key := make([]byte, 8)
+	rand.Read(key)
+	return hex.EncodeToString(key)
+}
@@ -0,0 +1,188 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	store db.AdminStore
+}
+
+func NewHandler(store db.AdminStore) *Handler {
+	return &Handler{store: store}
+}
+
+func (h *Handler) AddRoutes(r chi.Router) {
+	r.Route("/admin", func(r chi.Router) {
+		r.Get("/usage", h.handleUsage)
+		r.Get("/users", h.handleUsers)
+		r.Get("/users/{userId}", h.handleSingleUser)
+		r.Post("/users/{userId}/disable", h.handleDisableUser)
+		r.Post("/users/{userId}/enable", h.handleEnableUser)
+		r.Post("/users/{userId}/logout", h.handleForceLogout)
+		r.Put("/users/{userId}/role", h.handleSetRole)
+	})
+}
+
+func (h *Handler) handleUsage(w http.ResponseWriter, r *http.Request) {
+	usage, err := h.store.GetUsage(r.Context())
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	service.SendJsonResponse(w, usage, http.StatusOK)
+}
+
+func (h *Handler) handleUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := db.UserFilter{
+		Search: strings.TrimSpace(query.Get("q")),
+		Role:   query.Get("role"),
+	}
+
+	if value := query.Get("disabled"); value != "" {
+		disabled, err := strconv.ParseBool(value)
+		if err != nil {
+			service.SendErrorsResponse(w, []string{"Provided query param was not parsable"}, http.StatusBadRequest)
+			return
+		}
+		filter.Disabled = &disabled
+	}
+
+	pagination := service.GetPaginationParams(r)
+	users, err := h.store.ListUsers(r.Context(), filter, pagination.GetOffset(), pagination.Count)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	service.SendJsonResponse(w, users, http.StatusOK)
+}
+
+func (h *Handler) handleSingleUser(w http.ResponseWriter, r *http.Request) {
+	userId, ok := userIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.store.GetUser(r.Context(), userId)
+	if errors.Is(err, types.UserDoesNotExistErr) {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusNotFound)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	usage, err := h.store.GetUserUsage(r.Context(), userId)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	service.SendJsonResponse(w, UserResponseData{User: user, Usage: usage}, http.StatusOK)
+}
+
+func (h *Handler) handleDisableUser(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, true)
+}
+
+func (h *Handler) handleEnableUser(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, false)
+}
+
+func (h *Handler) setDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	userId, ok := userIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	if disabled && userId == service.UserIdFromContext(r.Context()) {
+		service.SendErrorsResponse(w, []string{"You cannot disable your own account"}, http.StatusConflict)
+		return
+	}
+
+	user, err := h.store.SetUserDisabled(r.Context(), userId, disabled, time.Now())
+	if errors.Is(err, types.UserDoesNotExistErr) {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusNotFound)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	service.SendJsonResponse(w, user, http.StatusOK)
+}
+
+func (h *Handler) handleForceLogout(w http.ResponseWriter, r *http.Request) {
+	userId, ok := userIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.store.ForceLogout(r.Context(), userId, time.Now())
+	if errors.Is(err, types.UserDoesNotExistErr) {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusNotFound)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) handleSetRole(w http.ResponseWriter, r *http.Request) {
+	userId, ok := userIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	var body SetRoleBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := body.IsValid(); err != nil {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if userId == service.UserIdFromContext(r.Context()) && *body.Role != types.RoleAdmin {
+		service.SendErrorsResponse(w, []string{"You cannot remove your own admin role"}, http.StatusConflict)
+		return
+	}
+
+	user, err := h.store.SetUserRole(r.Context(), userId, *body.Role, time.Now())
+	if errors.Is(err, types.UserDoesNotExistErr) {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusNotFound)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	service.SendJsonResponse(w, user, http.StatusOK)
+}
+
+func userIdParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		service.SendErrorsResponse(w, []string{"Provided url param was not parsable"}, http.StatusBadRequest)
+		return 0, false
+	}
+
+	return userId, true
+}
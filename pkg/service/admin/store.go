@@ -0,0 +1,237 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(connection *db.DbConnection) *Store {
+	return &Store{db: connection.DB}
+}
+
+const userFields = `id, first_name, last_name, email, verified_at, role, disabled_at, created_at, updated_at`
+
+func scanUserRow(row db.Scannable) (types.User, error) {
+	var user types.User
+	err := row.Scan(
+		&user.Id,
+		&user.FirstName,
+		&user.LastName,
+		&user.Email,
+		&user.VerifiedAt,
+		&user.Role,
+		&user.DisabledAt,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.User{}, types.UserDoesNotExistErr
+	}
+	if err != nil {
+		return types.User{}, err
+	}
+
+	return user, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (s *Store) ListUsers(ctx context.Context, filter db.UserFilter, offset int, count int) (users []types.User, err error) {
+	query := `
+        SELECT ` + userFields + ` FROM users
+        WHERE ($1 = '' OR email ILIKE $1 OR first_name ILIKE $1 OR last_name ILIKE $1)
+            AND ($2 = '' OR role = $2)
+            AND ($3::boolean IS NULL OR (disabled_at IS NOT NULL) = $3)
+        ORDER BY id
+        LIMIT $4 OFFSET $5`
+
+	ctx, span := db.StartQuerySpan(ctx, "users.select", query)
+	defer func() { tracing.End(span, err) }()
+
+	search := ""
+	if filter.Search != "" {
+		search = "%" + likeEscaper.Replace(filter.Search) + "%"
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, search, filter.Role, filter.Disabled, count, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users = make([]types.User, 0)
+	for rows.Next() {
+		user, err := scanUserRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (s *Store) GetUser(ctx context.Context, id int) (user types.User, err error) {
+	query := `SELECT ` + userFields + ` FROM users WHERE id = $1`
+
+	ctx, span := db.StartQuerySpan(ctx, "users.select", query)
+	defer func() { tracing.End(span, err) }()
+
+	return scanUserRow(s.db.QueryRowContext(ctx, query, id))
+}
+
+func (s *Store) GetUserUsage(ctx context.Context, id int) (usage types.UserUsage, err error) {
+	query := `
+        SELECT
+            (SELECT count(*) FROM resumes WHERE user_id = $1),
+            (SELECT count(*) FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL),
+            (SELECT count(*) FROM identities WHERE user_id = $1),
+            (SELECT count(*) FROM sessions
+                WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now())`
+
+	ctx, span := db.StartQuerySpan(ctx, "users.select_usage", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = s.db.QueryRowContext(ctx, query, id).Scan(
+		&usage.Resumes,
+		&usage.ApiKeys,
+		&usage.Identities,
+		&usage.ActiveSessions,
+	)
+	return usage, err
+}
+
+func (s *Store) GetUsage(ctx context.Context) (usage types.Usage, err error) {
+	query := `
+        SELECT
+            (SELECT count(*) FROM users),
+            (SELECT count(*) FROM users WHERE verified_at IS NOT NULL),
+            (SELECT count(*) FROM users WHERE disabled_at IS NOT NULL),
+            (SELECT count(*) FROM users WHERE role = 'admin'),
+            (SELECT count(*) FROM resumes),
+            (SELECT count(*) FROM api_keys WHERE revoked_at IS NULL),
+            (SELECT count(*) FROM sessions
+                WHERE revoked_at IS NULL AND expires_at > now())`
+
+	ctx, span := db.StartQuerySpan(ctx, "users.select_total_usage", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = s.db.QueryRowContext(ctx, query).Scan(
+		&usage.Users,
+		&usage.VerifiedUsers,
+		&usage.DisabledUsers,
+		&usage.Admins,
+		&usage.Resumes,
+		&usage.ApiKeys,
+		&usage.ActiveSessions,
+	)
+	return usage, err
+}
+
+// SetUserDisabled disables or enables the user. Disabling also revokes every
+// refresh token, so the user stays logged out once enabled again.
+func (s *Store) SetUserDisabled(ctx context.Context, id int, disabled bool, at time.Time) (user types.User, err error) {
+	query := `
+        UPDATE users SET
+            disabled_at = CASE WHEN $2 THEN coalesce(disabled_at, $3) END,
+            tokens_valid_after = CASE WHEN $2 THEN $3 ELSE tokens_valid_after END,
+            updated_at = DEFAULT
+        WHERE id = $1
+        RETURNING ` + userFields
+
+	ctx, span := db.StartQuerySpan(ctx, "users.update_disabled_at", query)
+	defer func() { tracing.End(span, err) }()
+
+	transaction, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return types.User{}, err
+	}
+	defer transaction.Rollback()
+
+	user, err = scanUserRow(transaction.QueryRowContext(ctx, query, id, disabled, at))
+	if err != nil {
+		return types.User{}, err
+	}
+
+	if disabled {
+		err = revokeRefreshTokens(ctx, transaction, id)
+		if err != nil {
+			return types.User{}, err
+		}
+	}
+
+	return user, transaction.Commit()
+}
+
+// SetUserRole changes the user's role. Refresh tokens stay valid so the
+// user's clients pick up the new role with their next refresh.
+func (s *Store) SetUserRole(ctx context.Context, id int, role string, at time.Time) (user types.User, err error) {
+	query := `
+        UPDATE users SET role = $2, tokens_valid_after = $3, updated_at = DEFAULT
+        WHERE id = $1
+        RETURNING ` + userFields
+
+	ctx, span := db.StartQuerySpan(ctx, "users.update_role", query)
+	defer func() { tracing.End(span, err) }()
+
+	return scanUserRow(s.db.QueryRowContext(ctx, query, id, role, at))
+}
+
+// ForceLogout rejects every access token issued to the user before at and
+// revokes all of their refresh tokens.
+func (s *Store) ForceLogout(ctx context.Context, id int, at time.Time) (err error) {
+	query := `UPDATE users SET tokens_valid_after = $2, updated_at = DEFAULT WHERE id = $1`
+
+	ctx, span := db.StartQuerySpan(ctx, "users.force_logout", query)
+	defer func() { tracing.End(span, err) }()
+
+	transaction, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer transaction.Rollback()
+
+	result, err := transaction.ExecContext(ctx, query, id, at)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return types.UserDoesNotExistErr
+	}
+
+	err = revokeRefreshTokens(ctx, transaction, id)
+	if err != nil {
+		return err
+	}
+
+	return transaction.Commit()
+}
+
+func revokeRefreshTokens(ctx context.Context, transaction *sql.Tx, userId int) error {
+	_, err := transaction.ExecContext(ctx, `
+        WITH revoked_sessions AS (
+            UPDATE sessions SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL
+        )
+        UPDATE refresh_tokens SET revoked_at = now()
+        WHERE user_id = $1 AND revoked_at IS NULL`,
+		userId,
+	)
+	return err
+}
@@ -0,0 +1,20 @@
+package admin
+
+import "github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+
+type SetRoleBody struct {
+	Role *string `json:"role" openapi:"enum=user|admin"`
+}
+
+func (b *SetRoleBody) IsValid() error {
+	if b.Role == nil {
+		return types.InvalidBodyErr
+	}
+
+	return nil
+}
+
+type UserResponseData struct {
+	User  types.User      `json:"user"`
+	Usage types.UserUsage `json:"usage"`
+}
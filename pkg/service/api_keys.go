@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// ApiKeyPrefix starts every personal API key, which lets the authentication
+// middleware tell them apart from access tokens.
+const ApiKeyPrefix = "jat_"
+
+var ScopesKey = "SCOPES"
+
+type ApiKeyVerifier interface {
+	// VerifyApiKey returns the key matching the secret or
+	// types.InvalidApiKeyErr.
+	VerifyApiKey(ctx context.Context, secret string) (types.ApiKey, error)
+}
+
+// ApiKeys verifies API keys for middleware.Authenticator. Requests with an
+// API key are rejected while it is not set.
+var ApiKeys ApiKeyVerifier
+
+func IsApiKey(token string) bool {
+	return strings.HasPrefix(token, ApiKeyPrefix)
+}
+
+// ScopesFromContext returns the scopes of the API key the request was
+// authenticated with, or nil for access tokens, which are not limited.
+func ScopesFromContext(ctx context.Context) []string {
+	scopes, _ := ctx.Value(ScopesKey).([]string)
+	return scopes
+}
@@ -0,0 +1,67 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// newApiKey returns a key formatted as jat_<prefix>_<secret> with its prefix
+// and hash. The prefix is stored in plain text to look the key up and to
+// let users recognise it.
+func newApiKey() (key string, prefix string, keyHash string) {
+	prefixBytes := make([]byte, 4)
+	rand.Read(prefixBytes)
+	prefix = hex.EncodeToString(prefixBytes)
+
+	secret := make([]byte, 32)
+	rand.Read(secret)
+
+	key = service.ApiKeyPrefix + prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return key, prefix, hashApiKey(key)
+}
+
+func hashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verifier implements service.ApiKeyVerifier.
+type Verifier struct {
+	store db.ApiKeyStore
+}
+
+func NewVerifier(store db.ApiKeyStore) *Verifier {
+	return &Verifier{store: store}
+}
+
+func (v *Verifier) VerifyApiKey(ctx context.Context, secret string) (types.ApiKey, error) {
+	prefix, _, ok := strings.Cut(strings.TrimPrefix(secret, service.ApiKeyPrefix), "_")
+	if !ok || !service.IsApiKey(secret) {
+		return types.ApiKey{}, types.InvalidApiKeyErr
+	}
+
+	key, err := v.store.GetApiKeyByPrefix(ctx, prefix)
+	if err != nil {
+		return types.ApiKey{}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashApiKey(secret))) != 1 {
+		return types.ApiKey{}, types.InvalidApiKeyErr
+	}
+
+	if err := v.store.TouchApiKey(ctx, key.Id); err != nil {
+		slog.WarnContext(ctx, "could not update api key last use", "api_key_id", key.Id, "error", err)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,101 @@
+package apikeys
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	store db.ApiKeyStore
+}
+
+func NewHandler(store db.ApiKeyStore) *Handler {
+	return &Handler{store: store}
+}
+
+func (h *Handler) AddRoutes(r chi.Router) {
+	r.Route("/api-keys", func(r chi.Router) {
+		r.Get("/", h.handleApiKeys)
+		r.Post("/", h.handlePostApiKey)
+		r.Delete("/{apiKeyId}", h.handleDeleteApiKey)
+	})
+}
+
+func (h *Handler) handleApiKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.store.GetUserApiKeys(r.Context(), service.UserIdFromContext(r.Context()))
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	service.SendJsonResponse(w, keys, http.StatusOK)
+}
+
+func (h *Handler) handlePostApiKey(w http.ResponseWriter, r *http.Request) {
+	var body CreateApiKeyBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := body.IsValid(); err != nil {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if fieldErrors := body.FieldErrors(); len(fieldErrors) > 0 {
+		service.SendFieldErrorsResponse(w, fieldErrors)
+		return
+	}
+
+	key, prefix, keyHash := newApiKey()
+	scopes := slices.Compact(slices.Sorted(slices.Values(body.Scopes)))
+
+	apiKey, err := h.store.CreateApiKey(r.Context(), types.ApiKey{
+		UserId:    service.UserIdFromContext(r.Context()),
+		Name:      *body.Name,
+		Prefix:    prefix,
+		KeyHash:   keyHash,
+		Scopes:    scopes,
+		ExpiresAt: body.ExpiresAt,
+	})
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	data := CreateApiKeyResponseData{
+		ApiKey: apiKey,
+		Key:    key,
+	}
+
+	service.SendJsonResponse(w, data, http.StatusOK)
+}
+
+func (h *Handler) handleDeleteApiKey(w http.ResponseWriter, r *http.Request) {
+	apiKeyId, err := strconv.Atoi(chi.URLParam(r, "apiKeyId"))
+	if err != nil {
+		service.SendErrorsResponse(w, []string{"Provided url param was not parsable"}, http.StatusBadRequest)
+		return
+	}
+
+	revoked, err := h.store.RevokeApiKey(r.Context(), service.UserIdFromContext(r.Context()), apiKeyId)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if !revoked {
+		service.SendErrorsResponse(w, []string{"API key does not exist"}, http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
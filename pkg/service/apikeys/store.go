@@ -0,0 +1,143 @@
+package apikeys
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/lib/pq"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(connection *db.DbConnection) *Store {
+	return &Store{db: connection.DB}
+}
+
+const apiKeyFields = `id, user_id, name, prefix, key_hash, scopes, last_used_at, expires_at, created_at`
+
+func scanApiKeyRow(row db.Scannable) (types.ApiKey, error) {
+	var key types.ApiKey
+	err := row.Scan(
+		&key.Id,
+		&key.UserId,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		pq.Array(&key.Scopes),
+		&key.LastUsedAt,
+		&key.ExpiresAt,
+		&key.CreatedAt,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.ApiKey{}, types.InvalidApiKeyErr
+	}
+	if err != nil {
+		return types.ApiKey{}, err
+	}
+
+	return key, nil
+}
+
+func (s *Store) CreateApiKey(ctx context.Context, key types.ApiKey) (newKey types.ApiKey, err error) {
+	query := `
+        INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING ` + apiKeyFields
+
+	ctx, span := db.StartQuerySpan(ctx, "api_keys.insert", query)
+	defer func() { tracing.End(span, err) }()
+
+	row := s.db.QueryRowContext(ctx, query, key.UserId, key.Name, key.Prefix, key.KeyHash, pq.Array(key.Scopes), key.ExpiresAt)
+	return scanApiKeyRow(row)
+}
+
+// GetUserApiKeys returns the user's keys that were not revoked, including
+// expired ones.
+func (s *Store) GetUserApiKeys(ctx context.Context, userId int) (keys []types.ApiKey, err error) {
+	query := `
+        SELECT ` + apiKeyFields + ` FROM api_keys
+        WHERE user_id = $1 AND revoked_at IS NULL
+        ORDER BY created_at DESC`
+
+	ctx, span := db.StartQuerySpan(ctx, "api_keys.select", query)
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys = make([]types.ApiKey, 0)
+	for rows.Next() {
+		key, err := scanApiKeyRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// GetApiKeyByPrefix returns the key with the prefix unless it was revoked,
+// has expired or belongs to a disabled user. InvalidApiKeyErr is returned
+// otherwise.
+func (s *Store) GetApiKeyByPrefix(ctx context.Context, prefix string) (key types.ApiKey, err error) {
+	query := `
+        SELECT ` + apiKeyFields + ` FROM api_keys
+        WHERE prefix = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now())
+            AND user_id IN (SELECT id FROM users WHERE disabled_at IS NULL)`
+
+	ctx, span := db.StartQuerySpan(ctx, "api_keys.select", query)
+	defer func() {
+		if errors.Is(err, types.InvalidApiKeyErr) {
+			tracing.End(span, nil)
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	return scanApiKeyRow(s.db.QueryRowContext(ctx, query, prefix))
+}
+
+// RevokeApiKey revokes one of the user's keys. It reports false when the
+// user has no such key.
+func (s *Store) RevokeApiKey(ctx context.Context, userId int, id int) (revoked bool, err error) {
+	query := `
+        UPDATE api_keys SET revoked_at = now()
+        WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+	ctx, span := db.StartQuerySpan(ctx, "api_keys.revoke", query)
+	defer func() { tracing.End(span, err) }()
+
+	result, err := s.db.ExecContext(ctx, query, id, userId)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// TouchApiKey records that the key was used. Writes are skipped while the
+// recorded time is less than a minute old so busy keys do not cause a write
+// per request.
+func (s *Store) TouchApiKey(ctx context.Context, id int) (err error) {
+	query := `
+        UPDATE api_keys SET last_used_at = now()
+        WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')`
+
+	ctx, span := db.StartQuerySpan(ctx, "api_keys.update_last_used_at", query)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.db.ExecContext(ctx, query, id)
+	return err
+}
@@ -0,0 +1,53 @@
+package apikeys
+
+import (
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+type CreateApiKeyBody struct {
+	Name      *string    `json:"name" openapi:"minLength=1,maxLength=100"`
+	Scopes    []string   `json:"scopes" openapi:"minItems=1"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (b *CreateApiKeyBody) IsValid() error {
+	if b.Name == nil || b.Scopes == nil {
+		return types.InvalidBodyErr
+	}
+
+	return nil
+}
+
+// FieldErrors checks the values a schema cannot express.
+func (b *CreateApiKeyBody) FieldErrors() []types.FieldError {
+	fieldErrors := make([]types.FieldError, 0)
+	for i, scope := range b.Scopes {
+		if !slices.Contains(types.Scopes, scope) {
+			fieldErrors = append(fieldErrors, types.FieldError{
+				In:      "body",
+				Pointer: "/scopes/" + strconv.Itoa(i),
+				Message: "unknown scope " + strconv.Quote(scope),
+			})
+		}
+	}
+
+	if b.ExpiresAt != nil && b.ExpiresAt.Before(time.Now()) {
+		fieldErrors = append(fieldErrors, types.FieldError{
+			In:      "body",
+			Pointer: "/expires_at",
+			Message: "must be in the future",
+		})
+	}
+
+	return fieldErrors
+}
+
+type CreateApiKeyResponseData struct {
+	ApiKey types.ApiKey `json:"api_key"`
+	// Key is only returned when the key is created.
+	Key string `json:"key"`
+}
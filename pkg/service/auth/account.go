@@ -0,0 +1,312 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/mailer"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// emailChangePayload is signed into email change links. The current email
+// makes the link stop working once the email changed by any means.
+type emailChangePayload struct {
+	UserId    int    `json:"uid"`
+	Email     string `json:"email"`
+	NewEmail  string `json:"new_email"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+func (h *Handler) handleMe(w http.ResponseWriter, r *http.Request) {
+	user, err := h.store.GetInternalUserById(r.Context(), service.UserIdFromContext(r.Context()))
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	service.SendJsonResponse(w, user.User, http.StatusOK)
+}
+
+func (h *Handler) handleUpdateMe(w http.ResponseWriter, r *http.Request) {
+	var body UpdateProfileBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.store.GetInternalUserById(r.Context(), service.UserIdFromContext(r.Context()))
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if body.FirstName != nil {
+		user.FirstName = *body.FirstName
+	}
+	if body.LastName != nil {
+		user.LastName = *body.LastName
+	}
+
+	user, err = h.store.UpdateUserProfile(r.Context(), user.Id, user.FirstName, user.LastName)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	service.SendJsonResponse(w, user.User, http.StatusOK)
+}
+
+// handleChangePassword replaces the password and signs the user out
+// everywhere. The response carries a new token pair for the current client.
+func (h *Handler) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	var body ChangePasswordBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := body.IsValid(); err != nil {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.store.GetInternalUserById(r.Context(), service.UserIdFromContext(r.Context()))
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	_, err = h.comparePassword(r.Context(), user.PasswordHash, *body.CurrentPassword)
+	if errors.Is(err, types.PasswordMismatchErr) {
+		service.SendErrorsResponse(w, []string{"Current password is not valid"}, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	fieldErrors, err := h.passwordFieldErrors(*body.Password, user.CommonUser)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+	if len(fieldErrors) > 0 {
+		service.SendFieldErrorsResponse(w, fieldErrors)
+		return
+	}
+
+	hash, err := h.hashPassword(r.Context(), *body.Password)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if err := h.store.UpdatePassword(r.Context(), user.Id, hash); err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if err := h.revokeAllTokens(r, user.Id); err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	tokens, err := h.issueTokens(r, user.User)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	h.sendEmail(r.Context(), mailer.Message{
+		To:      user.Email,
+		Subject: "Your password was changed",
+		Body: fmt.Sprintf("Hi %s,\n\nThe password of your account was changed and all other sessions were signed out.\n\n"+
+			"If you did not change it, reset your password right away.\n", user.FirstName),
+	})
+
+	service.SendJsonResponse(w, tokens, http.StatusOK)
+}
+
+// handleChangeEmail emails a confirmation link to the new address. The email
+// only changes once the link is opened, see handleConfirmEmailChange.
+func (h *Handler) handleChangeEmail(w http.ResponseWriter, r *http.Request) {
+	var body ChangeEmailBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := body.IsValid(); err != nil {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.store.GetInternalUserById(r.Context(), service.UserIdFromContext(r.Context()))
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	_, err = h.comparePassword(r.Context(), user.PasswordHash, *body.Password)
+	if errors.Is(err, types.PasswordMismatchErr) {
+		service.SendErrorsResponse(w, []string{"Password is not valid"}, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	newEmail := strings.TrimSpace(*body.Email)
+	if newEmail == user.Email {
+		service.SendErrorsResponse(w, []string{"New email is the same as the current one"}, http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.store.GetInternalUserByEmail(r.Context(), newEmail)
+	if err == nil {
+		service.SendErrorsResponse(w, []string{types.EmailTakenErr.Error()}, http.StatusConflict)
+		return
+	} else if !errors.Is(err, types.UserDoesNotExistErr) {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	token, err := h.createSignedToken("email-change", emailChangePayload{
+		UserId:    user.Id,
+		Email:     user.Email,
+		NewEmail:  newEmail,
+		ExpiresAt: time.Now().Add(h.config.EmailChangeTTL).Unix(),
+	})
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	link := fmt.Sprintf("%s/confirm-email-change?token=%s", h.config.AppUrl, url.QueryEscape(token))
+	h.sendEmail(r.Context(), mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to use this address for your account. It expires in %s.\n\n%s\n\n"+
+			"If you did not ask for this, you can ignore this email.\n",
+			user.FirstName, h.config.EmailChangeTTL, link),
+	})
+	h.sendEmail(r.Context(), mailer.Message{
+		To:      user.Email,
+		Subject: "Email change requested",
+		Body: fmt.Sprintf("Hi %s,\n\nA change of your account's email address to %s was requested. "+
+			"It only takes effect once confirmed from the new address.\n\n"+
+			"If you did not ask for this, change your password right away.\n", user.FirstName, newEmail),
+	})
+
+	service.SendJsonResponse(w, MessageResponseData{Message: "A confirmation link was sent to the new email address"}, http.StatusOK)
+}
+
+func (h *Handler) handleConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var body ConfirmEmailChangeBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := body.IsValid(); err != nil {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	var payload emailChangePayload
+	err := h.parseSignedToken("email-change", *body.Token, &payload)
+	if err != nil || time.Now().Unix() > payload.ExpiresAt {
+		service.SendErrorsResponse(w, []string{"Email change link is not valid or has expired"}, http.StatusBadRequest)
+		return
+	}
+
+	changed, err := h.store.ChangeEmail(r.Context(), payload.UserId, payload.Email, payload.NewEmail)
+	if errors.Is(err, types.EmailTakenErr) {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusConflict)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if !changed {
+		service.SendErrorsResponse(w, []string{"Email change link is not valid or has expired"}, http.StatusBadRequest)
+		return
+	}
+
+	h.sendEmail(r.Context(), mailer.Message{
+		To:      payload.Email,
+		Subject: "Your email address was changed",
+		Body: fmt.Sprintf("Hello,\n\nThe email address of your account was changed to %s.\n\n"+
+			"If you did not do this, contact support right away.\n", payload.NewEmail),
+	})
+
+	service.SendJsonResponse(w, MessageResponseData{Message: "Email address was changed"}, http.StatusOK)
+}
+
+// handleDeleteMe deletes the user and everything they own after checking
+// their password and, with 2FA enabled, a code.
+func (h *Handler) handleDeleteMe(w http.ResponseWriter, r *http.Request) {
+	var body DeleteAccountBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := body.IsValid(); err != nil {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.store.GetInternalUserById(r.Context(), service.UserIdFromContext(r.Context()))
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	_, err = h.comparePassword(r.Context(), user.PasswordHash, *body.Password)
+	if errors.Is(err, types.PasswordMismatchErr) {
+		service.SendErrorsResponse(w, []string{"Password is not valid"}, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if user.TwoFactorEnabled() {
+		if body.Code == nil {
+			service.SendErrorsResponse(w, []string{"Two-factor code is required"}, http.StatusBadRequest)
+			return
+		}
+
+		ok, err := h.verifySecondFactor(r.Context(), user, *body.Code)
+		if err != nil {
+			service.SendInternalServerError(w, r, err)
+			return
+		}
+
+		if !ok {
+			service.SendErrorsResponse(w, []string{"Code is not valid"}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if _, err := h.store.DeleteUser(r.Context(), user.Id); err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	h.sendEmail(r.Context(), mailer.Message{
+		To:      user.Email,
+		Subject: "Your account was deleted",
+		Body:    fmt.Sprintf("Hi %s,\n\nYour account and all of its data were deleted.\n", user.FirstName),
+	})
+
+	w.WriteHeader(http.StatusOK)
+}
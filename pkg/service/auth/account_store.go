@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/lib/pq"
+)
+
+func (s *Store) UpdateUserProfile(ctx context.Context, id int, firstName string, lastName string) (user types.InternalUser, err error) {
+	query := `
+        UPDATE users SET first_name = $2, last_name = $3, updated_at = DEFAULT
+        WHERE id = $1
+        RETURNING ` + userFields
+
+	ctx, span := db.StartQuerySpan(ctx, "users.update_profile", query)
+	defer func() { tracing.End(span, err) }()
+
+	return scanUserRow(s.db.QueryRowContext(ctx, query, id, firstName, lastName))
+}
+
+func (s *Store) UpdatePassword(ctx context.Context, id int, passwordHash string) (err error) {
+	query := `UPDATE users SET password_hash = $2, updated_at = DEFAULT WHERE id = $1`
+
+	ctx, span := db.StartQuerySpan(ctx, "users.update_password_hash", query)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.db.ExecContext(ctx, query, id, passwordHash)
+	return err
+}
+
+// RehashPassword replaces the password hash if it is still oldHash, so a
+// password changed in the meantime is not overwritten.
+func (s *Store) RehashPassword(ctx context.Context, id int, oldHash string, newHash string) (err error) {
+	query := `UPDATE users SET password_hash = $3 WHERE id = $1 AND password_hash = $2`
+
+	ctx, span := db.StartQuerySpan(ctx, "users.rehash_password", query)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.db.ExecContext(ctx, query, id, oldHash, newHash)
+	return err
+}
+
+// ChangeEmail replaces the email if it is still oldEmail and marks the new
+// one as verified, since changing it requires a link sent to it. It reports
+// false when the email was changed in the meantime and returns EmailTakenErr
+// when another user has the new email.
+func (s *Store) ChangeEmail(ctx context.Context, id int, oldEmail string, newEmail string) (changed bool, err error) {
+	query := `
+        UPDATE users SET email = $3, verified_at = now(), updated_at = DEFAULT
+        WHERE id = $1 AND email = $2`
+
+	ctx, span := db.StartQuerySpan(ctx, "users.update_email", query)
+	defer func() { tracing.End(span, err) }()
+
+	result, err := s.db.ExecContext(ctx, query, id, oldEmail, newEmail)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return false, types.EmailTakenErr
+	}
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// DeleteUser removes the user. Every table with user data references users
+// with ON DELETE CASCADE, so all of it is removed in the same statement.
+func (s *Store) DeleteUser(ctx context.Context, id int) (deleted bool, err error) {
+	query := `DELETE FROM users WHERE id = $1`
+
+	ctx, span := db.StartQuerySpan(ctx, "users.delete", query)
+	defer func() { tracing.End(span, err) }()
+
+	result, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
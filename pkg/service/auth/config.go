@@ -0,0 +1,374 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/passwords"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/ratelimit"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/webauthn"
+)
+
+type VerificationPolicy string
+
+const (
+	// VerificationOff sends no verification emails.
+	VerificationOff VerificationPolicy = "off"
+	// VerificationOptional sends verification emails but does not restrict
+	// unverified accounts.
+	VerificationOptional VerificationPolicy = "optional"
+	// VerificationRequired only lets unverified accounts use the auth
+	// endpoints until they verify their email.
+	VerificationRequired VerificationPolicy = "required"
+)
+
+type Config struct {
+	// AppUrl is the base url of the frontend, used for links in emails.
+	AppUrl           string
+	PasswordResetTTL time.Duration
+
+	MagicLinkTTL time.Duration
+	// MagicLinkRateLimit limits how many links are sent to an email.
+	MagicLinkRateLimit ratelimit.Rule
+
+	VerificationPolicy         VerificationPolicy
+	VerificationSecret         []byte
+	VerificationTTL            time.Duration
+	VerificationResendInterval time.Duration
+
+	EmailChangeTTL time.Duration
+
+	// WebAuthn is the relying party passkeys are registered with. Its
+	// Timeout is also how long ceremony challenges are valid.
+	WebAuthn webauthn.RelyingParty
+
+	// TotpIssuer is the account issuer shown in authenticator apps.
+	TotpIssuer            string
+	TwoFactorChallengeTTL time.Duration
+
+	OAuthProviders []OAuthProviderConfig
+	OAuthStateTTL  time.Duration
+
+	// LoginFreeAttempts failed logins of an account are allowed before every
+	// further one is followed by a delay, doubling from LoginBackoffBase up
+	// to LoginBackoffMax. Addresses get LoginIpFreeAttempts instead.
+	LoginFreeAttempts   int
+	LoginIpFreeAttempts int
+	LoginBackoffBase    time.Duration
+	LoginBackoffMax     time.Duration
+	// LoginLockoutThreshold failed logins lock the account for
+	// LoginLockoutDuration and notify its owner.
+	LoginLockoutThreshold int
+	LoginLockoutDuration  time.Duration
+	// LoginFailureWindow is how long failures are remembered after the last
+	// one.
+	LoginFailureWindow time.Duration
+
+	// PasswordPolicy applies to passwords set at registration, reset and
+	// change.
+	PasswordPolicy passwords.Policy
+	PasswordHasher *passwords.Hasher
+}
+
+type OAuthProviderType string
+
+const (
+	// OAuthProviderOIDC is any OpenID Connect provider, configured through
+	// its issuer's discovery document.
+	OAuthProviderOIDC OAuthProviderType = "oidc"
+	// OAuthProviderGitHub uses GitHub's OAuth2 API, which has no ID tokens.
+	OAuthProviderGitHub OAuthProviderType = "github"
+)
+
+// OAuthProviderConfig is read from OAUTH_<NAME>_* variables for every name
+// listed in OAUTH_PROVIDERS.
+type OAuthProviderConfig struct {
+	Name         string
+	DisplayName  string
+	Type         OAuthProviderType
+	ClientId     string
+	ClientSecret string
+	// RedirectUrl is the frontend page the provider sends the user back to.
+	// It posts the code and state to the callback endpoint.
+	RedirectUrl string
+	Scopes      []string
+
+	// Issuer is used by OIDC providers for discovery.
+	Issuer string
+
+	// AuthUrl, TokenUrl and ApiUrl override GitHub's endpoints, for example
+	// for GitHub Enterprise.
+	AuthUrl  string
+	TokenUrl string
+	ApiUrl   string
+}
+
+func ConfigFromEnv() (Config, error) {
+	var config Config
+	var err error
+
+	config.AppUrl = strings.TrimSuffix(os.Getenv("APP_URL"), "/")
+	if config.AppUrl == "" {
+		config.AppUrl = "http://localhost:3000"
+	}
+
+	config.PasswordResetTTL, err = service.DurationFromEnv("PASSWORD_RESET_TTL", time.Hour)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.MagicLinkTTL, err = service.DurationFromEnv("MAGIC_LINK_TTL", 15*time.Minute)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.MagicLinkRateLimit, err = ratelimit.RuleFromEnv("RATE_LIMIT_MAGIC_LINK", ratelimit.Rule{Name: "magic_link", Limit: 3, Period: 15 * time.Minute})
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.VerificationPolicy = VerificationPolicy(os.Getenv("EMAIL_VERIFICATION_POLICY"))
+	switch config.VerificationPolicy {
+	case "":
+		config.VerificationPolicy = VerificationOptional
+	case VerificationOff, VerificationOptional, VerificationRequired:
+	default:
+		return Config{}, fmt.Errorf("unknown EMAIL_VERIFICATION_POLICY %q", config.VerificationPolicy)
+	}
+
+	secret := os.Getenv("EMAIL_VERIFICATION_SECRET")
+	// The secret also signs email change links, so it is needed even when
+	// verification is off.
+	if secret == "" {
+		return Config{}, errors.New("EMAIL_VERIFICATION_SECRET not provided as an env variable")
+	}
+	config.VerificationSecret = []byte(secret)
+
+	config.VerificationTTL, err = service.DurationFromEnv("EMAIL_VERIFICATION_TTL", 48*time.Hour)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.VerificationResendInterval, err = service.DurationFromEnv("EMAIL_VERIFICATION_RESEND_INTERVAL", time.Minute)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.EmailChangeTTL, err = service.DurationFromEnv("EMAIL_CHANGE_TTL", 24*time.Hour)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.TotpIssuer = os.Getenv("TOTP_ISSUER")
+	if config.TotpIssuer == "" {
+		config.TotpIssuer = "Job Application Tracker"
+	}
+
+	config.WebAuthn, err = webauthnFromEnv(config.AppUrl)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.TwoFactorChallengeTTL, err = service.DurationFromEnv("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.OAuthStateTTL, err = service.DurationFromEnv("OAUTH_STATE_TTL", 10*time.Minute)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.LoginFreeAttempts, err = service.IntFromEnv("LOGIN_FREE_ATTEMPTS", 3)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.LoginIpFreeAttempts, err = service.IntFromEnv("LOGIN_IP_FREE_ATTEMPTS", 20)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.LoginBackoffBase, err = service.DurationFromEnv("LOGIN_BACKOFF_BASE", time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.LoginBackoffMax, err = service.DurationFromEnv("LOGIN_BACKOFF_MAX", time.Minute)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.LoginLockoutThreshold, err = service.IntFromEnv("LOGIN_LOCKOUT_THRESHOLD", 10)
+	if err != nil {
+		return Config{}, err
+	}
+	if config.LoginLockoutThreshold <= config.LoginFreeAttempts {
+		return Config{}, errors.New("LOGIN_LOCKOUT_THRESHOLD must be greater than LOGIN_FREE_ATTEMPTS")
+	}
+
+	config.LoginLockoutDuration, err = service.DurationFromEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.LoginFailureWindow, err = service.DurationFromEnv("LOGIN_FAILURE_WINDOW", time.Hour)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.PasswordPolicy, err = passwords.PolicyFromEnv()
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.PasswordHasher, err = passwords.HasherFromEnv()
+	if err != nil {
+		return Config{}, err
+	}
+	if config.PasswordHasher.Algorithm().Name() == passwords.AlgorithmBcrypt && config.PasswordPolicy.MaxLength > 72 {
+		return Config{}, errors.New("PASSWORD_MAX_LENGTH cannot be more than 72 when passwords are hashed with bcrypt")
+	}
+
+	for _, name := range strings.Split(os.Getenv("OAUTH_PROVIDERS"), ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+
+		provider, err := oauthProviderConfigFromEnv(name, config.AppUrl)
+		if err != nil {
+			return Config{}, err
+		}
+		config.OAuthProviders = append(config.OAuthProviders, provider)
+	}
+
+	return config, nil
+}
+
+// webauthnFromEnv defaults to the frontend's host as the relying party id and
+// its origin as the only allowed origin.
+func webauthnFromEnv(appUrl string) (webauthn.RelyingParty, error) {
+	app, err := url.Parse(appUrl)
+	if err != nil {
+		return webauthn.RelyingParty{}, fmt.Errorf("APP_URL is not valid: %w", err)
+	}
+
+	rp := webauthn.RelyingParty{
+		Id:          os.Getenv("WEBAUTHN_RP_ID"),
+		Name:        os.Getenv("WEBAUTHN_RP_NAME"),
+		Attestation: os.Getenv("WEBAUTHN_ATTESTATION"),
+	}
+	if rp.Id == "" {
+		rp.Id = app.Hostname()
+	}
+	if rp.Name == "" {
+		rp.Name = "Job Application Tracker"
+	}
+
+	switch rp.Attestation {
+	case "":
+		rp.Attestation = "none"
+	case "none", "direct":
+	default:
+		return webauthn.RelyingParty{}, fmt.Errorf("unknown WEBAUTHN_ATTESTATION %q", rp.Attestation)
+	}
+
+	for _, origin := range strings.Split(os.Getenv("WEBAUTHN_ORIGINS"), ",") {
+		if origin = strings.TrimSuffix(strings.TrimSpace(origin), "/"); origin != "" {
+			rp.Origins = append(rp.Origins, origin)
+		}
+	}
+	if rp.Origins == nil {
+		rp.Origins = []string{app.Scheme + "://" + app.Host}
+	}
+
+	// Browsers only allow ceremonies for an id that is the origin's host or
+	// one of its parent domains.
+	for _, origin := range rp.Origins {
+		parsed, err := url.Parse(origin)
+		if err != nil || parsed.Host == "" {
+			return webauthn.RelyingParty{}, fmt.Errorf("WEBAUTHN_ORIGINS has invalid origin %q", origin)
+		}
+		if host := parsed.Hostname(); host != rp.Id && !strings.HasSuffix(host, "."+rp.Id) {
+			return webauthn.RelyingParty{}, fmt.Errorf("WEBAUTHN_RP_ID %q does not match origin %q", rp.Id, origin)
+		}
+	}
+
+	rp.Timeout, err = service.DurationFromEnv("WEBAUTHN_TIMEOUT", 5*time.Minute)
+	if err != nil {
+		return webauthn.RelyingParty{}, err
+	}
+
+	return rp, nil
+}
+
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+func oauthProviderConfigFromEnv(name string, appUrl string) (OAuthProviderConfig, error) {
+	if !providerNamePattern.MatchString(name) {
+		return OAuthProviderConfig{}, fmt.Errorf("OAUTH_PROVIDERS has invalid provider name %q", name)
+	}
+
+	prefix := "OAUTH_" + strings.ToUpper(name) + "_"
+	config := OAuthProviderConfig{
+		Name:         name,
+		DisplayName:  os.Getenv(prefix + "DISPLAY_NAME"),
+		Type:         OAuthProviderType(os.Getenv(prefix + "TYPE")),
+		ClientId:     os.Getenv(prefix + "CLIENT_ID"),
+		ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+		RedirectUrl:  os.Getenv(prefix + "REDIRECT_URL"),
+		Issuer:       os.Getenv(prefix + "ISSUER"),
+		AuthUrl:      os.Getenv(prefix + "AUTH_URL"),
+		TokenUrl:     os.Getenv(prefix + "TOKEN_URL"),
+		ApiUrl:       os.Getenv(prefix + "API_URL"),
+	}
+
+	if config.DisplayName == "" {
+		config.DisplayName = name
+	}
+	if config.RedirectUrl == "" {
+		config.RedirectUrl = fmt.Sprintf("%s/oauth/%s/callback", appUrl, name)
+	}
+	if scopes := os.Getenv(prefix + "SCOPES"); scopes != "" {
+		config.Scopes = strings.Fields(strings.ReplaceAll(scopes, ",", " "))
+	}
+
+	if config.ClientId == "" {
+		return OAuthProviderConfig{}, fmt.Errorf("%sCLIENT_ID not provided as an env variable", prefix)
+	}
+
+	switch config.Type {
+	case "", OAuthProviderOIDC:
+		config.Type = OAuthProviderOIDC
+		if config.Issuer == "" {
+			return OAuthProviderConfig{}, fmt.Errorf("%sISSUER not provided as an env variable", prefix)
+		}
+		if config.Scopes == nil {
+			config.Scopes = []string{"openid", "email", "profile"}
+		}
+	case OAuthProviderGitHub:
+		if config.AuthUrl == "" {
+			config.AuthUrl = "https://github.com/login/oauth/authorize"
+		}
+		if config.TokenUrl == "" {
+			config.TokenUrl = "https://github.com/login/oauth/access_token"
+		}
+		if config.ApiUrl == "" {
+			config.ApiUrl = "https://api.github.com"
+		}
+		if config.Scopes == nil {
+			config.Scopes = []string{"read:user", "user:email"}
+		}
+	default:
+		return OAuthProviderConfig{}, fmt.Errorf("unknown %sTYPE %q", prefix, config.Type)
+	}
+
+	return config, nil
+}
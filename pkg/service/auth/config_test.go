@@ -0,0 +1,24 @@
+package auth
+
+import "testing"
+
+func TestConfigFromEnvRequiresVerificationSecret(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-jwt-secret")
+	t.Setenv("EMAIL_VERIFICATION_SECRET", "")
+	t.Setenv("WEBAUTHN_RP_ID", "localhost")
+	t.Setenv("APP_URL", testOrigin)
+
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Error("ConfigFromEnv fell back to JWT_SECRET")
+	}
+
+	t.Setenv("EMAIL_VERIFICATION_SECRET", "test-email-verification-secret")
+
+	config, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(config.VerificationSecret) != "test-email-verification-secret" {
+		t.Errorf("secret = %q", config.VerificationSecret)
+	}
+}
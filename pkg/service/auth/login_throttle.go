@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/mailer"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/metrics"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+const invalidCredentialsMessage = "Email or password is not valid"
+
+func accountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(r *http.Request) string {
+	return "ip:" + service.ClientIP(r)
+}
+
+// rejectThrottled responds with 429 while the account or the client's
+// address is blocked after failed logins and reports whether it did.
+func (h *Handler) rejectThrottled(w http.ResponseWriter, r *http.Request, email string) bool {
+	blockedUntil, err := h.store.GetLoginBlockedUntil(r.Context(), []string{accountThrottleKey(email), ipThrottleKey(r)})
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return true
+	}
+
+	if blockedUntil.IsZero() {
+		return false
+	}
+
+	retryAfter := max(int(math.Ceil(time.Until(blockedUntil).Seconds())), 1)
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+
+	metrics.LoginFailed("throttled")
+	service.SendErrorsResponse(w, []string{"Too many failed login attempts, please try again later"}, http.StatusTooManyRequests)
+	return true
+}
+
+// recordLoginFailure counts a failed login for the account and the client's
+// address and blocks them once they are over their free attempts. user is
+// nil when no account has the email, which is counted all the same.
+func (h *Handler) recordLoginFailure(r *http.Request, email string, user *types.InternalUser) error {
+	ctx := r.Context()
+	accountKey := accountThrottleKey(email)
+
+	failures, err := h.store.RecordLoginFailure(ctx, accountKey, h.config.LoginFailureWindow)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case failures >= h.config.LoginLockoutThreshold:
+		err = h.store.BlockLogin(ctx, accountKey, time.Now().Add(h.config.LoginLockoutDuration))
+		if err != nil {
+			return err
+		}
+
+		if failures == h.config.LoginLockoutThreshold {
+			metrics.LoginFailed("locked")
+			if user != nil {
+				h.sendLockoutEmail(ctx, *user)
+			}
+		}
+	case failures > h.config.LoginFreeAttempts:
+		err = h.store.BlockLogin(ctx, accountKey, time.Now().Add(h.loginBackoff(failures-h.config.LoginFreeAttempts)))
+		if err != nil {
+			return err
+		}
+	}
+
+	ipKey := ipThrottleKey(r)
+	failures, err = h.store.RecordLoginFailure(ctx, ipKey, h.config.LoginFailureWindow)
+	if err != nil {
+		return err
+	}
+
+	if failures > h.config.LoginIpFreeAttempts {
+		return h.store.BlockLogin(ctx, ipKey, time.Now().Add(h.loginBackoff(failures-h.config.LoginIpFreeAttempts)))
+	}
+
+	return nil
+}
+
+// loginBackoff doubles the delay with every attempt over the free ones.
+func (h *Handler) loginBackoff(attempt int) time.Duration {
+	if attempt > 32 {
+		return h.config.LoginBackoffMax
+	}
+
+	return min(h.config.LoginBackoffBase<<(attempt-1), h.config.LoginBackoffMax)
+}
+
+func (h *Handler) clearLoginFailures(ctx context.Context, email string) error {
+	return h.store.ClearLoginFailures(ctx, accountThrottleKey(email))
+}
+
+func (h *Handler) sendLockoutEmail(ctx context.Context, user types.InternalUser) {
+	h.sendEmail(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your account was temporarily locked",
+		Body: fmt.Sprintf("Hi %s,\n\nAfter %d failed login attempts, logging in to your account is blocked for %s.\n\n"+
+			"If these attempts were not yours, someone may be guessing your password. Consider changing it once you can log in again.\n",
+			user.FirstName, h.config.LoginLockoutThreshold, h.config.LoginLockoutDuration),
+	})
+}
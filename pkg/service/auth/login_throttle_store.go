@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
+	"github.com/lib/pq"
+)
+
+// GetLoginBlockedUntil returns the latest time any of the keys is blocked
+// until, or the zero time when none is blocked.
+func (s *Store) GetLoginBlockedUntil(ctx context.Context, keys []string) (blockedUntil time.Time, err error) {
+	query := `
+        SELECT max(blocked_until) FROM login_throttles
+        WHERE key = ANY($1) AND blocked_until > now()`
+
+	ctx, span := db.StartQuerySpan(ctx, "login_throttles.select", query)
+	defer func() { tracing.End(span, err) }()
+
+	var value sql.NullTime
+	err = s.db.QueryRowContext(ctx, query, pq.Array(keys)).Scan(&value)
+	return value.Time, err
+}
+
+// RecordLoginFailure counts a failed login for the key and returns the
+// number of failures within window. Keys whose last failure is older than
+// window start counting again, and such rows of other keys are removed.
+func (s *Store) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (failures int, err error) {
+	query := `
+        INSERT INTO login_throttles (key, failures) VALUES ($1, 1)
+        ON CONFLICT (key) DO UPDATE SET
+            failures = CASE
+                WHEN login_throttles.last_failure_at < now() - make_interval(secs => $2) THEN 1
+                ELSE login_throttles.failures + 1
+            END,
+            last_failure_at = now()
+        RETURNING failures`
+
+	ctx, span := db.StartQuerySpan(ctx, "login_throttles.upsert", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = s.db.QueryRowContext(ctx, query, key, window.Seconds()).Scan(&failures)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+        DELETE FROM login_throttles
+        WHERE last_failure_at < now() - make_interval(secs => $1)
+            AND (blocked_until IS NULL OR blocked_until < now())`,
+		window.Seconds(),
+	)
+	return failures, err
+}
+
+func (s *Store) BlockLogin(ctx context.Context, key string, until time.Time) (err error) {
+	query := `UPDATE login_throttles SET blocked_until = $2 WHERE key = $1`
+
+	ctx, span := db.StartQuerySpan(ctx, "login_throttles.block", query)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.db.ExecContext(ctx, query, key, until)
+	return err
+}
+
+func (s *Store) ClearLoginFailures(ctx context.Context, key string) (err error) {
+	query := `DELETE FROM login_throttles WHERE key = $1`
+
+	ctx, span := db.StartQuerySpan(ctx, "login_throttles.delete", query)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.db.ExecContext(ctx, query, key)
+	return err
+}
@@ -0,0 +1,311 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/passwords"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/ratelimit"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+const (
+	throttleEmail    = "throttle@example.com"
+	throttlePassword = "correct horse battery staple"
+)
+
+// throttleStore keeps a user and the login throttles in memory. Other
+// methods of db.AuthStore are not implemented.
+type throttleStore struct {
+	db.AuthStore
+
+	mu       sync.Mutex
+	user     types.InternalUser
+	failures map[string]int
+	blocked  map[string]time.Time
+}
+
+func (s *throttleStore) GetInternalUserByEmail(ctx context.Context, email string) (types.InternalUser, error) {
+	if email != s.user.Email {
+		return types.InternalUser{}, types.UserDoesNotExistErr
+	}
+	return s.user, nil
+}
+
+func (s *throttleStore) GetLoginBlockedUntil(ctx context.Context, keys []string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var blockedUntil time.Time
+	for _, key := range keys {
+		if until := s.blocked[key]; until.After(time.Now()) && until.After(blockedUntil) {
+			blockedUntil = until
+		}
+	}
+	return blockedUntil, nil
+}
+
+func (s *throttleStore) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[key]++
+	return s.failures[key], nil
+}
+
+func (s *throttleStore) BlockLogin(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blocked[key] = until
+	return nil
+}
+
+func (s *throttleStore) ClearLoginFailures(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failures, key)
+	delete(s.blocked, key)
+	return nil
+}
+
+func (s *throttleStore) CreateRefreshToken(ctx context.Context, token types.RefreshToken) (types.RefreshToken, error) {
+	return token, nil
+}
+
+func (s *throttleStore) TouchSession(ctx context.Context, session types.Session) (types.Session, error) {
+	session.Id = 1
+	return session, nil
+}
+
+// unblock lifts every block, as if their time ran out.
+func (s *throttleStore) unblock() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.blocked)
+}
+
+func (s *throttleStore) failureCount(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failures[key]
+}
+
+type throttleTest struct {
+	t       *testing.T
+	handler *Handler
+	store   *throttleStore
+	mailer  channelMailer
+}
+
+// newThrottleTest allows 2 free attempts per account and 5 per address,
+// and locks accounts out after 4 failures.
+func newThrottleTest(t *testing.T) *throttleTest {
+	t.Setenv("JWT_SECRET", "test-jwt-secret")
+	t.Setenv("JWT_ALGORITHM", "HS256")
+	t.Setenv("EMAIL_VERIFICATION_SECRET", "test-email-verification-secret")
+	t.Setenv("APP_URL", testOrigin)
+	t.Setenv("WEBAUTHN_RP_ID", "localhost")
+	t.Setenv("WEBAUTHN_RP_NAME", "JobApplicationTracker-test")
+	t.Setenv("ARGON2_MEMORY", "1024")
+	t.Setenv("ARGON2_ITERATIONS", "1")
+	t.Setenv("LOGIN_FREE_ATTEMPTS", "2")
+	t.Setenv("LOGIN_IP_FREE_ATTEMPTS", "5")
+	t.Setenv("LOGIN_BACKOFF_BASE", "10s")
+	t.Setenv("LOGIN_BACKOFF_MAX", "1m")
+	t.Setenv("LOGIN_LOCKOUT_THRESHOLD", "4")
+	t.Setenv("LOGIN_LOCKOUT_DURATION", "15m")
+
+	if err := service.JwtClient.InitJwtAuth(); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := config.PasswordHasher.Hash(throttlePassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := types.InternalUser{PasswordHash: hash}
+	user.Id = testUserId
+	user.Email = throttleEmail
+	user.FirstName = "Throttle"
+
+	store := &throttleStore{user: user, failures: make(map[string]int), blocked: make(map[string]time.Time)}
+	mailer := make(channelMailer, 10)
+
+	return &throttleTest{
+		t:       t,
+		handler: NewHandler(store, nil, mailer, ratelimit.NewMemoryLimiter(), config),
+		store:   store,
+		mailer:  mailer,
+	}
+}
+
+func (l *throttleTest) login(email string, password string) *httptest.ResponseRecorder {
+	l.t.Helper()
+
+	encoded, err := json.Marshal(map[string]string{"email": email, "password": password})
+	if err != nil {
+		l.t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(encoded))
+	r.RemoteAddr = "192.0.2.1:1234"
+
+	w := httptest.NewRecorder()
+	l.handler.handleLogin(w, r)
+	return w
+}
+
+// expectRetryAfter checks that the login was throttled for about seconds.
+func (l *throttleTest) expectRetryAfter(w *httptest.ResponseRecorder, seconds int) {
+	l.t.Helper()
+
+	expectError(l.t, w, http.StatusTooManyRequests, "Too many failed login attempts, please try again later")
+
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || retryAfter < seconds-1 || retryAfter > seconds {
+		l.t.Errorf("Retry-After = %q, want %d", w.Header().Get("Retry-After"), seconds)
+	}
+}
+
+func TestLoginFailuresAreUniform(t *testing.T) {
+	l := newThrottleTest(t)
+
+	unknown := l.login("unknown@example.com", throttlePassword)
+	expectError(t, unknown, http.StatusUnauthorized, invalidCredentialsMessage)
+
+	wrong := l.login(throttleEmail, "wrong password")
+	expectError(t, wrong, http.StatusUnauthorized, invalidCredentialsMessage)
+
+	if unknown.Header().Get("Retry-After") != "" || wrong.Header().Get("Retry-After") != "" {
+		t.Error("a failed login within the free attempts was throttled")
+	}
+
+	// Both count towards their account, whether or not it exists.
+	for _, email := range []string{"unknown@example.com", throttleEmail} {
+		if failures := l.store.failureCount(accountThrottleKey(email)); failures != 1 {
+			t.Errorf("failures of %s = %d, want 1", email, failures)
+		}
+	}
+}
+
+func TestLoginBackoffAndLockout(t *testing.T) {
+	l := newThrottleTest(t)
+
+	for range 2 {
+		expectError(t, l.login(throttleEmail, "wrong password"), http.StatusUnauthorized, invalidCredentialsMessage)
+	}
+	if w := l.login(throttleEmail, throttlePassword); w.Code != http.StatusOK {
+		t.Fatalf("login within the free attempts: status = %d, body = %s", w.Code, w.Body)
+	}
+
+	for range 2 {
+		expectError(t, l.login(throttleEmail, "wrong password"), http.StatusUnauthorized, invalidCredentialsMessage)
+	}
+
+	// The third failure is over the free attempts and backs off for the
+	// base delay, even for the right password.
+	expectError(t, l.login(throttleEmail, "wrong password"), http.StatusUnauthorized, invalidCredentialsMessage)
+	l.expectRetryAfter(l.login(throttleEmail, throttlePassword), 10)
+
+	// The fourth locks the account out and tells the user.
+	l.store.unblock()
+	expectError(t, l.login(throttleEmail, "wrong password"), http.StatusUnauthorized, invalidCredentialsMessage)
+	l.expectRetryAfter(l.login(throttleEmail, throttlePassword), 15*60)
+
+	select {
+	case message := <-l.mailer:
+		if message.To != throttleEmail || !strings.Contains(message.Subject, "locked") {
+			t.Errorf("sent %+v, want the lockout email", message)
+		}
+	case <-time.After(time.Second):
+		t.Error("no lockout email was sent")
+	}
+}
+
+func TestLoginThrottlesAddress(t *testing.T) {
+	l := newThrottleTest(t)
+
+	for i := range 6 {
+		w := l.login("unknown-"+strconv.Itoa(i)+"@example.com", "wrong password")
+		expectError(t, w, http.StatusUnauthorized, invalidCredentialsMessage)
+	}
+
+	l.expectRetryAfter(l.login(throttleEmail, throttlePassword), 10)
+}
+
+func TestLoginSuccessClearsFailures(t *testing.T) {
+	l := newThrottleTest(t)
+
+	for range 2 {
+		l.login(throttleEmail, "wrong password")
+	}
+
+	w := l.login(throttleEmail, throttlePassword)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+	}
+
+	var data LoginResponseData
+	decodeData(t, w, &data)
+	if data.Token == "" {
+		t.Errorf("response = %+v, want tokens", data)
+	}
+
+	if failures := l.store.failureCount(accountThrottleKey(throttleEmail)); failures != 0 {
+		t.Errorf("failures = %d after a successful login, want 0", failures)
+	}
+}
+
+func TestLoginBackoff(t *testing.T) {
+	h := &Handler{config: Config{LoginBackoffBase: time.Second, LoginBackoffMax: time.Minute}}
+
+	tests := map[int]time.Duration{
+		1:  time.Second,
+		2:  2 * time.Second,
+		6:  32 * time.Second,
+		7:  time.Minute,
+		40: time.Minute,
+	}
+	for attempt, want := range tests {
+		if got := h.loginBackoff(attempt); got != want {
+			t.Errorf("loginBackoff(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
+
+// failingAlgorithm cannot create hashes.
+type failingAlgorithm struct{}
+
+func (failingAlgorithm) Name() string                               { return "failing" }
+func (failingAlgorithm) Hash(password string) (string, error)       { return "", errors.New("hash failed") }
+func (failingAlgorithm) Recognizes(hash string) bool                { return false }
+func (failingAlgorithm) Compare(hash string, password string) error { return types.PasswordMismatchErr }
+func (failingAlgorithm) Outdated(hash string) bool                  { return false }
+
+func TestLoginDummyHashError(t *testing.T) {
+	l := newThrottleTest(t)
+	config := l.handler.config
+	config.PasswordHasher = passwords.NewHasher(failingAlgorithm{})
+	l.handler = NewHandler(l.store, nil, l.mailer, ratelimit.NewMemoryLimiter(), config)
+
+	w := l.login("unknown@example.com", throttlePassword)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// handleLogout revokes the access token used for the request and its
+// session. Tokens issued before sessions were tracked have none, for them
+// the refresh token family of the provided refresh token is revoked.
+func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
+	var body LogoutBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	claims := service.ClaimsFromContext(r.Context())
+
+	err := h.revocations.RevokeToken(r.Context(), claims.ID, claims.UserId, claims.ExpiresAt.Time)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if claims.SessionId != 0 {
+		if _, err := h.store.RevokeSession(r.Context(), claims.UserId, claims.SessionId); err != nil {
+			service.SendInternalServerError(w, r, err)
+			return
+		}
+		h.revocations.InvalidateSession(claims.SessionId)
+	}
+
+	if body.RefreshToken != nil {
+		refreshToken, err := h.store.GetRefreshTokenByHash(r.Context(), hashToken(*body.RefreshToken))
+		if err != nil && !errors.Is(err, types.RefreshTokenDoesNotExistErr) {
+			service.SendInternalServerError(w, r, err)
+			return
+		}
+
+		if err == nil && refreshToken.UserId == claims.UserId {
+			if err := h.store.RevokeRefreshTokenFamily(r.Context(), refreshToken.FamilyId); err != nil {
+				service.SendInternalServerError(w, r, err)
+				return
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleLogoutAll invalidates every access and refresh token issued to the
+// user up to now.
+func (h *Handler) handleLogoutAll(w http.ResponseWriter, r *http.Request) {
+	userId := service.UserIdFromContext(r.Context())
+
+	if err := h.revokeAllTokens(r, userId); err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) revokeAllTokens(r *http.Request, userId int) error {
+	if err := h.revocations.SetTokensValidAfter(r.Context(), userId, time.Now()); err != nil {
+		return err
+	}
+
+	return h.store.RevokeUserRefreshTokens(r.Context(), userId)
+}
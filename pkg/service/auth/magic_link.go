@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/mailer"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/metrics"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+const (
+	// MagicLinkDeviceHeader carries the device token returned when the link
+	// was requested.
+	MagicLinkDeviceHeader = "X-Magic-Link-Device"
+
+	magicLinkMessage        = "If an account with this email exists, a sign-in link was sent to it. Open it on this device"
+	invalidMagicLinkMessage = "Sign-in link is not valid or has expired, or was requested on another device"
+)
+
+// magicLinkPayload is signed into magic links. Including the email means a
+// link stops working once the user changes their address.
+type magicLinkPayload struct {
+	UserId    int    `json:"uid"`
+	Email     string `json:"email"`
+	Nonce     string `json:"nonce"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// handleRequestMagicLink emails a single use sign-in link. The response is
+// the same whether or not the email belongs to an account, and carries a
+// device token without which the link does not work, so a forwarded link is
+// useless to whoever it was forwarded to.
+func (h *Handler) handleRequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	var body MagicLinkBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := body.IsValid(); err != nil {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if h.rejectMagicLinkRateLimited(w, r, *body.Email) {
+		return
+	}
+
+	deviceToken, deviceHash := newOpaqueToken()
+	expiresAt := time.Now().Add(h.config.MagicLinkTTL)
+
+	user, err := h.store.GetInternalUserByEmail(r.Context(), *body.Email)
+	if err != nil && !errors.Is(err, types.UserDoesNotExistErr) {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if err == nil {
+		nonce, _ := newOpaqueToken()
+		token, err := h.createSignedToken("magic-link", magicLinkPayload{
+			UserId:    user.Id,
+			Email:     user.Email,
+			Nonce:     nonce,
+			ExpiresAt: expiresAt.Unix(),
+		})
+		if err != nil {
+			service.SendInternalServerError(w, r, err)
+			return
+		}
+
+		err = h.store.CreateMagicLink(r.Context(), types.MagicLink{
+			UserId:     user.Id,
+			TokenHash:  hashToken(token),
+			DeviceHash: deviceHash,
+			ExpiresAt:  expiresAt,
+		})
+		if err != nil {
+			service.SendInternalServerError(w, r, err)
+			return
+		}
+
+		link := fmt.Sprintf("%s/magic-link?token=%s", h.config.AppUrl, url.QueryEscape(token))
+		h.sendEmail(r.Context(), mailer.Message{
+			To:      user.Email,
+			Subject: "Your sign-in link",
+			Body: fmt.Sprintf("Hi %s,\n\nUse the link below to sign in. It expires in %s, can be used once and only "+
+				"works in the browser you requested it from.\n\n%s\n\n"+
+				"If you did not ask to sign in, you can ignore this email.\n",
+				user.FirstName, h.config.MagicLinkTTL, link),
+		})
+	}
+
+	data := MagicLinkResponseData{
+		Message:     magicLinkMessage,
+		DeviceToken: deviceToken,
+		ExpiresAt:   expiresAt,
+	}
+
+	service.SendJsonResponse(w, data, http.StatusOK)
+}
+
+// handleVerifyMagicLink exchanges a magic link and the device token of the
+// client that requested it for the same response as a login with a
+// password.
+func (h *Handler) handleVerifyMagicLink(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	deviceToken := r.Header.Get(MagicLinkDeviceHeader)
+
+	var payload magicLinkPayload
+	err := h.parseSignedToken("magic-link", token, &payload)
+	if err != nil || time.Now().Unix() > payload.ExpiresAt || deviceToken == "" {
+		metrics.LoginFailed("invalid_magic_link")
+		service.SendErrorsResponse(w, []string{invalidMagicLinkMessage}, http.StatusUnauthorized)
+		return
+	}
+
+	userId, err := h.store.ConsumeMagicLink(r.Context(), hashToken(token), hashToken(deviceToken))
+	if errors.Is(err, types.InvalidOneTimeTokenErr) {
+		metrics.LoginFailed("invalid_magic_link")
+		service.SendErrorsResponse(w, []string{invalidMagicLinkMessage}, http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	user, err := h.store.GetInternalUserById(r.Context(), userId)
+	if errors.Is(err, types.UserDoesNotExistErr) || (err == nil && user.Email != payload.Email) {
+		metrics.LoginFailed("invalid_magic_link")
+		service.SendErrorsResponse(w, []string{invalidMagicLinkMessage}, http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if rejectDisabled(w, user) {
+		return
+	}
+
+	// Opening the link proves the user controls the email address.
+	if user.VerifiedAt == nil {
+		if _, err := h.store.MarkEmailVerified(r.Context(), user.Id, user.Email); err != nil {
+			service.SendInternalServerError(w, r, err)
+			return
+		}
+		now := time.Now()
+		user.VerifiedAt = &now
+	}
+
+	if user.TwoFactorEnabled() {
+		h.startTwoFactorChallenge(w, r, user)
+		return
+	}
+
+	if err := h.clearLoginFailures(r.Context(), user.Email); err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	tokens, err := h.issueTokens(r, user.User)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	metrics.LoginSucceeded()
+
+	service.SendJsonResponse(w, LoginResponseData{TokenResponseData: tokens}, http.StatusOK)
+}
+
+// rejectMagicLinkRateLimited responds with 429 when too many links were
+// requested for the email and reports whether it did. Like the rate limit
+// middleware it lets the request through when the limiter fails.
+func (h *Handler) rejectMagicLinkRateLimited(w http.ResponseWriter, r *http.Request, email string) bool {
+	rule := h.config.MagicLinkRateLimit
+	if !rule.Enabled() {
+		return false
+	}
+
+	result, err := h.limiter.Allow(r.Context(), rule, "email:"+strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "could not check rate limit", "rule", rule.Name, "error", err)
+		return false
+	}
+
+	if result.Allowed {
+		return false
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+	metrics.RateLimited(rule.Name)
+	service.SendErrorsResponse(w, []string{"Too many sign-in links were requested for this email, please try again later"}, http.StatusTooManyRequests)
+	return true
+}
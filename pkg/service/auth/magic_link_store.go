@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// CreateMagicLink stores a new link, invalidating every link the user
+// requested before it, and removes expired links.
+func (s *Store) CreateMagicLink(ctx context.Context, link types.MagicLink) (err error) {
+	query := `
+        INSERT INTO magic_links (user_id, token_hash, device_hash, expires_at)
+        VALUES ($1, $2, $3, $4)`
+
+	ctx, span := db.StartQuerySpan(ctx, "magic_links.insert", query)
+	defer func() { tracing.End(span, err) }()
+
+	transaction, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer transaction.Rollback()
+
+	_, err = transaction.ExecContext(ctx, `DELETE FROM magic_links WHERE expires_at < now()`)
+	if err != nil {
+		return err
+	}
+
+	_, err = transaction.ExecContext(ctx, `
+        UPDATE magic_links SET used_at = now()
+        WHERE user_id = $1 AND used_at IS NULL`,
+		link.UserId,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = transaction.ExecContext(ctx, query, link.UserId, link.TokenHash, link.DeviceHash, link.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return transaction.Commit()
+}
+
+// ConsumeMagicLink spends the link and returns its user's id. A link is only
+// spent by the device it was requested from, so opening a forwarded link
+// does not invalidate it. InvalidOneTimeTokenErr is returned for unknown,
+// spent and expired links and for other devices.
+func (s *Store) ConsumeMagicLink(ctx context.Context, tokenHash string, deviceHash string) (userId int, err error) {
+	query := `
+        UPDATE magic_links SET used_at = now()
+        WHERE token_hash = $1 AND device_hash = $2 AND used_at IS NULL AND expires_at > now()
+        RETURNING user_id`
+
+	ctx, span := db.StartQuerySpan(ctx, "magic_links.consume", query)
+	defer func() {
+		if errors.Is(err, types.InvalidOneTimeTokenErr) {
+			tracing.End(span, nil)
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	err = s.db.QueryRowContext(ctx, query, tokenHash, deviceHash).Scan(&userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, types.InvalidOneTimeTokenErr
+	}
+
+	return userId, err
+}
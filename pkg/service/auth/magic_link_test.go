@@ -0,0 +1,366 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/ratelimit"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+const magicLinkEmail = "magic@example.com"
+
+// magicLinkStore keeps a user and their magic links in memory. Other
+// methods of db.AuthStore are not implemented.
+type magicLinkStore struct {
+	db.AuthStore
+
+	mu         sync.Mutex
+	user       types.InternalUser
+	links      []types.MagicLink
+	challenges []types.TwoFactorChallenge
+}
+
+func (s *magicLinkStore) GetInternalUserByEmail(ctx context.Context, email string) (types.InternalUser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if email != s.user.Email {
+		return types.InternalUser{}, types.UserDoesNotExistErr
+	}
+	return s.user, nil
+}
+
+func (s *magicLinkStore) GetInternalUserById(ctx context.Context, id int) (types.InternalUser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id != s.user.Id {
+		return types.InternalUser{}, types.UserDoesNotExistErr
+	}
+	return s.user, nil
+}
+
+func (s *magicLinkStore) CreateMagicLink(ctx context.Context, link types.MagicLink) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for i := range s.links {
+		if s.links[i].UserId == link.UserId && s.links[i].UsedAt == nil {
+			s.links[i].UsedAt = &now
+		}
+	}
+
+	s.links = append(s.links, link)
+	return nil
+}
+
+func (s *magicLinkStore) ConsumeMagicLink(ctx context.Context, tokenHash string, deviceHash string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for i, link := range s.links {
+		if link.TokenHash == tokenHash && link.DeviceHash == deviceHash && link.UsedAt == nil && link.ExpiresAt.After(now) {
+			s.links[i].UsedAt = &now
+			return link.UserId, nil
+		}
+	}
+	return 0, types.InvalidOneTimeTokenErr
+}
+
+func (s *magicLinkStore) MarkEmailVerified(ctx context.Context, userId int, email string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.user.VerifiedAt = &now
+	return true, nil
+}
+
+func (s *magicLinkStore) CreateTwoFactorChallenge(ctx context.Context, challenge types.TwoFactorChallenge) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.challenges = append(s.challenges, challenge)
+	return nil
+}
+
+func (s *magicLinkStore) ClearLoginFailures(ctx context.Context, key string) error {
+	return nil
+}
+
+func (s *magicLinkStore) CreateRefreshToken(ctx context.Context, token types.RefreshToken) (types.RefreshToken, error) {
+	return token, nil
+}
+
+func (s *magicLinkStore) TouchSession(ctx context.Context, session types.Session) (types.Session, error) {
+	session.Id = 1
+	return session, nil
+}
+
+func (s *magicLinkStore) change(change func(user *types.InternalUser)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change(&s.user)
+}
+
+type magicLinkTest struct {
+	t       *testing.T
+	handler *Handler
+	store   *magicLinkStore
+	mailer  channelMailer
+}
+
+// newMagicLinkTest allows 3 links per email every 15 minutes.
+func newMagicLinkTest(t *testing.T) *magicLinkTest {
+	t.Setenv("JWT_SECRET", "test-jwt-secret")
+	t.Setenv("JWT_ALGORITHM", "HS256")
+	t.Setenv("EMAIL_VERIFICATION_SECRET", "test-email-verification-secret")
+	t.Setenv("APP_URL", testOrigin)
+	t.Setenv("WEBAUTHN_RP_ID", "localhost")
+	t.Setenv("WEBAUTHN_RP_NAME", "JobApplicationTracker-test")
+	t.Setenv("RATE_LIMIT_MAGIC_LINK", "3/15m")
+
+	if err := service.JwtClient.InitJwtAuth(); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := types.InternalUser{}
+	user.Id = testUserId
+	user.Email = magicLinkEmail
+	user.FirstName = "Magic"
+
+	store := &magicLinkStore{user: user}
+	mailer := make(channelMailer, 10)
+
+	return &magicLinkTest{
+		t:       t,
+		handler: NewHandler(store, nil, mailer, ratelimit.NewMemoryLimiter(), config),
+		store:   store,
+		mailer:  mailer,
+	}
+}
+
+func (m *magicLinkTest) request(email string) *httptest.ResponseRecorder {
+	m.t.Helper()
+
+	encoded, err := json.Marshal(map[string]string{"email": email})
+	if err != nil {
+		m.t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	m.handler.handleRequestMagicLink(w, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(encoded)))
+	return w
+}
+
+// requestLink requests a link for the user and returns the token of the
+// emailed link and the device token of the response.
+func (m *magicLinkTest) requestLink() (string, string) {
+	m.t.Helper()
+
+	w := m.request(magicLinkEmail)
+	if w.Code != http.StatusOK {
+		m.t.Fatalf("status = %d, want 200, body = %s", w.Code, w.Body)
+	}
+
+	var data MagicLinkResponseData
+	decodeData(m.t, w, &data)
+	if data.Message != magicLinkMessage || data.DeviceToken == "" {
+		m.t.Fatalf("response = %+v", data)
+	}
+
+	select {
+	case message := <-m.mailer:
+		_, query, found := strings.Cut(message.Body, "/magic-link?")
+		if message.To != magicLinkEmail || !found {
+			m.t.Fatalf("sent %+v, want a magic link", message)
+		}
+
+		query, _, _ = strings.Cut(query, "\n")
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			m.t.Fatal(err)
+		}
+		return values.Get("token"), data.DeviceToken
+	case <-time.After(time.Second):
+		m.t.Fatal("no magic link was sent")
+		return "", ""
+	}
+}
+
+func (m *magicLinkTest) verify(token string, deviceToken string) *httptest.ResponseRecorder {
+	m.t.Helper()
+
+	r := httptest.NewRequest(http.MethodGet, "/?token="+url.QueryEscape(token), nil)
+	if deviceToken != "" {
+		r.Header.Set(MagicLinkDeviceHeader, deviceToken)
+	}
+
+	w := httptest.NewRecorder()
+	m.handler.handleVerifyMagicLink(w, r)
+	return w
+}
+
+func TestMagicLink(t *testing.T) {
+	m := newMagicLinkTest(t)
+	token, deviceToken := m.requestLink()
+
+	w := m.verify(token, deviceToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200, body = %s", w.Code, w.Body)
+	}
+
+	var data LoginResponseData
+	decodeData(t, w, &data)
+	if data.Token == "" || data.RefreshToken == "" {
+		t.Errorf("response = %+v, want tokens", data)
+	}
+
+	if m.store.user.VerifiedAt == nil {
+		t.Error("opening the link did not verify the email")
+	}
+}
+
+func TestMagicLinkUnknownEmail(t *testing.T) {
+	m := newMagicLinkTest(t)
+
+	w := m.request("unknown@example.com")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200, body = %s", w.Code, w.Body)
+	}
+
+	var data MagicLinkResponseData
+	decodeData(t, w, &data)
+	if data.Message != magicLinkMessage || data.DeviceToken == "" {
+		t.Errorf("response = %+v, want the same as for an account", data)
+	}
+
+	select {
+	case message := <-m.mailer:
+		t.Errorf("sent %+v for an unknown email", message)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestMagicLinkDevice(t *testing.T) {
+	m := newMagicLinkTest(t)
+	token, deviceToken := m.requestLink()
+
+	expectError(t, m.verify(token, ""), http.StatusUnauthorized, invalidMagicLinkMessage)
+
+	_, otherDeviceToken := newOpaqueToken()
+	expectError(t, m.verify(token, otherDeviceToken), http.StatusUnauthorized, invalidMagicLinkMessage)
+
+	// Opening a forwarded link does not spend it.
+	if w := m.verify(token, deviceToken); w.Code != http.StatusOK {
+		t.Errorf("status = %d on the requesting device, want 200, body = %s", w.Code, w.Body)
+	}
+}
+
+func TestMagicLinkReuse(t *testing.T) {
+	m := newMagicLinkTest(t)
+	token, deviceToken := m.requestLink()
+
+	if w := m.verify(token, deviceToken); w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200, body = %s", w.Code, w.Body)
+	}
+
+	expectError(t, m.verify(token, deviceToken), http.StatusUnauthorized, invalidMagicLinkMessage)
+}
+
+func TestMagicLinkReplacedByNewerLink(t *testing.T) {
+	m := newMagicLinkTest(t)
+	token, deviceToken := m.requestLink()
+	m.requestLink()
+
+	expectError(t, m.verify(token, deviceToken), http.StatusUnauthorized, invalidMagicLinkMessage)
+}
+
+func TestMagicLinkExpired(t *testing.T) {
+	m := newMagicLinkTest(t)
+	m.handler.config.MagicLinkTTL = -time.Minute
+	token, deviceToken := m.requestLink()
+
+	expectError(t, m.verify(token, deviceToken), http.StatusUnauthorized, invalidMagicLinkMessage)
+}
+
+func TestMagicLinkTampered(t *testing.T) {
+	m := newMagicLinkTest(t)
+	token, deviceToken := m.requestLink()
+
+	expectError(t, m.verify(token+"x", deviceToken), http.StatusUnauthorized, invalidMagicLinkMessage)
+}
+
+func TestMagicLinkEmailChanged(t *testing.T) {
+	m := newMagicLinkTest(t)
+	token, deviceToken := m.requestLink()
+
+	m.store.change(func(user *types.InternalUser) {
+		user.Email = "changed@example.com"
+	})
+
+	expectError(t, m.verify(token, deviceToken), http.StatusUnauthorized, invalidMagicLinkMessage)
+}
+
+func TestMagicLinkTwoFactor(t *testing.T) {
+	m := newMagicLinkTest(t)
+	m.store.change(func(user *types.InternalUser) {
+		now := time.Now()
+		user.TotpEnabledAt = &now
+	})
+	token, deviceToken := m.requestLink()
+
+	w := m.verify(token, deviceToken)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202, body = %s", w.Code, w.Body)
+	}
+
+	var data TwoFactorChallengeData
+	decodeData(t, w, &data)
+	if data.ChallengeToken == "" {
+		t.Errorf("response = %+v, want a challenge", data)
+	}
+	if len(m.store.challenges) != 1 || m.store.challenges[0].TokenHash != hashToken(data.ChallengeToken) {
+		t.Errorf("challenges = %+v, want the one in the response", m.store.challenges)
+	}
+}
+
+func TestMagicLinkRateLimit(t *testing.T) {
+	m := newMagicLinkTest(t)
+
+	for range 3 {
+		m.requestLink()
+	}
+
+	// The limit is per email, however it is written.
+	w := m.request(" MAGIC@example.com")
+	expectError(t, w, http.StatusTooManyRequests, "Too many sign-in links were requested for this email, please try again later")
+
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || retryAfter <= 0 || retryAfter > 15*60 {
+		t.Errorf("Retry-After = %q, want at most 15 minutes", w.Header().Get("Retry-After"))
+	}
+
+	if w := m.request("other@example.com"); w.Code != http.StatusOK {
+		t.Errorf("status = %d for another email, want 200", w.Code)
+	}
+}
@@ -0,0 +1,283 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/metrics"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/oauth2"
+)
+
+func (h *Handler) oauthProvider(w http.ResponseWriter, r *http.Request) (OAuthProvider, bool) {
+	provider, ok := h.providers[chi.URLParam(r, "provider")]
+	if !ok {
+		service.SendErrorsResponse(w, []string{"Provider does not exist"}, http.StatusNotFound)
+	}
+
+	return provider, ok
+}
+
+func (h *Handler) handleListOAuthProviders(w http.ResponseWriter, r *http.Request) {
+	data := make([]OAuthProviderData, 0, len(h.config.OAuthProviders))
+	for _, provider := range h.config.OAuthProviders {
+		data = append(data, OAuthProviderData{Name: provider.Name, DisplayName: provider.DisplayName})
+	}
+
+	service.SendJsonResponse(w, data, http.StatusOK)
+}
+
+// handleOAuthAuthorize starts the authorization code flow. The state, nonce
+// and PKCE verifier stay on the server; only the state's hash is stored.
+func (h *Handler) handleOAuthAuthorize(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.oauthProvider(w, r)
+	if !ok {
+		return
+	}
+
+	stateToken, stateHash := newOpaqueToken()
+	nonce, _ := newOpaqueToken()
+	state := types.OAuthState{
+		StateHash:    stateHash,
+		Provider:     provider.Config().Name,
+		CodeVerifier: oauth2.GenerateVerifier(),
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(h.config.OAuthStateTTL),
+	}
+
+	authorizationUrl, err := provider.AuthCodeURL(r.Context(), stateToken, state.Nonce, state.CodeVerifier)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "could not build authorization url", "provider", state.Provider, "error", err)
+		service.SendErrorsResponse(w, []string{"Provider is not available, please try again later"}, http.StatusBadGateway)
+		return
+	}
+
+	if err := h.store.CreateOAuthState(r.Context(), state); err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	data := OAuthAuthorizeResponseData{
+		AuthorizationUrl: authorizationUrl,
+		ExpiresAt:        state.ExpiresAt,
+	}
+
+	service.SendJsonResponse(w, data, http.StatusOK)
+}
+
+// handleOAuthCallback finishes the flow with the code and state the provider
+// redirected the user back with. Known identities log in to their user, new
+// ones are linked to the user with the same email when both the provider and
+// the user verified it, or create a new user otherwise.
+func (h *Handler) handleOAuthCallback(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.oauthProvider(w, r)
+	if !ok {
+		return
+	}
+	providerName := provider.Config().Name
+
+	var body OAuthCallbackBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := body.IsValid(); err != nil {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	state, err := h.store.ConsumeOAuthState(r.Context(), hashToken(*body.State), providerName)
+	if errors.Is(err, types.InvalidOneTimeTokenErr) {
+		metrics.LoginFailed("invalid_oauth_state")
+		service.SendErrorsResponse(w, []string{"Sign in request is not valid or has expired, please try again"}, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	info, err := provider.Exchange(r.Context(), *body.Code, state.CodeVerifier, state.Nonce)
+	if err == nil && info.Subject == "" {
+		err = errors.New("provider returned no subject")
+	}
+	if err != nil {
+		slog.WarnContext(r.Context(), "oauth code exchange failed", "provider", providerName, "error", err)
+		metrics.LoginFailed("oauth_exchange_failed")
+		service.SendErrorsResponse(w, []string{"Could not sign in with " + provider.Config().DisplayName}, http.StatusUnauthorized)
+		return
+	}
+
+	var user types.InternalUser
+	identity, err := h.store.GetIdentity(r.Context(), providerName, info.Subject)
+	switch {
+	case err == nil:
+		user, err = h.store.GetInternalUserById(r.Context(), identity.UserId)
+		if err != nil {
+			service.SendInternalServerError(w, r, err)
+			return
+		}
+
+		if err := h.store.TouchIdentity(r.Context(), identity.Id); err != nil {
+			service.SendInternalServerError(w, r, err)
+			return
+		}
+	case errors.Is(err, types.IdentityDoesNotExistErr):
+		var status int
+		var message string
+		user, status, message, err = h.linkIdentity(r, providerName, info)
+		if err != nil {
+			service.SendInternalServerError(w, r, err)
+			return
+		}
+
+		if status != 0 {
+			metrics.LoginFailed("oauth_link_rejected")
+			service.SendErrorsResponse(w, []string{message}, status)
+			return
+		}
+	default:
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if rejectDisabled(w, user) {
+		return
+	}
+
+	if user.TwoFactorEnabled() {
+		h.startTwoFactorChallenge(w, r, user)
+		return
+	}
+
+	tokens, err := h.issueTokens(r, user.User)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	metrics.LoginSucceeded()
+
+	service.SendJsonResponse(w, LoginResponseData{TokenResponseData: tokens}, http.StatusOK)
+}
+
+// linkIdentity stores a new identity for the verified user with the same
+// email or, when there is none, for a new user. A non zero status rejects the
+// sign in with the message.
+func (h *Handler) linkIdentity(r *http.Request, providerName string, info OAuthUserInfo) (types.InternalUser, int, string, error) {
+	ctx := r.Context()
+	if info.Email == "" {
+		return types.InternalUser{}, http.StatusBadRequest, "Provider did not share an email address", nil
+	}
+
+	identity := types.Identity{
+		Provider: providerName,
+		Subject:  info.Subject,
+		Email:    info.Email,
+	}
+
+	user, err := h.store.GetInternalUserByEmail(ctx, info.Email)
+	if err == nil {
+		// Linking to an unverified email would let anyone with an account at
+		// the provider take over the user.
+		if !info.EmailVerified {
+			return types.InternalUser{}, http.StatusConflict,
+				"An account with this email already exists, please log in with your password", nil
+		}
+
+		// Whoever registered an unverified account may not own the email, and
+		// would keep access through its password once the owner links it.
+		if user.VerifiedAt == nil {
+			return types.InternalUser{}, http.StatusConflict,
+				"An account with this email already exists, please verify your email address before linking it", nil
+		}
+
+		identity.UserId = user.Id
+		if _, err := h.store.CreateIdentity(ctx, identity); err != nil {
+			return types.InternalUser{}, 0, "", err
+		}
+
+		return user, 0, "", nil
+	} else if !errors.Is(err, types.UserDoesNotExistErr) {
+		return types.InternalUser{}, 0, "", err
+	}
+
+	// Users created through a provider get a random password they can
+	// replace through the password reset flow.
+	password, _ := newOpaqueToken()
+	hash, err := h.hashPassword(ctx, password)
+	if err != nil {
+		return types.InternalUser{}, 0, "", err
+	}
+
+	firstName, lastName := info.FirstName, info.LastName
+	if firstName == "" {
+		firstName, _, _ = strings.Cut(info.Email, "@")
+	}
+
+	user, err = h.store.CreateUserWithIdentity(ctx, types.InternalUser{
+		User: types.User{
+			CommonUser: types.CommonUser{
+				FirstName: truncate(firstName, 100),
+				LastName:  truncate(lastName, 100),
+				Email:     info.Email,
+			},
+		},
+		PasswordHash: hash,
+	}, identity, info.EmailVerified)
+	if err != nil {
+		return types.InternalUser{}, 0, "", err
+	}
+
+	if _, err := h.sendVerificationEmail(ctx, user.User); err != nil {
+		return types.InternalUser{}, 0, "", err
+	}
+
+	return user, 0, "", nil
+}
+
+func truncate(value string, length int) string {
+	runes := []rune(value)
+	if len(runes) > length {
+		return string(runes[:length])
+	}
+	return value
+}
+
+func (h *Handler) handleListIdentities(w http.ResponseWriter, r *http.Request) {
+	identities, err := h.store.GetUserIdentities(r.Context(), service.UserIdFromContext(r.Context()))
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	service.SendJsonResponse(w, identities, http.StatusOK)
+}
+
+func (h *Handler) handleDeleteIdentity(w http.ResponseWriter, r *http.Request) {
+	identityId, err := strconv.Atoi(chi.URLParam(r, "identityId"))
+	if err != nil {
+		service.SendErrorsResponse(w, []string{"Provided url param was not parsable"}, http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.store.DeleteIdentity(r.Context(), service.UserIdFromContext(r.Context()), identityId)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if !deleted {
+		service.SendErrorsResponse(w, []string{"Identity does not exist"}, http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
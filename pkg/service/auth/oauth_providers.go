@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OAuthUserInfo is what a provider tells us about the user that signed in.
+type OAuthUserInfo struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+}
+
+type OAuthProvider interface {
+	Config() OAuthProviderConfig
+	// AuthCodeURL returns the url the user is sent to, with the state, the
+	// nonce and the PKCE challenge for the verifier.
+	AuthCodeURL(ctx context.Context, state string, nonce string, verifier string) (string, error)
+	// Exchange trades the authorization code for the user's info, checking
+	// the ID token against the provider's keys and the nonce when the
+	// provider issues one.
+	Exchange(ctx context.Context, code string, verifier string, nonce string) (OAuthUserInfo, error)
+}
+
+func newOAuthProvider(config OAuthProviderConfig) OAuthProvider {
+	switch config.Type {
+	case OAuthProviderGitHub:
+		return &gitHubProvider{config: config}
+	default:
+		return &oidcProvider{config: config}
+	}
+}
+
+// oidcProvider runs discovery on first use rather than at startup, so an
+// unreachable provider does not keep the API from starting.
+type oidcProvider struct {
+	config OAuthProviderConfig
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+func (p *oidcProvider) Config() OAuthProviderConfig {
+	return p.config
+}
+
+func (p *oidcProvider) discover(ctx context.Context) (*oidc.Provider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.provider != nil {
+		return p.provider, nil
+	}
+
+	ctx, span := tracing.Start(ctx, "oidc.discover")
+	provider, err := oidc.NewProvider(ctx, p.config.Issuer)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("could not discover oidc provider %s: %w", p.config.Name, err)
+	}
+
+	p.provider = provider
+	return provider, nil
+}
+
+func (p *oidcProvider) oauth2Config(provider *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.config.ClientId,
+		ClientSecret: p.config.ClientSecret,
+		RedirectURL:  p.config.RedirectUrl,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       p.config.Scopes,
+	}
+}
+
+func (p *oidcProvider) AuthCodeURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return p.oauth2Config(provider).AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code string, verifier string, nonce string) (info OAuthUserInfo, err error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return OAuthUserInfo{}, err
+	}
+
+	ctx, span := tracing.Start(ctx, "oidc.exchange")
+	defer func() { tracing.End(span, err) }()
+
+	token, err := p.oauth2Config(provider).Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return OAuthUserInfo{}, err
+	}
+
+	rawIdToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return OAuthUserInfo{}, errors.New("token response has no id_token")
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: p.config.ClientId}).Verify(ctx, rawIdToken)
+	if err != nil {
+		return OAuthUserInfo{}, err
+	}
+
+	if idToken.Nonce != nonce {
+		return OAuthUserInfo{}, errors.New("id token nonce does not match")
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified any    `json:"email_verified"`
+		GivenName     string `json:"given_name"`
+		FamilyName    string `json:"family_name"`
+		Name          string `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return OAuthUserInfo{}, err
+	}
+
+	info = OAuthUserInfo{
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: isTrue(claims.EmailVerified),
+		FirstName:     claims.GivenName,
+		LastName:      claims.FamilyName,
+	}
+	if info.FirstName == "" && info.LastName == "" {
+		info.FirstName, info.LastName = splitName(claims.Name)
+	}
+
+	return info, nil
+}
+
+// isTrue accepts email_verified as a boolean or, as some providers send it,
+// a string.
+func isTrue(value any) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		verified, _ := strconv.ParseBool(v)
+		return verified
+	}
+	return false
+}
+
+func splitName(name string) (string, string) {
+	first, last, _ := strings.Cut(strings.TrimSpace(name), " ")
+	return first, strings.TrimSpace(last)
+}
+
+type gitHubProvider struct {
+	config OAuthProviderConfig
+}
+
+func (p *gitHubProvider) Config() OAuthProviderConfig {
+	return p.config
+}
+
+func (p *gitHubProvider) oauth2Config() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.config.ClientId,
+		ClientSecret: p.config.ClientSecret,
+		RedirectURL:  p.config.RedirectUrl,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  p.config.AuthUrl,
+			TokenURL: p.config.TokenUrl,
+		},
+		Scopes: p.config.Scopes,
+	}
+}
+
+func (p *gitHubProvider) AuthCodeURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	return p.oauth2Config().AuthCodeURL(state, oauth2.S256ChallengeOption(verifier)), nil
+}
+
+func (p *gitHubProvider) Exchange(ctx context.Context, code string, verifier string, nonce string) (info OAuthUserInfo, err error) {
+	ctx, span := tracing.Start(ctx, "github.exchange")
+	defer func() { tracing.End(span, err) }()
+
+	config := p.oauth2Config()
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return OAuthUserInfo{}, err
+	}
+
+	client := config.Client(ctx, token)
+
+	var user struct {
+		Id    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := p.get(ctx, client, "/user", &user); err != nil {
+		return OAuthUserInfo{}, err
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.get(ctx, client, "/user/emails", &emails); err != nil {
+		return OAuthUserInfo{}, err
+	}
+
+	info = OAuthUserInfo{Subject: strconv.FormatInt(user.Id, 10)}
+	info.FirstName, info.LastName = splitName(user.Name)
+	if info.FirstName == "" {
+		info.FirstName = user.Login
+	}
+
+	for _, email := range emails {
+		if email.Primary {
+			info.Email = email.Email
+			info.EmailVerified = email.Verified
+		}
+	}
+
+	return info, nil
+}
+
+func (p *gitHubProvider) get(ctx context.Context, client *http.Client, path string, out any) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.config.ApiUrl, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/vnd.github+json")
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("github %s returned status %d", path, response.StatusCode)
+	}
+
+	return json.NewDecoder(response.Body).Decode(out)
+}
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// CreateOAuthState stores the state of a started authorization and removes
+// expired ones.
+func (s *Store) CreateOAuthState(ctx context.Context, state types.OAuthState) (err error) {
+	query := `
+        INSERT INTO oauth_states (state_hash, provider, code_verifier, nonce, expires_at)
+        VALUES ($1, $2, $3, $4, $5)`
+
+	ctx, span := db.StartQuerySpan(ctx, "oauth_states.insert", query)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.db.ExecContext(ctx, `DELETE FROM oauth_states WHERE expires_at < now()`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, query, state.StateHash, state.Provider, state.CodeVerifier, state.Nonce, state.ExpiresAt)
+	return err
+}
+
+// ConsumeOAuthState deletes and returns the state started for the provider.
+// InvalidOneTimeTokenErr is returned for unknown and expired states.
+func (s *Store) ConsumeOAuthState(ctx context.Context, stateHash string, provider string) (state types.OAuthState, err error) {
+	query := `
+        DELETE FROM oauth_states
+        WHERE state_hash = $1 AND provider = $2 AND expires_at > now()
+        RETURNING id, state_hash, provider, code_verifier, nonce, expires_at, created_at`
+
+	ctx, span := db.StartQuerySpan(ctx, "oauth_states.consume", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = s.db.QueryRowContext(ctx, query, stateHash, provider).Scan(
+		&state.Id,
+		&state.StateHash,
+		&state.Provider,
+		&state.CodeVerifier,
+		&state.Nonce,
+		&state.ExpiresAt,
+		&state.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.OAuthState{}, types.InvalidOneTimeTokenErr
+	}
+	if err != nil {
+		return types.OAuthState{}, err
+	}
+
+	return state, nil
+}
+
+const identityFields = `id, user_id, provider, subject, email, last_login_at, created_at`
+
+func scanIdentityRow(row db.Scannable) (types.Identity, error) {
+	var identity types.Identity
+	err := row.Scan(
+		&identity.Id,
+		&identity.UserId,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.Email,
+		&identity.LastLoginAt,
+		&identity.CreatedAt,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.Identity{}, types.IdentityDoesNotExistErr
+	}
+	if err != nil {
+		return types.Identity{}, err
+	}
+
+	return identity, nil
+}
+
+func (s *Store) GetIdentity(ctx context.Context, provider string, subject string) (identity types.Identity, err error) {
+	query := `SELECT ` + identityFields + ` FROM identities WHERE provider = $1 AND subject = $2`
+
+	ctx, span := db.StartQuerySpan(ctx, "identities.select", query)
+	defer func() {
+		if errors.Is(err, types.IdentityDoesNotExistErr) {
+			tracing.End(span, nil)
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	return scanIdentityRow(s.db.QueryRowContext(ctx, query, provider, subject))
+}
+
+func (s *Store) GetUserIdentities(ctx context.Context, userId int) (identities []types.Identity, err error) {
+	query := `SELECT ` + identityFields + ` FROM identities WHERE user_id = $1 ORDER BY created_at`
+
+	ctx, span := db.StartQuerySpan(ctx, "identities.select", query)
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities = make([]types.Identity, 0)
+	for rows.Next() {
+		identity, err := scanIdentityRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+
+	return identities, rows.Err()
+}
+
+func (s *Store) CreateIdentity(ctx context.Context, identity types.Identity) (newIdentity types.Identity, err error) {
+	query := `
+        INSERT INTO identities (user_id, provider, subject, email, last_login_at)
+        VALUES ($1, $2, $3, $4, now())
+        RETURNING ` + identityFields
+
+	ctx, span := db.StartQuerySpan(ctx, "identities.insert", query)
+	defer func() { tracing.End(span, err) }()
+
+	row := s.db.QueryRowContext(ctx, query, identity.UserId, identity.Provider, identity.Subject, identity.Email)
+	return scanIdentityRow(row)
+}
+
+// CreateUserWithIdentity creates a user signing in with a provider for the
+// first time together with their identity. The user is verified when the
+// provider verified their email.
+func (s *Store) CreateUserWithIdentity(ctx context.Context, user types.InternalUser, identity types.Identity, emailVerified bool) (newUser types.InternalUser, err error) {
+	query := `
+        INSERT INTO users (first_name, last_name, email, password_hash, verified_at)
+        VALUES ($1, $2, $3, $4, CASE WHEN $5 THEN now() END)
+        RETURNING ` + userFields
+
+	ctx, span := db.StartQuerySpan(ctx, "users.insert", query)
+	defer func() { tracing.End(span, err) }()
+
+	transaction, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return types.InternalUser{}, err
+	}
+	defer transaction.Rollback()
+
+	row := transaction.QueryRowContext(ctx, query,
+		user.FirstName,
+		user.LastName,
+		user.Email,
+		user.PasswordHash,
+		emailVerified,
+	)
+
+	newUser, err = scanUserRow(row)
+	if err != nil {
+		return types.InternalUser{}, err
+	}
+
+	_, err = transaction.ExecContext(ctx, `
+        INSERT INTO identities (user_id, provider, subject, email, last_login_at)
+        VALUES ($1, $2, $3, $4, now())`,
+		newUser.Id, identity.Provider, identity.Subject, identity.Email,
+	)
+	if err != nil {
+		return types.InternalUser{}, err
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		return types.InternalUser{}, err
+	}
+
+	return newUser, nil
+}
+
+func (s *Store) TouchIdentity(ctx context.Context, id int) (err error) {
+	query := `UPDATE identities SET last_login_at = now() WHERE id = $1`
+
+	ctx, span := db.StartQuerySpan(ctx, "identities.update_last_login_at", query)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// DeleteIdentity unlinks one of the user's identities. It reports false when
+// the user has no such identity.
+func (s *Store) DeleteIdentity(ctx context.Context, userId int, id int) (deleted bool, err error) {
+	query := `DELETE FROM identities WHERE id = $1 AND user_id = $2`
+
+	ctx, span := db.StartQuerySpan(ctx, "identities.delete", query)
+	defer func() { tracing.End(span, err) }()
+
+	result, err := s.db.ExecContext(ctx, query, id, userId)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/ratelimit"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+// fakeOAuthProvider signs every code in as its user.
+type fakeOAuthProvider struct {
+	info OAuthUserInfo
+}
+
+func (p *fakeOAuthProvider) Config() OAuthProviderConfig {
+	return OAuthProviderConfig{Name: "test", DisplayName: "Test"}
+}
+
+func (p *fakeOAuthProvider) AuthCodeURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	return "https://provider.example.com/authorize?state=" + state, nil
+}
+
+func (p *fakeOAuthProvider) Exchange(ctx context.Context, code string, verifier string, nonce string) (OAuthUserInfo, error) {
+	return p.info, nil
+}
+
+// oauthStore keeps the users and identities of the OAuth handlers in memory.
+// Other methods of db.AuthStore are not implemented.
+type oauthStore struct {
+	db.AuthStore
+
+	users      []types.InternalUser
+	identities []types.Identity
+}
+
+func (s *oauthStore) ConsumeOAuthState(ctx context.Context, stateHash string, provider string) (types.OAuthState, error) {
+	return types.OAuthState{StateHash: stateHash, Provider: provider}, nil
+}
+
+func (s *oauthStore) GetIdentity(ctx context.Context, provider string, subject string) (types.Identity, error) {
+	for _, identity := range s.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return types.Identity{}, types.IdentityDoesNotExistErr
+}
+
+func (s *oauthStore) CreateIdentity(ctx context.Context, identity types.Identity) (types.Identity, error) {
+	identity.Id = len(s.identities) + 1
+	s.identities = append(s.identities, identity)
+	return identity, nil
+}
+
+func (s *oauthStore) GetInternalUserByEmail(ctx context.Context, email string) (types.InternalUser, error) {
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return types.InternalUser{}, types.UserDoesNotExistErr
+}
+
+func (s *oauthStore) CreateUserWithIdentity(ctx context.Context, user types.InternalUser, identity types.Identity, emailVerified bool) (types.InternalUser, error) {
+	user.Id = len(s.users) + 1
+	if emailVerified {
+		now := time.Now()
+		user.VerifiedAt = &now
+	}
+	s.users = append(s.users, user)
+
+	identity.UserId = user.Id
+	_, err := s.CreateIdentity(ctx, identity)
+	return user, err
+}
+
+func (s *oauthStore) CreateRefreshToken(ctx context.Context, token types.RefreshToken) (types.RefreshToken, error) {
+	return token, nil
+}
+
+func (s *oauthStore) TouchSession(ctx context.Context, session types.Session) (types.Session, error) {
+	session.Id = 1
+	return session, nil
+}
+
+// addUser stores a user registered with a password, verified when verified
+// is set.
+func (s *oauthStore) addUser(email string, verified bool) types.InternalUser {
+	user := types.InternalUser{PasswordHash: "registered password hash"}
+	user.Id = len(s.users) + 1
+	user.Email = email
+	if verified {
+		now := time.Now()
+		user.VerifiedAt = &now
+	}
+
+	s.users = append(s.users, user)
+	return user
+}
+
+func newOAuthTest(t *testing.T, info OAuthUserInfo) (*Handler, *oauthStore) {
+	t.Setenv("JWT_SECRET", "test-jwt-secret")
+	t.Setenv("JWT_ALGORITHM", "HS256")
+	t.Setenv("EMAIL_VERIFICATION_SECRET", "test-email-verification-secret")
+	t.Setenv("APP_URL", testOrigin)
+	t.Setenv("WEBAUTHN_RP_ID", "localhost")
+	t.Setenv("WEBAUTHN_RP_NAME", "JobApplicationTracker-test")
+
+	if err := service.JwtClient.InitJwtAuth(); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := &oauthStore{}
+	handler := NewHandler(store, nil, make(channelMailer, 10), ratelimit.NewMemoryLimiter(), config)
+	handler.providers = map[string]OAuthProvider{"test": &fakeOAuthProvider{info: info}}
+
+	return handler, store
+}
+
+func callOAuthCallback(t *testing.T, handler *Handler) *httptest.ResponseRecorder {
+	t.Helper()
+
+	encoded, err := json.Marshal(map[string]string{"code": "code", "state": "state"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("provider", "test")
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(encoded))
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext))
+
+	w := httptest.NewRecorder()
+	handler.handleOAuthCallback(w, r)
+	return w
+}
+
+func TestOAuthCallbackLinksVerifiedUser(t *testing.T) {
+	handler, store := newOAuthTest(t, OAuthUserInfo{Subject: "subject", Email: "user@example.com", EmailVerified: true})
+	user := store.addUser("user@example.com", true)
+
+	w := callOAuthCallback(t, handler)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200, body = %s", w.Code, w.Body)
+	}
+
+	var data LoginResponseData
+	decodeData(t, w, &data)
+	if data.Token == "" || data.RefreshToken == "" {
+		t.Errorf("response = %+v, want tokens", data)
+	}
+
+	if len(store.identities) != 1 || store.identities[0].UserId != user.Id {
+		t.Errorf("identities = %+v, want one for user %d", store.identities, user.Id)
+	}
+	if len(store.users) != 1 {
+		t.Errorf("created a user next to the existing one: %+v", store.users)
+	}
+}
+
+func TestOAuthCallbackRejectsUnverifiedUser(t *testing.T) {
+	handler, store := newOAuthTest(t, OAuthUserInfo{Subject: "subject", Email: "user@example.com", EmailVerified: true})
+	store.addUser("user@example.com", false)
+
+	w := callOAuthCallback(t, handler)
+	expectError(t, w, http.StatusConflict, "An account with this email already exists, please verify your email address before linking it")
+
+	if len(store.identities) != 0 {
+		t.Errorf("identities = %+v, want none", store.identities)
+	}
+	if store.users[0].VerifiedAt != nil {
+		t.Error("the user was marked as verified")
+	}
+}
+
+func TestOAuthCallbackRejectsUnverifiedProviderEmail(t *testing.T) {
+	handler, store := newOAuthTest(t, OAuthUserInfo{Subject: "subject", Email: "user@example.com"})
+	store.addUser("user@example.com", true)
+
+	w := callOAuthCallback(t, handler)
+	expectError(t, w, http.StatusConflict, "An account with this email already exists, please log in with your password")
+
+	if len(store.identities) != 0 {
+		t.Errorf("identities = %+v, want none", store.identities)
+	}
+}
@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/lib/pq"
+)
+
+const passkeyFields = `id, user_id, name, credential_id, public_key, algorithm, sign_count, aaguid,
+        attestation_format, transports, backup_eligible, backed_up, last_used_at, created_at`
+
+func scanPasskeyRow(row db.Scannable, extra ...any) (types.Passkey, error) {
+	var passkey types.Passkey
+	err := row.Scan(append([]any{
+		&passkey.Id,
+		&passkey.UserId,
+		&passkey.Name,
+		&passkey.CredentialId,
+		&passkey.PublicKey,
+		&passkey.Algorithm,
+		&passkey.SignCount,
+		&passkey.Aaguid,
+		&passkey.AttestationFormat,
+		pq.Array(&passkey.Transports),
+		&passkey.BackupEligible,
+		&passkey.BackedUp,
+		&passkey.LastUsedAt,
+		&passkey.CreatedAt,
+	}, extra...)...)
+
+	return passkey, err
+}
+
+// GetWebAuthnUserHandle returns the user's WebAuthn user handle, setting it
+// to newHandle when the user has none yet.
+func (s *Store) GetWebAuthnUserHandle(ctx context.Context, userId int, newHandle []byte) (handle []byte, err error) {
+	query := `
+        UPDATE users SET webauthn_user_handle = COALESCE(webauthn_user_handle, $2)
+        WHERE id = $1
+        RETURNING webauthn_user_handle`
+
+	ctx, span := db.StartQuerySpan(ctx, "users.webauthn_user_handle", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = s.db.QueryRowContext(ctx, query, userId, newHandle).Scan(&handle)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, types.UserDoesNotExistErr
+	}
+
+	return handle, err
+}
+
+// CreateWebAuthnChallenge stores a challenge and removes expired ones.
+func (s *Store) CreateWebAuthnChallenge(ctx context.Context, challenge types.WebAuthnChallenge) (err error) {
+	query := `
+        INSERT INTO webauthn_challenges (challenge_hash, ceremony, user_id, expires_at)
+        VALUES ($1, $2, $3, $4)`
+
+	ctx, span := db.StartQuerySpan(ctx, "webauthn_challenges.insert", query)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.db.ExecContext(ctx, `DELETE FROM webauthn_challenges WHERE expires_at < now()`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, query, challenge.ChallengeHash, challenge.Ceremony, challenge.UserId, challenge.ExpiresAt)
+	return err
+}
+
+// ConsumeWebAuthnChallenge spends a challenge of the ceremony issued to the
+// user, or to nobody when userId is nil. InvalidOneTimeTokenErr is returned
+// for unknown, spent and expired challenges.
+func (s *Store) ConsumeWebAuthnChallenge(ctx context.Context, challengeHash string, ceremony string, userId *int) (err error) {
+	query := `
+        DELETE FROM webauthn_challenges
+        WHERE challenge_hash = $1 AND ceremony = $2 AND user_id IS NOT DISTINCT FROM $3 AND expires_at > now()`
+
+	ctx, span := db.StartQuerySpan(ctx, "webauthn_challenges.consume", query)
+	defer func() {
+		if errors.Is(err, types.InvalidOneTimeTokenErr) {
+			tracing.End(span, nil)
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	result, err := s.db.ExecContext(ctx, query, challengeHash, ceremony, userId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return types.InvalidOneTimeTokenErr
+	}
+
+	return nil
+}
+
+// CreatePasskey returns PasskeyExistsErr when any user registered the
+// credential before.
+func (s *Store) CreatePasskey(ctx context.Context, passkey types.Passkey) (created types.Passkey, err error) {
+	query := `
+        INSERT INTO passkeys (user_id, name, credential_id, public_key, algorithm, sign_count, aaguid,
+            attestation_format, transports, backup_eligible, backed_up)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+        RETURNING ` + passkeyFields
+
+	ctx, span := db.StartQuerySpan(ctx, "passkeys.insert", query)
+	defer func() { tracing.End(span, err) }()
+
+	created, err = scanPasskeyRow(s.db.QueryRowContext(ctx, query,
+		passkey.UserId, passkey.Name, passkey.CredentialId, passkey.PublicKey, passkey.Algorithm,
+		passkey.SignCount, passkey.Aaguid, passkey.AttestationFormat, pq.Array(passkey.Transports),
+		passkey.BackupEligible, passkey.BackedUp,
+	))
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return types.Passkey{}, types.PasskeyExistsErr
+	}
+	if err != nil {
+		return types.Passkey{}, err
+	}
+
+	return created, nil
+}
+
+func (s *Store) GetUserPasskeys(ctx context.Context, userId int) (passkeys []types.Passkey, err error) {
+	query := `SELECT ` + passkeyFields + ` FROM passkeys WHERE user_id = $1 ORDER BY id`
+
+	ctx, span := db.StartQuerySpan(ctx, "passkeys.select_many", query)
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passkeys = make([]types.Passkey, 0)
+	for rows.Next() {
+		passkey, err := scanPasskeyRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		passkeys = append(passkeys, passkey)
+	}
+
+	return passkeys, rows.Err()
+}
+
+// GetPasskeyByCredentialId returns the passkey with its owner's WebAuthn
+// user handle.
+func (s *Store) GetPasskeyByCredentialId(ctx context.Context, credentialId []byte) (passkey types.Passkey, userHandle []byte, err error) {
+	query := `
+        SELECT ` + passkeyFields + `,
+            (SELECT webauthn_user_handle FROM users WHERE users.id = passkeys.user_id)
+        FROM passkeys WHERE credential_id = $1`
+
+	ctx, span := db.StartQuerySpan(ctx, "passkeys.select", query)
+	defer func() {
+		if errors.Is(err, types.PasskeyDoesNotExistErr) {
+			tracing.End(span, nil)
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	passkey, err = scanPasskeyRow(s.db.QueryRowContext(ctx, query, credentialId), &userHandle)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.Passkey{}, nil, types.PasskeyDoesNotExistErr
+	}
+	if err != nil {
+		return types.Passkey{}, nil, err
+	}
+
+	return passkey, userHandle, nil
+}
+
+// UsePasskey records a login with the passkey and its new signature counter.
+// It reports false when the counter changed since oldSignCount was read, as
+// the passkey was used concurrently.
+func (s *Store) UsePasskey(ctx context.Context, id int, oldSignCount uint32, newSignCount uint32, backedUp bool) (used bool, err error) {
+	query := `
+        UPDATE passkeys SET sign_count = $3, backed_up = $4, last_used_at = now()
+        WHERE id = $1 AND sign_count = $2`
+
+	ctx, span := db.StartQuerySpan(ctx, "passkeys.use", query)
+	defer func() { tracing.End(span, err) }()
+
+	result, err := s.db.ExecContext(ctx, query, id, oldSignCount, newSignCount, backedUp)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// DeletePasskey reports whether the user had such a passkey.
+func (s *Store) DeletePasskey(ctx context.Context, userId int, id int) (deleted bool, err error) {
+	query := `DELETE FROM passkeys WHERE id = $1 AND user_id = $2`
+
+	ctx, span := db.StartQuerySpan(ctx, "passkeys.delete", query)
+	defer func() { tracing.End(span, err) }()
+
+	result, err := s.db.ExecContext(ctx, query, id, userId)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
@@ -0,0 +1,317 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/mailer"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/metrics"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/webauthn"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	ceremonyRegistration   = "registration"
+	ceremonyAuthentication = "authentication"
+
+	webauthnUserHandleLength = 32
+
+	expiredCeremonyMessage = "Passkey request expired or was already used, please try again"
+	invalidPasskeyMessage  = "Passkey is not valid"
+)
+
+// handleBeginPasskeyRegistration returns the options for the browser to
+// create a passkey with.
+func (h *Handler) handleBeginPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	user, err := h.store.GetInternalUserById(r.Context(), service.UserIdFromContext(r.Context()))
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	newHandle := make([]byte, webauthnUserHandleLength)
+	rand.Read(newHandle)
+
+	handle, err := h.store.GetWebAuthnUserHandle(r.Context(), user.Id, newHandle)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	passkeys, err := h.store.GetUserPasskeys(r.Context(), user.Id)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	exclude := make([]webauthn.CredentialDescriptor, len(passkeys))
+	for i, passkey := range passkeys {
+		exclude[i] = passkeyCredential(passkey).Descriptor()
+	}
+
+	challenge, err := h.createWebAuthnChallenge(r, ceremonyRegistration, &user.Id)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	options := h.config.WebAuthn.CreationOptions(challenge, webauthn.UserEntity{
+		Id:          handle,
+		Name:        user.Email,
+		DisplayName: strings.TrimSpace(user.FirstName + " " + user.LastName),
+	}, exclude)
+
+	service.SendJsonResponse(w, options, http.StatusOK)
+}
+
+// handleFinishPasskeyRegistration verifies the credential the browser
+// created and stores it as a passkey of the user.
+func (h *Handler) handleFinishPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	var body RegisterPasskeyBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := body.IsValid(); err != nil {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.store.GetInternalUserById(r.Context(), service.UserIdFromContext(r.Context()))
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	challenge, err := webauthn.ChallengeOf(body.Credential.Response.ClientDataJSON)
+	if err == nil {
+		err = h.store.ConsumeWebAuthnChallenge(r.Context(), hashToken(string(challenge)), ceremonyRegistration, &user.Id)
+	}
+	if errors.Is(err, webauthn.ErrVerification) || errors.Is(err, types.InvalidOneTimeTokenErr) {
+		service.SendErrorsResponse(w, []string{expiredCeremonyMessage}, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	credential, err := h.config.WebAuthn.VerifyRegistration(*body.Credential, challenge)
+	if err != nil {
+		slog.InfoContext(r.Context(), "passkey registration failed", "user_id", user.Id, "error", err)
+		service.SendErrorsResponse(w, []string{"Passkey could not be verified"}, http.StatusBadRequest)
+		return
+	}
+
+	name := describeDevice(r.UserAgent())
+	if body.Name != nil {
+		name = *body.Name
+	}
+
+	passkey, err := h.store.CreatePasskey(r.Context(), types.Passkey{
+		UserId:            user.Id,
+		Name:              name,
+		CredentialId:      credential.Id,
+		PublicKey:         credential.PublicKey,
+		Algorithm:         credential.Algorithm,
+		SignCount:         credential.SignCount,
+		Aaguid:            credential.AAGUID,
+		AttestationFormat: credential.AttestationFormat,
+		Transports:        credential.Transports,
+		BackupEligible:    credential.BackupEligible,
+		BackedUp:          credential.BackedUp,
+	})
+	if errors.Is(err, types.PasskeyExistsErr) {
+		service.SendErrorsResponse(w, []string{"Passkey is already registered"}, http.StatusConflict)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	h.sendEmail(r.Context(), mailer.Message{
+		To:      user.Email,
+		Subject: "A passkey was added to your account",
+		Body: fmt.Sprintf("Hi %s,\n\nThe passkey %q was added to your account and can now be used to sign in.\n\n"+
+			"If you did not add it, remove it from your account and reset your password right away.\n",
+			user.FirstName, passkey.Name),
+	})
+
+	service.SendJsonResponse(w, passkey, http.StatusCreated)
+}
+
+func (h *Handler) handleListPasskeys(w http.ResponseWriter, r *http.Request) {
+	passkeys, err := h.store.GetUserPasskeys(r.Context(), service.UserIdFromContext(r.Context()))
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	service.SendJsonResponse(w, passkeys, http.StatusOK)
+}
+
+func (h *Handler) handleDeletePasskey(w http.ResponseWriter, r *http.Request) {
+	passkeyId, err := strconv.Atoi(chi.URLParam(r, "passkeyId"))
+	if err != nil {
+		service.SendErrorsResponse(w, []string{"Provided url param was not parsable"}, http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.store.DeletePasskey(r.Context(), service.UserIdFromContext(r.Context()), passkeyId)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if !deleted {
+		service.SendErrorsResponse(w, []string{"Passkey does not exist"}, http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleBeginPasskeyLogin returns the options for the browser to sign in
+// with any passkey of the service, so the user does not even enter an email.
+func (h *Handler) handleBeginPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	challenge, err := h.createWebAuthnChallenge(r, ceremonyAuthentication, nil)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	service.SendJsonResponse(w, h.config.WebAuthn.RequestOptions(challenge, nil), http.StatusOK)
+}
+
+// handleFinishPasskeyLogin verifies the passkey's signature and responds like
+// a login with a password. Passkeys verify the user themselves, with a PIN
+// or biometrics, so users with 2FA enabled get no second challenge.
+func (h *Handler) handleFinishPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	var body PasskeyLoginBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := body.IsValid(); err != nil {
+		metrics.LoginFailed("invalid_body")
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	challenge, err := webauthn.ChallengeOf(body.Credential.Response.ClientDataJSON)
+	if err == nil {
+		err = h.store.ConsumeWebAuthnChallenge(r.Context(), hashToken(string(challenge)), ceremonyAuthentication, nil)
+	}
+	if errors.Is(err, webauthn.ErrVerification) || errors.Is(err, types.InvalidOneTimeTokenErr) {
+		metrics.LoginFailed("invalid_passkey")
+		service.SendErrorsResponse(w, []string{expiredCeremonyMessage}, http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	passkey, userHandle, err := h.store.GetPasskeyByCredentialId(r.Context(), body.Credential.RawId)
+	if errors.Is(err, types.PasskeyDoesNotExistErr) {
+		metrics.LoginFailed("unknown_passkey")
+		service.SendErrorsResponse(w, []string{invalidPasskeyMessage}, http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	authentication, err := h.config.WebAuthn.VerifyAuthentication(*body.Credential, challenge, passkeyCredential(passkey), userHandle)
+	if errors.Is(err, webauthn.ErrSignCount) {
+		slog.WarnContext(r.Context(), "passkey signature counter did not increase, it may have been cloned",
+			"user_id", passkey.UserId, "passkey_id", passkey.Id)
+		metrics.LoginFailed("cloned_passkey")
+		service.SendErrorsResponse(w, []string{invalidPasskeyMessage}, http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		slog.InfoContext(r.Context(), "passkey login failed", "user_id", passkey.UserId, "passkey_id", passkey.Id, "error", err)
+		metrics.LoginFailed("invalid_passkey")
+		service.SendErrorsResponse(w, []string{invalidPasskeyMessage}, http.StatusUnauthorized)
+		return
+	}
+
+	// The counter check only holds if a concurrent login with the same
+	// counter cannot also succeed.
+	used, err := h.store.UsePasskey(r.Context(), passkey.Id, passkey.SignCount, authentication.SignCount, authentication.BackedUp)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+	if !used {
+		metrics.LoginFailed("cloned_passkey")
+		service.SendErrorsResponse(w, []string{invalidPasskeyMessage}, http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.store.GetInternalUserById(r.Context(), passkey.UserId)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if rejectDisabled(w, user) {
+		return
+	}
+
+	if err := h.clearLoginFailures(r.Context(), user.Email); err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	tokens, err := h.issueTokens(r, user.User)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	metrics.LoginSucceeded()
+
+	service.SendJsonResponse(w, LoginResponseData{TokenResponseData: tokens}, http.StatusOK)
+}
+
+// createWebAuthnChallenge stores a new challenge of the ceremony, which only
+// the user can spend when userId is set.
+func (h *Handler) createWebAuthnChallenge(r *http.Request, ceremony string, userId *int) ([]byte, error) {
+	challenge := webauthn.NewChallenge()
+
+	err := h.store.CreateWebAuthnChallenge(r.Context(), types.WebAuthnChallenge{
+		ChallengeHash: hashToken(string(challenge)),
+		Ceremony:      ceremony,
+		UserId:        userId,
+		ExpiresAt:     time.Now().Add(h.config.WebAuthn.Timeout),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+func passkeyCredential(passkey types.Passkey) webauthn.Credential {
+	return webauthn.Credential{
+		Id:                passkey.CredentialId,
+		PublicKey:         passkey.PublicKey,
+		Algorithm:         passkey.Algorithm,
+		SignCount:         passkey.SignCount,
+		AAGUID:            passkey.Aaguid,
+		AttestationFormat: passkey.AttestationFormat,
+		Transports:        passkey.Transports,
+		BackupEligible:    passkey.BackupEligible,
+		BackedUp:          passkey.BackedUp,
+	}
+}
@@ -0,0 +1,525 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/mailer"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/ratelimit"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/webauthn"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/webauthn/webauthntest"
+)
+
+const (
+	testOrigin = "http://localhost:3000"
+	testUserId = 7
+)
+
+// passkeyStore keeps the users, challenges and passkeys of the passkey
+// handlers in memory. Other methods of db.AuthStore are not implemented.
+type passkeyStore struct {
+	db.AuthStore
+
+	mu         sync.Mutex
+	user       types.InternalUser
+	handle     []byte
+	challenges map[string]types.WebAuthnChallenge
+	passkeys   []types.Passkey
+}
+
+func newPasskeyStore() *passkeyStore {
+	user := types.InternalUser{}
+	user.Id = testUserId
+	user.Email = "passkey@example.com"
+	user.FirstName = "Pass"
+	user.LastName = "Key"
+
+	return &passkeyStore{user: user, challenges: make(map[string]types.WebAuthnChallenge)}
+}
+
+func (s *passkeyStore) GetInternalUserById(ctx context.Context, id int) (types.InternalUser, error) {
+	if id != s.user.Id {
+		return types.InternalUser{}, types.UserDoesNotExistErr
+	}
+	return s.user, nil
+}
+
+func (s *passkeyStore) GetWebAuthnUserHandle(ctx context.Context, userId int, newHandle []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.handle == nil {
+		s.handle = newHandle
+	}
+	return s.handle, nil
+}
+
+func (s *passkeyStore) CreateWebAuthnChallenge(ctx context.Context, challenge types.WebAuthnChallenge) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.challenges[challenge.ChallengeHash] = challenge
+	return nil
+}
+
+func (s *passkeyStore) ConsumeWebAuthnChallenge(ctx context.Context, challengeHash string, ceremony string, userId *int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	challenge, ok := s.challenges[challengeHash]
+	if !ok || challenge.Ceremony != ceremony || (challenge.UserId == nil) != (userId == nil) ||
+		(userId != nil && *challenge.UserId != *userId) {
+		return types.InvalidOneTimeTokenErr
+	}
+
+	delete(s.challenges, challengeHash)
+	return nil
+}
+
+func (s *passkeyStore) CreatePasskey(ctx context.Context, passkey types.Passkey) (types.Passkey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.passkeys {
+		if bytes.Equal(existing.CredentialId, passkey.CredentialId) {
+			return types.Passkey{}, types.PasskeyExistsErr
+		}
+	}
+
+	passkey.Id = len(s.passkeys) + 1
+	s.passkeys = append(s.passkeys, passkey)
+	return passkey, nil
+}
+
+func (s *passkeyStore) GetUserPasskeys(ctx context.Context, userId int) ([]types.Passkey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.passkeys), nil
+}
+
+func (s *passkeyStore) GetPasskeyByCredentialId(ctx context.Context, credentialId []byte) (types.Passkey, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, passkey := range s.passkeys {
+		if bytes.Equal(passkey.CredentialId, credentialId) {
+			return passkey, s.handle, nil
+		}
+	}
+	return types.Passkey{}, nil, types.PasskeyDoesNotExistErr
+}
+
+func (s *passkeyStore) UsePasskey(ctx context.Context, id int, oldSignCount uint32, newSignCount uint32, backedUp bool) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	passkey := &s.passkeys[id-1]
+	if passkey.SignCount != oldSignCount {
+		return false, nil
+	}
+	passkey.SignCount = newSignCount
+	passkey.BackedUp = backedUp
+	return true, nil
+}
+
+func (s *passkeyStore) signCount(id int) uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.passkeys[id-1].SignCount
+}
+
+func (s *passkeyStore) ClearLoginFailures(ctx context.Context, key string) error {
+	return nil
+}
+
+func (s *passkeyStore) CreateRefreshToken(ctx context.Context, token types.RefreshToken) (types.RefreshToken, error) {
+	return token, nil
+}
+
+func (s *passkeyStore) TouchSession(ctx context.Context, session types.Session) (types.Session, error) {
+	session.Id = 1
+	return session, nil
+}
+
+// channelMailer delivers messages to a channel, as emails are sent in the
+// background.
+type channelMailer chan mailer.Message
+
+func (m channelMailer) Send(ctx context.Context, message mailer.Message) error {
+	m <- message
+	return nil
+}
+
+type passkeyTest struct {
+	t       *testing.T
+	handler *Handler
+	store   *passkeyStore
+	mailer  channelMailer
+}
+
+func newPasskeyTest(t *testing.T) *passkeyTest {
+	t.Setenv("JWT_SECRET", "test-jwt-secret")
+	t.Setenv("JWT_ALGORITHM", "HS256")
+	t.Setenv("EMAIL_VERIFICATION_SECRET", "test-email-verification-secret")
+	t.Setenv("APP_URL", testOrigin)
+	t.Setenv("WEBAUTHN_RP_ID", "localhost")
+	t.Setenv("WEBAUTHN_RP_NAME", "JobApplicationTracker-test")
+
+	if err := service.JwtClient.InitJwtAuth(); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := newPasskeyStore()
+	mailer := make(channelMailer, 10)
+
+	return &passkeyTest{
+		t:       t,
+		handler: NewHandler(store, nil, mailer, ratelimit.NewMemoryLimiter(), config),
+		store:   store,
+		mailer:  mailer,
+	}
+}
+
+// call runs the handler with body encoded as JSON, as the test user when
+// authenticated is set.
+func (p *passkeyTest) call(handler http.HandlerFunc, body any, authenticated bool) *httptest.ResponseRecorder {
+	p.t.Helper()
+
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(encoded))
+	r.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0")
+	if authenticated {
+		r = r.WithContext(context.WithValue(r.Context(), service.UserIdKey, testUserId))
+	}
+
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func decodeData(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+
+	body := service.JsonResponse{Data: v}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding %s: %v", w.Body, err)
+	}
+}
+
+func expectError(t *testing.T, w *httptest.ResponseRecorder, status int, message string) {
+	t.Helper()
+
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d, body = %s", w.Code, status, w.Body)
+	}
+
+	var body service.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Errors) != 1 || body.Errors[0] != message {
+		t.Errorf("errors = %v, want %q", body.Errors, message)
+	}
+}
+
+func (p *passkeyTest) beginRegistration() webauthn.CreationOptions {
+	p.t.Helper()
+
+	w := p.call(p.handler.handleBeginPasskeyRegistration, nil, true)
+	if w.Code != http.StatusOK {
+		p.t.Fatalf("begin registration status = %d, body = %s", w.Code, w.Body)
+	}
+
+	var options webauthn.CreationOptions
+	decodeData(p.t, w, &options)
+	return options
+}
+
+func (p *passkeyTest) finishRegistration(credential webauthn.RegistrationCredential) *httptest.ResponseRecorder {
+	return p.call(p.handler.handleFinishPasskeyRegistration, RegisterPasskeyBody{Credential: &credential}, true)
+}
+
+// register registers a passkey on the authenticator and returns it.
+func (p *passkeyTest) register(authenticator *webauthntest.Authenticator) types.Passkey {
+	p.t.Helper()
+
+	credential, err := authenticator.Register(p.beginRegistration())
+	if err != nil {
+		p.t.Fatal(err)
+	}
+
+	w := p.finishRegistration(credential)
+	if w.Code != http.StatusCreated {
+		p.t.Fatalf("finish registration status = %d, body = %s", w.Code, w.Body)
+	}
+
+	var passkey types.Passkey
+	decodeData(p.t, w, &passkey)
+	return passkey
+}
+
+func (p *passkeyTest) beginLogin() webauthn.RequestOptions {
+	p.t.Helper()
+
+	w := p.call(p.handler.handleBeginPasskeyLogin, nil, false)
+	if w.Code != http.StatusOK {
+		p.t.Fatalf("begin login status = %d, body = %s", w.Code, w.Body)
+	}
+
+	var options webauthn.RequestOptions
+	decodeData(p.t, w, &options)
+	return options
+}
+
+func (p *passkeyTest) finishLogin(credential webauthn.AuthenticationCredential) *httptest.ResponseRecorder {
+	return p.call(p.handler.handleFinishPasskeyLogin, PasskeyLoginBody{Credential: &credential}, false)
+}
+
+func (p *passkeyTest) login(authenticator *webauthntest.Authenticator) *httptest.ResponseRecorder {
+	p.t.Helper()
+
+	credential, err := authenticator.Login(p.beginLogin())
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	return p.finishLogin(credential)
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	for name, attestation := range map[string]webauthntest.Attestation{
+		"none":  webauthntest.AttestationNone,
+		"self":  webauthntest.AttestationSelf,
+		"basic": webauthntest.AttestationBasic,
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := newPasskeyTest(t)
+			authenticator := webauthntest.NewAuthenticator(testOrigin)
+			authenticator.Attestation = attestation
+
+			passkey := p.register(authenticator)
+			if passkey.Name != "Firefox on Linux" {
+				t.Errorf("passkey name = %q, want it named after the user agent", passkey.Name)
+			}
+			select {
+			case message := <-p.mailer:
+				if message.To != p.store.user.Email {
+					t.Errorf("notification sent to %q, want the user", message.To)
+				}
+			case <-time.After(time.Second):
+				t.Error("no notification was sent")
+			}
+
+			for range 2 {
+				w := p.login(authenticator)
+				if w.Code != http.StatusOK {
+					t.Fatalf("login status = %d, body = %s", w.Code, w.Body)
+				}
+
+				var response LoginResponseData
+				decodeData(t, w, &response)
+				if response.Token == "" || response.RefreshToken == "" {
+					t.Errorf("login returned %+v", response)
+				}
+			}
+
+			if count := p.store.signCount(passkey.Id); count != authenticator.SignCount {
+				t.Errorf("stored sign count = %d, want %d", count, authenticator.SignCount)
+			}
+		})
+	}
+}
+
+func TestPasskeyRegistrationExcludesRegisteredCredentials(t *testing.T) {
+	p := newPasskeyTest(t)
+	authenticator := webauthntest.NewAuthenticator(testOrigin)
+	p.register(authenticator)
+
+	if _, err := authenticator.Register(p.beginRegistration()); err != webauthntest.ErrExcluded {
+		t.Errorf("Register error = %v, want ErrExcluded", err)
+	}
+}
+
+func TestPasskeyRegistrationReplay(t *testing.T) {
+	p := newPasskeyTest(t)
+	authenticator := webauthntest.NewAuthenticator(testOrigin)
+
+	credential, err := authenticator.Register(p.beginRegistration())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := p.finishRegistration(credential); w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+	}
+
+	expectError(t, p.finishRegistration(credential), http.StatusBadRequest, expiredCeremonyMessage)
+}
+
+func TestPasskeyBadChallenge(t *testing.T) {
+	t.Run("registration", func(t *testing.T) {
+		p := newPasskeyTest(t)
+		options := p.beginRegistration()
+		options.Challenge = webauthn.NewChallenge()
+
+		credential, err := webauthntest.NewAuthenticator(testOrigin).Register(options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectError(t, p.finishRegistration(credential), http.StatusBadRequest, expiredCeremonyMessage)
+		if len(p.store.passkeys) != 0 {
+			t.Error("passkey was stored")
+		}
+	})
+
+	t.Run("registration challenge used to log in", func(t *testing.T) {
+		p := newPasskeyTest(t)
+		authenticator := webauthntest.NewAuthenticator(testOrigin)
+		p.register(authenticator)
+
+		options := p.beginLogin()
+		options.Challenge = p.beginRegistration().Challenge
+
+		credential, err := authenticator.Login(options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectError(t, p.finishLogin(credential), http.StatusUnauthorized, expiredCeremonyMessage)
+	})
+
+	t.Run("login replay", func(t *testing.T) {
+		p := newPasskeyTest(t)
+		authenticator := webauthntest.NewAuthenticator(testOrigin)
+		p.register(authenticator)
+
+		credential, err := authenticator.Login(p.beginLogin())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w := p.finishLogin(credential); w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+		}
+
+		expectError(t, p.finishLogin(credential), http.StatusUnauthorized, expiredCeremonyMessage)
+	})
+}
+
+func TestPasskeyWrongOrigin(t *testing.T) {
+	t.Run("registration", func(t *testing.T) {
+		p := newPasskeyTest(t)
+
+		credential, err := webauthntest.NewAuthenticator("https://evil.example").Register(p.beginRegistration())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectError(t, p.finishRegistration(credential), http.StatusBadRequest, "Passkey could not be verified")
+	})
+
+	t.Run("login", func(t *testing.T) {
+		p := newPasskeyTest(t)
+		authenticator := webauthntest.NewAuthenticator(testOrigin)
+		p.register(authenticator)
+
+		authenticator.Origin = "https://evil.example"
+		expectError(t, p.login(authenticator), http.StatusUnauthorized, invalidPasskeyMessage)
+	})
+}
+
+func TestPasskeyWrongRelyingPartyId(t *testing.T) {
+	t.Run("registration", func(t *testing.T) {
+		p := newPasskeyTest(t)
+		options := p.beginRegistration()
+		options.RelyingParty.Id = "evil.example"
+
+		credential, err := webauthntest.NewAuthenticator(testOrigin).Register(options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectError(t, p.finishRegistration(credential), http.StatusBadRequest, "Passkey could not be verified")
+	})
+
+	t.Run("login", func(t *testing.T) {
+		p := newPasskeyTest(t)
+		authenticator := webauthntest.NewAuthenticator(testOrigin)
+		p.register(authenticator)
+
+		// The same passkey used with a relying party that moved to another
+		// domain, which has to be rejected.
+		p.handler.config.WebAuthn.Id = "accounts.localhost"
+		options := p.beginLogin()
+		options.RpId = "localhost"
+
+		credential, err := authenticator.Login(options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectError(t, p.finishLogin(credential), http.StatusUnauthorized, invalidPasskeyMessage)
+	})
+}
+
+func TestPasskeySignCountRegression(t *testing.T) {
+	p := newPasskeyTest(t)
+	authenticator := webauthntest.NewAuthenticator(testOrigin)
+	passkey := p.register(authenticator)
+
+	for range 3 {
+		if w := p.login(authenticator); w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+		}
+	}
+	stored := p.store.signCount(passkey.Id)
+
+	// A clone of the authenticator, copied before the last logins.
+	authenticator.SignCount = 1
+	expectError(t, p.login(authenticator), http.StatusUnauthorized, invalidPasskeyMessage)
+
+	if count := p.store.signCount(passkey.Id); count != stored {
+		t.Errorf("stored sign count = %d, want it unchanged at %d", count, stored)
+	}
+}
+
+func TestPasskeyWithoutCounter(t *testing.T) {
+	p := newPasskeyTest(t)
+	authenticator := webauthntest.NewAuthenticator(testOrigin)
+	authenticator.DisableCounter = true
+	p.register(authenticator)
+
+	for range 2 {
+		if w := p.login(authenticator); w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+		}
+	}
+}
+
+func TestPasskeyUnknownCredential(t *testing.T) {
+	p := newPasskeyTest(t)
+	p.register(webauthntest.NewAuthenticator(testOrigin))
+
+	// The credential is created, but its registration is never finished.
+	unregistered := webauthntest.NewAuthenticator(testOrigin)
+	if _, err := unregistered.Register(p.beginRegistration()); err != nil {
+		t.Fatal(err)
+	}
+
+	expectError(t, p.login(unregistered), http.StatusUnauthorized, invalidPasskeyMessage)
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// appWords make passwords of this app easy to guess, like the user's name
+// and email do.
+var appWords = []string{"job", "jobs", "application", "applications", "tracker", "resume", "career"}
+
+// passwordFieldErrors checks a new password of the user against the password
+// policy.
+func (h *Handler) passwordFieldErrors(password string, user types.CommonUser) ([]types.FieldError, error) {
+	userInputs := append([]string{user.FirstName, user.LastName, user.Email}, appWords...)
+
+	problems, err := h.config.PasswordPolicy.Check(password, userInputs)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldErrors := make([]types.FieldError, 0, len(problems))
+	for _, problem := range problems {
+		fieldErrors = append(fieldErrors, types.FieldError{
+			In:      "body",
+			Pointer: "/password",
+			Message: problem,
+		})
+	}
+
+	return fieldErrors, nil
+}
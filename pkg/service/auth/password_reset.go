@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/mailer"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+const forgotPasswordMessage = "If an account with this email exists, a password reset link was sent to it"
+
+// handleForgotPassword emails a password reset link. The response is the
+// same whether or not the email belongs to an account.
+func (h *Handler) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var body ForgotPasswordBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := body.IsValid(); err != nil {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.store.GetInternalUserByEmail(r.Context(), *body.Email)
+	if err != nil && !errors.Is(err, types.UserDoesNotExistErr) {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if err == nil {
+		token, tokenHash := newOpaqueToken()
+		err = h.store.CreatePasswordResetToken(r.Context(), types.PasswordResetToken{
+			UserId:    user.Id,
+			TokenHash: tokenHash,
+			ExpiresAt: time.Now().Add(h.config.PasswordResetTTL),
+		})
+		if err != nil {
+			service.SendInternalServerError(w, r, err)
+			return
+		}
+
+		link := fmt.Sprintf("%s/reset-password?token=%s", h.config.AppUrl, url.QueryEscape(token))
+		h.sendEmail(r.Context(), mailer.Message{
+			To:      user.Email,
+			Subject: "Reset your password",
+			Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s.\n\n%s\n\n"+
+				"If you did not ask for a password reset, you can ignore this email.\n",
+				user.FirstName, h.config.PasswordResetTTL, link),
+		})
+	}
+
+	service.SendJsonResponse(w, MessageResponseData{Message: forgotPasswordMessage}, http.StatusOK)
+}
+
+// handleResetPassword sets a new password with a reset token and signs the
+// user out everywhere.
+func (h *Handler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var body ResetPasswordBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := body.IsValid(); err != nil {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.store.GetPasswordResetUser(r.Context(), hashToken(*body.Token))
+	if errors.Is(err, types.InvalidOneTimeTokenErr) {
+		service.SendErrorsResponse(w, []string{"Password reset token is not valid or has expired"}, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	fieldErrors, err := h.passwordFieldErrors(*body.Password, user.CommonUser)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+	if len(fieldErrors) > 0 {
+		service.SendFieldErrorsResponse(w, fieldErrors)
+		return
+	}
+
+	hash, err := h.hashPassword(r.Context(), *body.Password)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	userId, err := h.store.ResetPassword(r.Context(), hashToken(*body.Token), hash)
+	if errors.Is(err, types.InvalidOneTimeTokenErr) {
+		service.SendErrorsResponse(w, []string{"Password reset token is not valid or has expired"}, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if err := h.revokeAllTokens(r, userId); err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	service.SendJsonResponse(w, MessageResponseData{Message: "Password was reset"}, http.StatusOK)
+}
+
+// sendEmail sends the message in the background so that response times do
+// not reveal whether an email was sent.
+func (h *Handler) sendEmail(ctx context.Context, message mailer.Message) {
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		if err := h.mailer.Send(ctx, message); err != nil {
+			slog.ErrorContext(ctx, "could not send email", "subject", message.Subject, "error", err)
+		}
+	}()
+}
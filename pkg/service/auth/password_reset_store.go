@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// CreatePasswordResetToken stores a new token, invalidating every token the
+// user requested before it.
+func (s *Store) CreatePasswordResetToken(ctx context.Context, token types.PasswordResetToken) (err error) {
+	query := `
+        INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+        VALUES ($1, $2, $3)`
+
+	ctx, span := db.StartQuerySpan(ctx, "password_reset_tokens.insert", query)
+	defer func() { tracing.End(span, err) }()
+
+	transaction, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer transaction.Rollback()
+
+	_, err = transaction.ExecContext(ctx, `
+        UPDATE password_reset_tokens SET used_at = now()
+        WHERE user_id = $1 AND used_at IS NULL`,
+		token.UserId,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = transaction.ExecContext(ctx, query, token.UserId, token.TokenHash, token.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return transaction.Commit()
+}
+
+// GetPasswordResetUser returns the user a reset token was issued to, without
+// spending it. InvalidOneTimeTokenErr is returned for unknown, spent and
+// expired tokens.
+func (s *Store) GetPasswordResetUser(ctx context.Context, tokenHash string) (types.InternalUser, error) {
+	user, err := s.getInternalUser(ctx, `
+        WHERE users.id = (
+            SELECT user_id FROM password_reset_tokens
+            WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+        )`,
+		tokenHash,
+	)
+	if errors.Is(err, types.UserDoesNotExistErr) {
+		return types.InternalUser{}, types.InvalidOneTimeTokenErr
+	}
+
+	return user, err
+}
+
+// ResetPassword spends the reset token and replaces the password of its user
+// in one transaction, returning the user's id. InvalidOneTimeTokenErr is
+// returned for unknown, spent and expired tokens.
+func (s *Store) ResetPassword(ctx context.Context, tokenHash string, passwordHash string) (userId int, err error) {
+	query := `
+        UPDATE password_reset_tokens SET used_at = now()
+        WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+        RETURNING user_id`
+
+	ctx, span := db.StartQuerySpan(ctx, "password_reset_tokens.consume", query)
+	defer func() { tracing.End(span, err) }()
+
+	transaction, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer transaction.Rollback()
+
+	err = transaction.QueryRowContext(ctx, query, tokenHash).Scan(&userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, types.InvalidOneTimeTokenErr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = transaction.ExecContext(ctx, `
+        UPDATE users SET password_hash = $2, updated_at = DEFAULT
+        WHERE id = $1`,
+		userId, passwordHash,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return userId, nil
+}
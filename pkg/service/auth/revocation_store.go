@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+func (s *Store) IsTokenRevoked(ctx context.Context, jti string) (revoked bool, err error) {
+	query := `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)`
+
+	ctx, span := db.StartQuerySpan(ctx, "revoked_tokens.select", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = s.db.QueryRowContext(ctx, query, jti).Scan(&revoked)
+	return revoked, err
+}
+
+// RevokeToken stores the jti until the token expires. Rows of tokens that
+// have expired in the meantime are no longer needed and are removed.
+func (s *Store) RevokeToken(ctx context.Context, jti string, userId int, expiresAt time.Time) (err error) {
+	query := `
+        INSERT INTO revoked_tokens (jti, user_id, expires_at)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (jti) DO NOTHING`
+
+	ctx, span := db.StartQuerySpan(ctx, "revoked_tokens.insert", query)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.db.ExecContext(ctx, query, jti, userId, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < now()`)
+	return err
+}
+
+// GetTokensValidAfter returns UserDisabledErr for users disabled by an
+// admin, whose tokens are all rejected.
+func (s *Store) GetTokensValidAfter(ctx context.Context, userId int) (validAfter time.Time, err error) {
+	query := `SELECT tokens_valid_after, disabled_at FROM users WHERE id = $1`
+
+	ctx, span := db.StartQuerySpan(ctx, "users.select_tokens_valid_after", query)
+	defer func() { tracing.End(span, err) }()
+
+	var value, disabledAt sql.NullTime
+	err = s.db.QueryRowContext(ctx, query, userId).Scan(&value, &disabledAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, types.UserDoesNotExistErr
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	if disabledAt.Valid {
+		return time.Time{}, types.UserDisabledErr
+	}
+
+	return value.Time, nil
+}
+
+func (s *Store) SetTokensValidAfter(ctx context.Context, userId int, validAfter time.Time) (err error) {
+	query := `UPDATE users SET tokens_valid_after = $2, updated_at = DEFAULT WHERE id = $1`
+
+	ctx, span := db.StartQuerySpan(ctx, "users.update_tokens_valid_after", query)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.db.ExecContext(ctx, query, userId, validAfter)
+	return err
+}
@@ -1,48 +1,132 @@
 package auth
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"log/slog"
 	"net/http"
+	"sync"
 
 	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/mailer"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/metrics"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/ratelimit"
 	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
 	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
 	"github.com/go-chi/chi/v5"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type Handler struct {
-	store db.AuthStore
+	store       db.AuthStore
+	revocations *service.RevocationCache
+	mailer      mailer.Mailer
+	limiter     ratelimit.Limiter
+	config      Config
+	providers   map[string]OAuthProvider
+
+	// dummyPasswordHash is compared against when no user has the email, so
+	// logins take as long whether or not the account exists.
+	dummyPasswordHash func() (string, error)
 }
 
-func NewHandler(store db.AuthStore) *Handler {
-	return &Handler{store: store}
+func NewHandler(store db.AuthStore, revocations *service.RevocationCache, mailer mailer.Mailer, limiter ratelimit.Limiter, config Config) *Handler {
+	providers := make(map[string]OAuthProvider, len(config.OAuthProviders))
+	for _, provider := range config.OAuthProviders {
+		providers[provider.Name] = newOAuthProvider(provider)
+	}
+
+	return &Handler{
+		store:       store,
+		revocations: revocations,
+		mailer:      mailer,
+		limiter:     limiter,
+		config:      config,
+		providers:   providers,
+		dummyPasswordHash: sync.OnceValues(func() (string, error) {
+			return config.PasswordHasher.Hash("dummy password")
+		}),
+	}
 }
 
+// AddRoutes adds the routes used before signing in.
 func (h *Handler) AddRoutes(r chi.Router) {
-	r.Route("/auth", func(r chi.Router) {
-		r.Post("/register", h.handleRegister)
-		r.Post("/login", h.handleLogin)
-		r.Post("/check", h.handleAuthCheck)
-	})
+	r.Post("/auth/register", h.handleRegister)
+	r.Post("/auth/login", h.handleLogin)
+	r.Post("/auth/login/2fa", h.handleTwoFactorLogin)
+	r.Post("/auth/check", h.handleAuthCheck)
+	r.Post("/auth/refresh", h.handleRefresh)
+	r.Post("/auth/password/forgot", h.handleForgotPassword)
+	r.Post("/auth/password/reset", h.handleResetPassword)
+	r.Post("/auth/magic-link", h.handleRequestMagicLink)
+	r.Get("/auth/magic-link/verify", h.handleVerifyMagicLink)
+	r.Post("/auth/passkeys/login/begin", h.handleBeginPasskeyLogin)
+	r.Post("/auth/passkeys/login/finish", h.handleFinishPasskeyLogin)
+	r.Post("/auth/email/verify", h.handleVerifyEmail)
+	r.Post("/auth/email/change/confirm", h.handleConfirmEmailChange)
+	r.Get("/auth/oauth/providers", h.handleListOAuthProviders)
+	r.Post("/auth/oauth/{provider}/authorize", h.handleOAuthAuthorize)
+	r.Post("/auth/oauth/{provider}/callback", h.handleOAuthCallback)
+}
+
+// AddAccountRoutes adds the routes of the signed in user. They have to be
+// behind middleware.JwtAuthenticator.
+func (h *Handler) AddAccountRoutes(r chi.Router) {
+	r.Post("/auth/logout", h.handleLogout)
+	r.Post("/auth/logout-all", h.handleLogoutAll)
+	r.Post("/auth/email/resend", h.handleResendVerification)
+	r.Post("/auth/2fa/setup", h.handleTotpSetup)
+	r.Post("/auth/2fa/enable", h.handleTotpEnable)
+	r.Post("/auth/2fa/disable", h.handleTotpDisable)
+	r.Post("/auth/2fa/recovery-codes", h.handleRegenerateRecoveryCodes)
+	r.Get("/auth/identities", h.handleListIdentities)
+	r.Delete("/auth/identities/{identityId}", h.handleDeleteIdentity)
+
+	r.Get("/me", h.handleMe)
+	r.Patch("/me", h.handleUpdateMe)
+	r.Delete("/me", h.handleDeleteMe)
+	r.Post("/me/password", h.handleChangePassword)
+	r.Post("/me/email", h.handleChangeEmail)
+	r.Get("/me/sessions", h.handleListSessions)
+	r.Delete("/me/sessions/{sessionId}", h.handleRevokeSession)
+	r.Get("/me/passkeys", h.handleListPasskeys)
+	r.Post("/me/passkeys/register/begin", h.handleBeginPasskeyRegistration)
+	r.Post("/me/passkeys/register/finish", h.handleFinishPasskeyRegistration)
+	r.Delete("/me/passkeys/{passkeyId}", h.handleDeletePasskey)
 }
 
 func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
-	decoder := json.NewDecoder(r.Body)
 	var body RegisterBody
-	decoder.Decode(&body)
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
 
 	if err := body.IsValid(); err != nil {
 		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
 		return
 	}
 
+	fieldErrors, err := h.passwordFieldErrors(*body.Password, types.CommonUser{
+		FirstName: *body.FirstName,
+		LastName:  *body.LastName,
+		Email:     *body.Email,
+	})
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+	if len(fieldErrors) > 0 {
+		service.SendFieldErrorsResponse(w, fieldErrors)
+		return
+	}
+
 	currentErrors := make([]string, 0)
 
-	existingUser, err := h.store.GetInternalUserByEmail(*body.Email)
+	existingUser, err := h.store.GetInternalUserByEmail(r.Context(), *body.Email)
 	if err != nil && !errors.Is(err, types.UserDoesNotExistErr) {
-		service.SendInternalServerError(w)
+		service.SendInternalServerError(w, r, err)
 		return
 	}
 
@@ -55,77 +139,121 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hash, err := hashPassword(*body.Password)
+	hash, err := h.hashPassword(r.Context(), *body.Password)
 	if err != nil {
-		service.SendInternalServerError(w)
+		service.SendInternalServerError(w, r, err)
 		return
 	}
 
-	user, err := h.store.CreateUser(body.CreateInternalUser(hash))
+	user, err := h.store.CreateUser(r.Context(), body.CreateInternalUser(hash))
 	if err != nil {
-		service.SendInternalServerError(w)
+		service.SendInternalServerError(w, r, err)
 		return
 	}
 
-	token, err := service.JwtClient.CreateToken(user.Id)
-	if err != nil {
-		service.SendInternalServerError(w)
+	if _, err := h.sendVerificationEmail(r.Context(), user.User); err != nil {
+		service.SendInternalServerError(w, r, err)
 		return
 	}
 
-	type newUserResponseData struct {
-		User  types.User `json:"user"`
-		Token string     `json:"auth_token"`
+	tokens, err := h.issueTokens(r, user.User)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
 	}
 
-	data := newUserResponseData{
-		User:  user.User,
-		Token: token,
+	data := RegisterResponseData{
+		User:              user.User,
+		TokenResponseData: tokens,
 	}
 
 	service.SendJsonResponse(w, data, http.StatusOK)
 }
 
 func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
-	decoder := json.NewDecoder(r.Body)
 	var body LoginBody
-	decoder.Decode(&body)
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
 
 	if err := body.IsValid(); err != nil {
+		metrics.LoginFailed("invalid_body")
 		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
 		return
 	}
 
-	existingUser, err := h.store.GetInternalUserByEmail(*body.Email)
+	if h.rejectThrottled(w, r, *body.Email) {
+		return
+	}
+
+	// Unknown emails and wrong passwords get the same response after the same
+	// work, so it does not tell which emails have an account.
+	existingUser, err := h.store.GetInternalUserByEmail(r.Context(), *body.Email)
 	if errors.Is(err, types.UserDoesNotExistErr) {
-		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusMethodNotAllowed)
+		dummyHash, err := h.dummyPasswordHash()
+		if err != nil {
+			service.SendInternalServerError(w, r, err)
+			return
+		}
+		h.comparePassword(r.Context(), dummyHash, *body.Password)
+
+		if err := h.recordLoginFailure(r, *body.Email, nil); err != nil {
+			service.SendInternalServerError(w, r, err)
+			return
+		}
+
+		metrics.LoginFailed("unknown_user")
+		service.SendErrorsResponse(w, []string{invalidCredentialsMessage}, http.StatusUnauthorized)
 		return
 	} else if err != nil {
-		service.SendInternalServerError(w)
+		service.SendInternalServerError(w, r, err)
 		return
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(existingUser.PasswordHash), []byte(*body.Password))
-	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-		service.SendErrorsResponse(w, []string{"Passwords do not match"}, http.StatusBadRequest)
+	needsRehash, err := h.comparePassword(r.Context(), existingUser.PasswordHash, *body.Password)
+	if errors.Is(err, types.PasswordMismatchErr) {
+		if err := h.recordLoginFailure(r, *body.Email, &existingUser); err != nil {
+			service.SendInternalServerError(w, r, err)
+			return
+		}
+
+		metrics.LoginFailed("invalid_password")
+		service.SendErrorsResponse(w, []string{invalidCredentialsMessage}, http.StatusUnauthorized)
 		return
 	} else if err != nil {
-		service.SendInternalServerError(w)
+		service.SendInternalServerError(w, r, err)
 		return
 	}
 
-	token, err := service.JwtClient.CreateToken(existingUser.Id)
-	if err != nil {
-		service.SendInternalServerError(w)
+	if needsRehash {
+		h.rehashPassword(r.Context(), existingUser, *body.Password)
+	}
+
+	if rejectDisabled(w, existingUser) {
 		return
 	}
 
-	type loginResponseData struct {
-		Token string `json:"auth_token"`
+	if existingUser.TwoFactorEnabled() {
+		h.startTwoFactorChallenge(w, r, existingUser)
+		return
+	}
+
+	if err := h.clearLoginFailures(r.Context(), existingUser.Email); err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	tokens, err := h.issueTokens(r, existingUser.User)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
 	}
 
-	data := loginResponseData{
-		Token: token,
+	metrics.LoginSucceeded()
+
+	data := LoginResponseData{
+		TokenResponseData: tokens,
 	}
 
 	service.SendJsonResponse(w, data, http.StatusOK)
@@ -141,11 +269,54 @@ func (h *Handler) handleAuthCheck(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-func hashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// rejectDisabled responds with 403 for users disabled by an admin and
+// reports whether it did.
+func rejectDisabled(w http.ResponseWriter, user types.InternalUser) bool {
+	if user.DisabledAt == nil {
+		return false
+	}
+
+	metrics.LoginFailed("disabled")
+	service.SendErrorsResponse(w, []string{"Account is disabled"}, http.StatusForbidden)
+	return true
+}
+
+func (h *Handler) hashPassword(ctx context.Context, password string) (string, error) {
+	_, span := tracing.Start(ctx, "password.hash")
+	hash, err := h.config.PasswordHasher.Hash(password)
+	tracing.End(span, err)
 	if err != nil {
 		return "", err
 	}
 
-	return string(hash), nil
+	return hash, nil
+}
+
+// comparePassword returns types.PasswordMismatchErr when the password does
+// not match. needsRehash reports whether the hash uses an outdated algorithm
+// or parameters.
+func (h *Handler) comparePassword(ctx context.Context, hash string, password string) (needsRehash bool, err error) {
+	_, span := tracing.Start(ctx, "password.compare")
+	defer func() {
+		if errors.Is(err, types.PasswordMismatchErr) {
+			tracing.End(span, nil)
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	return h.config.PasswordHasher.Verify(hash, password)
+}
+
+// rehashPassword replaces an outdated hash of the user's password, which was
+// just verified. The login goes ahead when it fails, the next one tries
+// again.
+func (h *Handler) rehashPassword(ctx context.Context, user types.InternalUser, password string) {
+	hash, err := h.hashPassword(ctx, password)
+	if err == nil {
+		err = h.store.RehashPassword(ctx, user.Id, user.PasswordHash, hash)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "could not rehash password", "user_id", user.Id, "error", err)
+	}
 }
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+const sessionFields = `id, user_id, family_id, user_agent, ip_address, created_at, last_seen_at, expires_at, revoked_at`
+
+func scanSessionRow(row db.Scannable) (types.Session, error) {
+	var session types.Session
+	err := row.Scan(
+		&session.Id,
+		&session.UserId,
+		&session.FamilyId,
+		&session.UserAgent,
+		&session.IpAddress,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+	)
+
+	return session, err
+}
+
+// TouchSession creates the session of the refresh token family on its first
+// use and afterwards updates where and when it was last seen. Sessions that
+// expired are removed.
+func (s *Store) TouchSession(ctx context.Context, session types.Session) (touched types.Session, err error) {
+	query := `
+        INSERT INTO sessions (user_id, family_id, user_agent, ip_address, expires_at)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (family_id) DO UPDATE SET
+            user_agent = excluded.user_agent,
+            ip_address = excluded.ip_address,
+            last_seen_at = now(),
+            expires_at = excluded.expires_at
+        RETURNING ` + sessionFields
+
+	ctx, span := db.StartQuerySpan(ctx, "sessions.upsert", query)
+	defer func() { tracing.End(span, err) }()
+
+	touched, err = scanSessionRow(s.db.QueryRowContext(ctx, query,
+		session.UserId, session.FamilyId, session.UserAgent, session.IpAddress, session.ExpiresAt,
+	))
+	if err != nil {
+		return types.Session{}, err
+	}
+
+	_, err = s.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at < now()`)
+	return touched, err
+}
+
+// GetUserSessions returns the sessions that were neither revoked nor expired,
+// most recently seen first.
+func (s *Store) GetUserSessions(ctx context.Context, userId int) (sessions []types.Session, err error) {
+	query := `
+        SELECT ` + sessionFields + ` FROM sessions
+        WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+        ORDER BY last_seen_at DESC`
+
+	ctx, span := db.StartQuerySpan(ctx, "sessions.select_many", query)
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions = make([]types.Session, 0)
+	for rows.Next() {
+		session, err := scanSessionRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// RevokeSession revokes the session and its refresh token family and reports
+// whether the user had such a session.
+func (s *Store) RevokeSession(ctx context.Context, userId int, id int) (revoked bool, err error) {
+	query := `
+        UPDATE sessions SET revoked_at = now()
+        WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+        RETURNING family_id`
+
+	ctx, span := db.StartQuerySpan(ctx, "sessions.revoke", query)
+	defer func() { tracing.End(span, err) }()
+
+	transaction, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer transaction.Rollback()
+
+	var familyId string
+	err = transaction.QueryRowContext(ctx, query, id, userId).Scan(&familyId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, err = transaction.ExecContext(ctx, `
+        UPDATE refresh_tokens SET revoked_at = now()
+        WHERE family_id = $1 AND revoked_at IS NULL`,
+		familyId,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, transaction.Commit()
+}
+
+// IsSessionRevoked also treats sessions that no longer exist as revoked.
+func (s *Store) IsSessionRevoked(ctx context.Context, id int) (revoked bool, err error) {
+	query := `SELECT NOT EXISTS (SELECT 1 FROM sessions WHERE id = $1 AND revoked_at IS NULL)`
+
+	ctx, span := db.StartQuerySpan(ctx, "sessions.select_revoked", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = s.db.QueryRowContext(ctx, query, id).Scan(&revoked)
+	return revoked, err
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxUserAgentLength caps the user agent stored with a session.
+const maxUserAgentLength = 512
+
+func (h *Handler) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.store.GetUserSessions(r.Context(), service.UserIdFromContext(r.Context()))
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	claims := service.ClaimsFromContext(r.Context())
+	for i := range sessions {
+		sessions[i].Device = describeDevice(sessions[i].UserAgent)
+		sessions[i].Current = claims != nil && sessions[i].Id == claims.SessionId
+	}
+
+	service.SendJsonResponse(w, sessions, http.StatusOK)
+}
+
+// handleRevokeSession signs the session out. Its refresh token stops working
+// right away and its access tokens once the revocation cache of every
+// replica picked it up.
+func (h *Handler) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	sessionId, err := strconv.Atoi(chi.URLParam(r, "sessionId"))
+	if err != nil {
+		service.SendErrorsResponse(w, []string{"Provided url param was not parsable"}, http.StatusBadRequest)
+		return
+	}
+
+	revoked, err := h.store.RevokeSession(r.Context(), service.UserIdFromContext(r.Context()), sessionId)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if !revoked {
+		service.SendErrorsResponse(w, []string{"Session does not exist"}, http.StatusNotFound)
+		return
+	}
+
+	h.revocations.InvalidateSession(sessionId)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// describeDevice names the browser and operating system of a user agent,
+// e.g. "Firefox on Linux", for people to recognize their sessions by.
+func describeDevice(userAgent string) string {
+	var browser string
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(userAgent, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	}
+
+	var system string
+	switch {
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"):
+		system = "iOS"
+	case strings.Contains(userAgent, "Android"):
+		system = "Android"
+	case strings.Contains(userAgent, "Windows"):
+		system = "Windows"
+	case strings.Contains(userAgent, "Mac OS X"):
+		system = "macOS"
+	case strings.Contains(userAgent, "Linux"):
+		system = "Linux"
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	case userAgent != "":
+		// Non browser clients, like the Go client, usually name themselves
+		// first.
+		name, _, _ := strings.Cut(userAgent, " ")
+		return name
+	default:
+		return "Unknown device"
+	}
+}
@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/ratelimit"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestDescribeDevice(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		device    string
+	}{
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0", "Firefox on Linux"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", "Chrome on Windows"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.61", "Edge on Windows"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 OPR/106.0.0.0", "Opera on macOS"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15", "Safari on macOS"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1", "Safari on iOS"},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36", "Chrome on Android"},
+		{"Mozilla/5.0 (Android 14; Mobile; rv:120.0) Gecko/120.0 Firefox/120.0", "Firefox on Android"},
+		{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/120.0.0.0 Safari/537.36", "Chrome on Linux"},
+		{"Mozilla/5.0 (compatible; MSIE 10.0; Windows NT 6.2)", "Windows"},
+		{"Go-http-client/1.1", "Go-http-client/1.1"},
+		{"curl/8.4.0", "curl/8.4.0"},
+		{"", "Unknown device"},
+	}
+
+	for _, test := range tests {
+		if device := describeDevice(test.userAgent); device != test.device {
+			t.Errorf("describeDevice(%q) = %q, want %q", test.userAgent, device, test.device)
+		}
+	}
+}
+
+const (
+	sessionRefreshToken = "session refresh token"
+	otherUserId         = 8
+)
+
+// sessionStore keeps sessions and their refresh tokens in memory, and is the
+// revocation store of the access tokens too. Other methods of db.AuthStore
+// are not implemented.
+type sessionStore struct {
+	db.AuthStore
+
+	mu            sync.Mutex
+	sessions      []types.Session
+	refreshTokens []types.RefreshToken
+}
+
+func (s *sessionStore) RevokeSession(ctx context.Context, userId int, id int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for i, session := range s.sessions {
+		if session.Id != id || session.UserId != userId || session.RevokedAt != nil {
+			continue
+		}
+
+		s.sessions[i].RevokedAt = &now
+		for j, token := range s.refreshTokens {
+			if token.FamilyId == session.FamilyId && token.RevokedAt == nil {
+				s.refreshTokens[j].RevokedAt = &now
+			}
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (s *sessionStore) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (types.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.refreshTokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return types.RefreshToken{}, types.RefreshTokenDoesNotExistErr
+}
+
+func (s *sessionStore) RevokeRefreshTokenFamily(ctx context.Context, familyId string) error {
+	return nil
+}
+
+func (s *sessionStore) IsSessionRevoked(ctx context.Context, id int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, session := range s.sessions {
+		if session.Id == id {
+			return session.RevokedAt != nil, nil
+		}
+	}
+	return true, nil
+}
+
+func (s *sessionStore) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return false, nil
+}
+
+func (s *sessionStore) RevokeToken(ctx context.Context, jti string, userId int, expiresAt time.Time) error {
+	return nil
+}
+
+func (s *sessionStore) GetTokensValidAfter(ctx context.Context, userId int) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func (s *sessionStore) SetTokensValidAfter(ctx context.Context, userId int, validAfter time.Time) error {
+	return nil
+}
+
+// add stores a session of the user with id, and a refresh token of its
+// family.
+func (s *sessionStore) add(id int, userId int, refreshToken string) {
+	familyId := "family " + strconv.Itoa(id)
+	expiresAt := time.Now().Add(time.Hour)
+
+	session := types.Session{UserId: userId, FamilyId: familyId, ExpiresAt: expiresAt}
+	session.Id = id
+	s.sessions = append(s.sessions, session)
+
+	token := types.RefreshToken{UserId: userId, FamilyId: familyId, TokenHash: hashToken(refreshToken), ExpiresAt: expiresAt}
+	token.Id = id
+	s.refreshTokens = append(s.refreshTokens, token)
+}
+
+type sessionTest struct {
+	t       *testing.T
+	handler *Handler
+	store   *sessionStore
+}
+
+// newSessionTest stores session 1 of the test user and session 2 of another
+// user, each with a refresh token. Access tokens are checked against the
+// store, with a cache that would keep sessions found valid for an hour.
+func newSessionTest(t *testing.T) *sessionTest {
+	t.Setenv("JWT_SECRET", "test-jwt-secret")
+	t.Setenv("JWT_ALGORITHM", "HS256")
+	t.Setenv("EMAIL_VERIFICATION_SECRET", "test-email-verification-secret")
+	t.Setenv("APP_URL", testOrigin)
+	t.Setenv("WEBAUTHN_RP_ID", "localhost")
+	t.Setenv("WEBAUTHN_RP_NAME", "JobApplicationTracker-test")
+
+	if err := service.JwtClient.InitJwtAuth(); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := &sessionStore{}
+	store.add(1, testUserId, sessionRefreshToken)
+	store.add(2, otherUserId, "other refresh token")
+
+	revocations := service.NewRevocationCache(store, time.Hour)
+	service.JwtClient.SetRevocations(revocations)
+	t.Cleanup(func() { service.JwtClient.SetRevocations(nil) })
+
+	return &sessionTest{
+		t:       t,
+		handler: NewHandler(store, revocations, make(channelMailer, 10), ratelimit.NewMemoryLimiter(), config),
+		store:   store,
+	}
+}
+
+func (s *sessionTest) revoke(sessionId string) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("sessionId", sessionId)
+
+	r := httptest.NewRequest(http.MethodDelete, "/", nil)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, routeContext)
+	r = r.WithContext(context.WithValue(ctx, service.UserIdKey, testUserId))
+
+	w := httptest.NewRecorder()
+	s.handler.handleRevokeSession(w, r)
+	return w
+}
+
+// parseToken checks the access token of the session as the JWT
+// authenticator does.
+func (s *sessionTest) parseToken(token string) error {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	_, err := service.JwtClient.ParseToken(r)
+	return err
+}
+
+func (s *sessionTest) refresh(refreshToken string) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	encoded, err := json.Marshal(map[string]string{"refresh_token": refreshToken})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.handler.handleRefresh(w, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(encoded)))
+	return w
+}
+
+func TestRevokeSessionRejectsAccessTokens(t *testing.T) {
+	s := newSessionTest(t)
+
+	user := types.User{}
+	user.Id = testUserId
+	token, err := service.JwtClient.CreateToken(context.Background(), user, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Caches the session as valid.
+	if err := s.parseToken(token); err != nil {
+		t.Fatalf("token of the active session: %v", err)
+	}
+
+	if w := s.revoke("1"); w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200, body = %s", w.Code, w.Body)
+	}
+
+	if err := s.parseToken(token); !errors.Is(err, types.TokenRevokedErr) {
+		t.Errorf("token of the revoked session: %v, want %v", err, types.TokenRevokedErr)
+	}
+}
+
+func TestRevokeSessionOfAnotherUser(t *testing.T) {
+	s := newSessionTest(t)
+
+	expectError(t, s.revoke("2"), http.StatusNotFound, "Session does not exist")
+
+	if s.store.sessions[1].RevokedAt != nil || s.store.refreshTokens[1].RevokedAt != nil {
+		t.Error("the session of the other user was revoked")
+	}
+}
+
+func TestRevokeSessionTwice(t *testing.T) {
+	s := newSessionTest(t)
+
+	if w := s.revoke("1"); w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200, body = %s", w.Code, w.Body)
+	}
+	expectError(t, s.revoke("1"), http.StatusNotFound, "Session does not exist")
+}
+
+func TestRevokeSessionBadId(t *testing.T) {
+	s := newSessionTest(t)
+
+	expectError(t, s.revoke("current"), http.StatusBadRequest, "Provided url param was not parsable")
+}
+
+func TestRefreshRevokedSession(t *testing.T) {
+	s := newSessionTest(t)
+
+	if w := s.revoke("1"); w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200, body = %s", w.Code, w.Body)
+	}
+
+	expectError(t, s.refresh(sessionRefreshToken), http.StatusUnauthorized, "Refresh token is not valid")
+}
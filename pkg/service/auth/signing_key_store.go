@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// GetSigningKeys returns the keys that can still verify tokens, oldest
+// activation first.
+func (s *Store) GetSigningKeys(ctx context.Context) (keys []types.SigningKey, err error) {
+	query := `
+        SELECT kid, algorithm, private_key, public_key, created_at, activates_at, retires_at, expires_at
+        FROM signing_keys WHERE expires_at > now()
+        ORDER BY activates_at`
+
+	ctx, span := db.StartQuerySpan(ctx, "signing_keys.select_many", query)
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys = make([]types.SigningKey, 0)
+	for rows.Next() {
+		var key types.SigningKey
+		err = rows.Scan(&key.Kid, &key.Algorithm, &key.PrivateKey, &key.PublicKey,
+			&key.CreatedAt, &key.ActivatesAt, &key.RetiresAt, &key.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// CreateSigningKey reports false when another replica already created a key
+// activating at the same time. Expired keys are removed.
+func (s *Store) CreateSigningKey(ctx context.Context, key types.SigningKey) (created bool, err error) {
+	query := `
+        INSERT INTO signing_keys (kid, algorithm, private_key, public_key, activates_at, retires_at, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        ON CONFLICT (activates_at) DO NOTHING`
+
+	ctx, span := db.StartQuerySpan(ctx, "signing_keys.insert", query)
+	defer func() { tracing.End(span, err) }()
+
+	result, err := s.db.ExecContext(ctx, query, key.Kid, key.Algorithm, key.PrivateKey, key.PublicKey,
+		key.ActivatesAt, key.RetiresAt, key.ExpiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = s.db.ExecContext(ctx, `DELETE FROM signing_keys WHERE expires_at < now()`)
+	return rows > 0, err
+}
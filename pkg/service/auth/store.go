@@ -1,10 +1,12 @@
 package auth
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 
 	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
 	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
 )
 
@@ -16,32 +18,37 @@ func NewStore(connection *db.DbConnection) *Store {
 	return &Store{db: connection.DB}
 }
 
-func (s *Store) GetInternalUserById(id int) (types.InternalUser, error) {
-	return s.getInternalUser("WHERE users.id = $1", id)
+func (s *Store) GetInternalUserById(ctx context.Context, id int) (types.InternalUser, error) {
+	return s.getInternalUser(ctx, "WHERE users.id = $1", id)
 }
 
-func (s *Store) GetInternalUserByEmail(email string) (types.InternalUser, error) {
-	return s.getInternalUser("WHERE users.email = $1", email)
+func (s *Store) GetInternalUserByEmail(ctx context.Context, email string) (types.InternalUser, error) {
+	return s.getInternalUser(ctx, "WHERE users.email = $1", email)
 }
 
-func (s *Store) CreateUser(user types.InternalUser) (types.InternalUser, error) {
-	transaction, err := s.db.Begin()
+func (s *Store) CreateUser(ctx context.Context, user types.InternalUser) (newUser types.InternalUser, err error) {
+	query := `
+        INSERT INTO users (first_name, last_name, email, password_hash)
+        VALUES ($1, $2, $3, $4)
+        RETURNING ` + userFields
+
+	ctx, span := db.StartQuerySpan(ctx, "users.insert", query)
+	defer func() { tracing.End(span, err) }()
+
+	transaction, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return types.InternalUser{}, err
 	}
 	defer transaction.Rollback()
 
-	row := transaction.QueryRow(`
-        INSERT INTO users (first_name, last_name, email, password_hash)
-        VALUES ($1, $2, $3, $4)
-        RETURNING id, first_name, last_name, email, password_hash, created_at, updated_at`,
+	row := transaction.QueryRowContext(ctx, query,
 		user.FirstName,
 		user.LastName,
 		user.Email,
 		user.PasswordHash,
 	)
 
-	newUser, err := scanUserRow(row)
+	newUser, err = scanUserRow(row)
 	if err != nil {
 		return types.InternalUser{}, err
 	}
@@ -54,20 +61,21 @@ func (s *Store) CreateUser(user types.InternalUser) (types.InternalUser, error)
 	return newUser, nil
 }
 
-func (s *Store) getInternalUser(whereClause string, value any) (types.InternalUser, error) {
-	row := s.db.QueryRow(`
-        SELECT
-            id,
-            first_name,
-            last_name,
-            email,
-            password_hash,
-            created_at,
-            updated_at
-        FROM users `+whereClause,
-		value)
-
-	user, err := scanUserRow(row)
+func (s *Store) getInternalUser(ctx context.Context, whereClause string, value any) (user types.InternalUser, err error) {
+	query := `SELECT ` + userFields + ` FROM users ` + whereClause
+
+	ctx, span := db.StartQuerySpan(ctx, "users.select", query)
+	defer func() {
+		if errors.Is(err, types.UserDoesNotExistErr) {
+			tracing.End(span, nil)
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	row := s.db.QueryRowContext(ctx, query, value)
+
+	user, err = scanUserRow(row)
 	if err != nil {
 		return types.InternalUser{}, err
 	}
@@ -75,6 +83,8 @@ func (s *Store) getInternalUser(whereClause string, value any) (types.InternalUs
 	return user, nil
 }
 
+const userFields = `id, first_name, last_name, email, password_hash, verified_at, role, disabled_at, totp_secret, totp_enabled_at, created_at, updated_at`
+
 func scanUserRow(row *sql.Row) (types.InternalUser, error) {
 	var user types.InternalUser
 	err := row.Scan(
@@ -83,6 +93,11 @@ func scanUserRow(row *sql.Row) (types.InternalUser, error) {
 		&user.LastName,
 		&user.Email,
 		&user.PasswordHash,
+		&user.VerifiedAt,
+		&user.Role,
+		&user.DisabledAt,
+		&user.TotpSecret,
+		&user.TotpEnabledAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -96,3 +111,131 @@ func scanUserRow(row *sql.Row) (types.InternalUser, error) {
 
 	return user, nil
 }
+
+func (s *Store) CreateRefreshToken(ctx context.Context, token types.RefreshToken) (newToken types.RefreshToken, err error) {
+	query := `
+        INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+        VALUES ($1, $2, $3, $4)
+        RETURNING ` + refreshTokenFields
+
+	ctx, span := db.StartQuerySpan(ctx, "refresh_tokens.insert", query)
+	defer func() { tracing.End(span, err) }()
+
+	row := s.db.QueryRowContext(ctx, query, token.UserId, token.FamilyId, token.TokenHash, token.ExpiresAt)
+	return scanRefreshTokenRow(row)
+}
+
+func (s *Store) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (token types.RefreshToken, err error) {
+	query := `SELECT ` + refreshTokenFields + ` FROM refresh_tokens WHERE token_hash = $1`
+
+	ctx, span := db.StartQuerySpan(ctx, "refresh_tokens.select", query)
+	defer func() { tracing.End(span, err) }()
+
+	row := s.db.QueryRowContext(ctx, query, tokenHash)
+	return scanRefreshTokenRow(row)
+}
+
+// RotateRefreshToken marks the used token as spent and stores its successor
+// in one transaction. RefreshTokenReusedErr is returned when the used token
+// was already spent or revoked, including by a concurrent rotation.
+func (s *Store) RotateRefreshToken(ctx context.Context, usedId int, next types.RefreshToken) (newToken types.RefreshToken, err error) {
+	query := `
+        UPDATE refresh_tokens SET used_at = now()
+        WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL`
+
+	ctx, span := db.StartQuerySpan(ctx, "refresh_tokens.rotate", query)
+	defer func() { tracing.End(span, err) }()
+
+	transaction, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return types.RefreshToken{}, err
+	}
+	defer transaction.Rollback()
+
+	result, err := transaction.ExecContext(ctx, query, usedId)
+	if err != nil {
+		return types.RefreshToken{}, err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return types.RefreshToken{}, err
+	}
+	if updated == 0 {
+		return types.RefreshToken{}, types.RefreshTokenReusedErr
+	}
+
+	row := transaction.QueryRowContext(ctx, `
+        INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+        VALUES ($1, $2, $3, $4)
+        RETURNING `+refreshTokenFields,
+		next.UserId, next.FamilyId, next.TokenHash, next.ExpiresAt,
+	)
+
+	newToken, err = scanRefreshTokenRow(row)
+	if err != nil {
+		return types.RefreshToken{}, err
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		return types.RefreshToken{}, err
+	}
+
+	return newToken, nil
+}
+
+// RevokeRefreshTokenFamily also revokes the session the family belongs to.
+func (s *Store) RevokeRefreshTokenFamily(ctx context.Context, familyId string) (err error) {
+	query := `
+        WITH revoked_sessions AS (
+            UPDATE sessions SET revoked_at = now() WHERE family_id = $1 AND revoked_at IS NULL
+        )
+        UPDATE refresh_tokens SET revoked_at = now() WHERE family_id = $1 AND revoked_at IS NULL`
+
+	ctx, span := db.StartQuerySpan(ctx, "refresh_tokens.revoke_family", query)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.db.ExecContext(ctx, query, familyId)
+	return err
+}
+
+const refreshTokenFields = `id, user_id, family_id, token_hash, expires_at, used_at, revoked_at, created_at`
+
+func scanRefreshTokenRow(row *sql.Row) (types.RefreshToken, error) {
+	var token types.RefreshToken
+	err := row.Scan(
+		&token.Id,
+		&token.UserId,
+		&token.FamilyId,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.RefreshToken{}, types.RefreshTokenDoesNotExistErr
+	}
+	if err != nil {
+		return types.RefreshToken{}, err
+	}
+
+	return token, nil
+}
+
+// RevokeUserRefreshTokens also revokes every session of the user.
+func (s *Store) RevokeUserRefreshTokens(ctx context.Context, userId int) (err error) {
+	query := `
+        WITH revoked_sessions AS (
+            UPDATE sessions SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL
+        )
+        UPDATE refresh_tokens SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL`
+
+	ctx, span := db.StartQuerySpan(ctx, "refresh_tokens.revoke_user", query)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.db.ExecContext(ctx, query, userId)
+	return err
+}
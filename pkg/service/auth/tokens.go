@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// issueTokens creates an access token and a refresh token starting a new
+// refresh token family, and with it a new session, for the user.
+func (h *Handler) issueTokens(r *http.Request, user types.User) (TokenResponseData, error) {
+	familyId := make([]byte, 16)
+	rand.Read(familyId)
+
+	return h.issueTokensInFamily(r, user, hex.EncodeToString(familyId), nil)
+}
+
+// issueTokensInFamily creates an access token and a refresh token in an
+// existing family. When used is set, the used refresh token is spent in the
+// same step. The session of the family is updated with the client making
+// the request.
+func (h *Handler) issueTokensInFamily(r *http.Request, user types.User, familyId string, used *types.RefreshToken) (TokenResponseData, error) {
+	ctx := r.Context()
+
+	refreshToken, refreshTokenHash := newOpaqueToken()
+	next := types.RefreshToken{
+		UserId:    user.Id,
+		FamilyId:  familyId,
+		TokenHash: refreshTokenHash,
+		ExpiresAt: time.Now().Add(service.JwtClient.RefreshTokenTTL()),
+	}
+
+	var stored types.RefreshToken
+	var err error
+	if used != nil {
+		stored, err = h.store.RotateRefreshToken(ctx, used.Id, next)
+	} else {
+		stored, err = h.store.CreateRefreshToken(ctx, next)
+	}
+	if err != nil {
+		return TokenResponseData{}, err
+	}
+
+	session, err := h.store.TouchSession(ctx, types.Session{
+		UserId:    user.Id,
+		FamilyId:  familyId,
+		UserAgent: truncate(r.UserAgent(), maxUserAgentLength),
+		IpAddress: service.ClientIP(r),
+		ExpiresAt: stored.ExpiresAt,
+	})
+	if err != nil {
+		return TokenResponseData{}, err
+	}
+
+	token, err := service.JwtClient.CreateToken(ctx, user, session.Id)
+	if err != nil {
+		return TokenResponseData{}, err
+	}
+
+	return TokenResponseData{
+		Token:                 token,
+		ExpiresAt:             time.Now().Add(service.JwtClient.AccessTokenTTL()),
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: stored.ExpiresAt,
+	}, nil
+}
+
+func (h *Handler) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	var body RefreshBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := body.IsValid(); err != nil {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	used, err := h.store.GetRefreshTokenByHash(r.Context(), hashToken(*body.RefreshToken))
+	if errors.Is(err, types.RefreshTokenDoesNotExistErr) {
+		service.SendErrorsResponse(w, []string{"Refresh token is not valid"}, http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if used.UsedAt != nil || used.RevokedAt != nil {
+		h.revokeReusedFamily(w, r, used)
+		return
+	}
+
+	if time.Now().After(used.ExpiresAt) {
+		service.SendErrorsResponse(w, []string{"Refresh token has expired"}, http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.store.GetInternalUserById(r.Context(), used.UserId)
+	if errors.Is(err, types.UserDoesNotExistErr) {
+		service.SendErrorsResponse(w, []string{"Refresh token is not valid"}, http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if rejectDisabled(w, user) {
+		return
+	}
+
+	data, err := h.issueTokensInFamily(r, user.User, used.FamilyId, &used)
+	if errors.Is(err, types.RefreshTokenReusedErr) {
+		h.revokeReusedFamily(w, r, used)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	service.SendJsonResponse(w, data, http.StatusOK)
+}
+
+// revokeReusedFamily handles a refresh token that was presented after it had
+// already been spent. Either the client or an attacker holds a stolen copy,
+// so every token descended from the same login is revoked.
+func (h *Handler) revokeReusedFamily(w http.ResponseWriter, r *http.Request, used types.RefreshToken) {
+	slog.WarnContext(r.Context(), "refresh token reuse detected, revoking token family",
+		"user_id", used.UserId,
+		"family_id", used.FamilyId,
+	)
+
+	if err := h.store.RevokeRefreshTokenFamily(r.Context(), used.FamilyId); err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	service.SendErrorsResponse(w, []string{"Refresh token is not valid"}, http.StatusUnauthorized)
+}
+
+// newOpaqueToken returns a random token for the client and the hash of it
+// that is stored in place of the token.
+func newOpaqueToken() (token string, tokenHash string) {
+	b := make([]byte, 32)
+	rand.Read(b)
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,353 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/metrics"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/totp"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	recoveryCodeCount = 10
+	// maxTwoFactorAttempts limits how many codes can be tried per challenge,
+	// so guessing a code needs a correct password for every few attempts.
+	maxTwoFactorAttempts = 5
+)
+
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns codes formatted for the user and their hashes.
+func newRecoveryCodes() ([]string, []string) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		random := make([]byte, 7)
+		rand.Read(random)
+
+		code := recoveryCodeEncoding.EncodeToString(random)[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryCode(code)
+	}
+
+	return codes, hashes
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return hashToken(code)
+}
+
+// verifySecondFactor accepts either a TOTP code or an unused recovery code.
+// Both can only be used once.
+func (h *Handler) verifySecondFactor(ctx context.Context, user types.InternalUser, code string) (bool, error) {
+	if !user.TwoFactorEnabled() || user.TotpSecret == nil {
+		return false, nil
+	}
+
+	code = strings.TrimSpace(code)
+	if len(strings.ReplaceAll(code, " ", "")) == totp.Digits {
+		step, ok := totp.Validate(*user.TotpSecret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+
+		return h.store.ClaimTotpStep(ctx, user.Id, step)
+	}
+
+	return h.store.UseRecoveryCode(ctx, user.Id, hashRecoveryCode(code))
+}
+
+// startTwoFactorChallenge responds to a login with correct credentials by a
+// user with 2FA enabled.
+func (h *Handler) startTwoFactorChallenge(w http.ResponseWriter, r *http.Request, user types.InternalUser) {
+	token, tokenHash := newOpaqueToken()
+	challenge := types.TwoFactorChallenge{
+		UserId:    user.Id,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(h.config.TwoFactorChallengeTTL),
+	}
+
+	if err := h.store.CreateTwoFactorChallenge(r.Context(), challenge); err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	data := TwoFactorChallengeData{
+		ChallengeToken: token,
+		ExpiresAt:      challenge.ExpiresAt,
+	}
+
+	service.SendJsonResponse(w, data, http.StatusAccepted)
+}
+
+func (h *Handler) handleTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	var body TwoFactorLoginBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := body.IsValid(); err != nil {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	challenge, err := h.store.AttemptTwoFactorChallenge(r.Context(), hashToken(*body.ChallengeToken), maxTwoFactorAttempts)
+	if errors.Is(err, types.InvalidOneTimeTokenErr) {
+		metrics.LoginFailed("invalid_two_factor_challenge")
+		service.SendErrorsResponse(w, []string{"Challenge is not valid or has expired, please log in again"}, http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	user, err := h.store.GetInternalUserById(r.Context(), challenge.UserId)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if rejectDisabled(w, user) || h.rejectThrottled(w, r, user.Email) {
+		return
+	}
+
+	ok, err := h.verifySecondFactor(r.Context(), user, *body.Code)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if !ok {
+		if err := h.recordLoginFailure(r, user.Email, &user); err != nil {
+			service.SendInternalServerError(w, r, err)
+			return
+		}
+
+		metrics.LoginFailed("invalid_two_factor_code")
+		service.SendErrorsResponse(w, []string{"Code is not valid"}, http.StatusUnauthorized)
+		return
+	}
+
+	completed, err := h.store.CompleteTwoFactorChallenge(r.Context(), challenge.Id)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if !completed {
+		service.SendErrorsResponse(w, []string{"Challenge is not valid or has expired, please log in again"}, http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.clearLoginFailures(r.Context(), user.Email); err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	tokens, err := h.issueTokens(r, user.User)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	metrics.LoginSucceeded()
+
+	service.SendJsonResponse(w, LoginResponseData{TokenResponseData: tokens}, http.StatusOK)
+}
+
+func (h *Handler) handleTotpSetup(w http.ResponseWriter, r *http.Request) {
+	user, err := h.store.GetInternalUserById(r.Context(), service.UserIdFromContext(r.Context()))
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if user.TwoFactorEnabled() {
+		service.SendErrorsResponse(w, []string{"Two-factor authentication is already enabled"}, http.StatusConflict)
+		return
+	}
+
+	secret := totp.GenerateSecret()
+	updated, err := h.store.SetPendingTotpSecret(r.Context(), user.Id, secret)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if !updated {
+		service.SendErrorsResponse(w, []string{"Two-factor authentication is already enabled"}, http.StatusConflict)
+		return
+	}
+
+	uri := totp.URI(h.config.TotpIssuer, user.Email, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	data := TotpSetupResponseData{
+		Secret:     secret,
+		OtpauthUri: uri,
+		QrCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}
+
+	service.SendJsonResponse(w, data, http.StatusOK)
+}
+
+func (h *Handler) handleTotpEnable(w http.ResponseWriter, r *http.Request) {
+	var body TwoFactorCodeBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := body.IsValid(); err != nil {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.store.GetInternalUserById(r.Context(), service.UserIdFromContext(r.Context()))
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if user.TwoFactorEnabled() {
+		service.SendErrorsResponse(w, []string{"Two-factor authentication is already enabled"}, http.StatusConflict)
+		return
+	}
+
+	if user.TotpSecret == nil {
+		service.SendErrorsResponse(w, []string{"Two-factor authentication setup was not started"}, http.StatusBadRequest)
+		return
+	}
+
+	step, ok := totp.Validate(*user.TotpSecret, *body.Code, time.Now())
+	if !ok {
+		service.SendErrorsResponse(w, []string{"Code is not valid"}, http.StatusBadRequest)
+		return
+	}
+
+	codes, hashes := newRecoveryCodes()
+	enabled, err := h.store.EnableTotp(r.Context(), user.Id, step, hashes)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if !enabled {
+		service.SendErrorsResponse(w, []string{"Two-factor authentication is already enabled"}, http.StatusConflict)
+		return
+	}
+
+	service.SendJsonResponse(w, RecoveryCodesResponseData{RecoveryCodes: codes}, http.StatusOK)
+}
+
+func (h *Handler) handleTotpDisable(w http.ResponseWriter, r *http.Request) {
+	var body DisableTwoFactorBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := body.IsValid(); err != nil {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.store.GetInternalUserById(r.Context(), service.UserIdFromContext(r.Context()))
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if !user.TwoFactorEnabled() {
+		service.SendErrorsResponse(w, []string{"Two-factor authentication is not enabled"}, http.StatusConflict)
+		return
+	}
+
+	_, err = h.comparePassword(r.Context(), user.PasswordHash, *body.Password)
+	if errors.Is(err, types.PasswordMismatchErr) {
+		service.SendErrorsResponse(w, []string{"Password is not valid"}, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	ok, err := h.verifySecondFactor(r.Context(), user, *body.Code)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if !ok {
+		service.SendErrorsResponse(w, []string{"Code is not valid"}, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.DisableTotp(r.Context(), user.Id); err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	service.SendJsonResponse(w, MessageResponseData{Message: "Two-factor authentication was disabled"}, http.StatusOK)
+}
+
+func (h *Handler) handleRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var body TwoFactorCodeBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := body.IsValid(); err != nil {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.store.GetInternalUserById(r.Context(), service.UserIdFromContext(r.Context()))
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if !user.TwoFactorEnabled() {
+		service.SendErrorsResponse(w, []string{"Two-factor authentication is not enabled"}, http.StatusConflict)
+		return
+	}
+
+	ok, err := h.verifySecondFactor(r.Context(), user, *body.Code)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if !ok {
+		service.SendErrorsResponse(w, []string{"Code is not valid"}, http.StatusBadRequest)
+		return
+	}
+
+	codes, hashes := newRecoveryCodes()
+	if err := h.store.ReplaceRecoveryCodes(r.Context(), user.Id, hashes); err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	service.SendJsonResponse(w, RecoveryCodesResponseData{RecoveryCodes: codes}, http.StatusOK)
+}
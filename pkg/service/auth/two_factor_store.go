@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// SetPendingTotpSecret stores a secret that is not used for logins until it
+// is confirmed with EnableTotp. It reports false when 2FA is already enabled.
+func (s *Store) SetPendingTotpSecret(ctx context.Context, userId int, secret string) (updated bool, err error) {
+	query := `
+        UPDATE users SET totp_secret = $2, totp_last_step = NULL
+        WHERE id = $1 AND totp_enabled_at IS NULL`
+
+	ctx, span := db.StartQuerySpan(ctx, "users.update_totp_secret", query)
+	defer func() { tracing.End(span, err) }()
+
+	result, err := s.db.ExecContext(ctx, query, userId, secret)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// EnableTotp enables 2FA with the pending secret, recording step as used and
+// replacing the user's recovery codes. It reports false when there is no
+// pending secret.
+func (s *Store) EnableTotp(ctx context.Context, userId int, step int64, recoveryCodeHashes []string) (enabled bool, err error) {
+	query := `
+        UPDATE users SET totp_enabled_at = now(), totp_last_step = $2, updated_at = DEFAULT
+        WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL`
+
+	ctx, span := db.StartQuerySpan(ctx, "users.enable_totp", query)
+	defer func() { tracing.End(span, err) }()
+
+	transaction, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer transaction.Rollback()
+
+	result, err := transaction.ExecContext(ctx, query, userId, step)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil || rows == 0 {
+		return false, err
+	}
+
+	err = replaceRecoveryCodes(ctx, transaction, userId, recoveryCodeHashes)
+	if err != nil {
+		return false, err
+	}
+
+	return true, transaction.Commit()
+}
+
+// DisableTotp removes the user's secret, recovery codes and open challenges.
+func (s *Store) DisableTotp(ctx context.Context, userId int) (err error) {
+	query := `
+        UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = DEFAULT
+        WHERE id = $1`
+
+	ctx, span := db.StartQuerySpan(ctx, "users.disable_totp", query)
+	defer func() { tracing.End(span, err) }()
+
+	transaction, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer transaction.Rollback()
+
+	_, err = transaction.ExecContext(ctx, query, userId)
+	if err != nil {
+		return err
+	}
+
+	_, err = transaction.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userId)
+	if err != nil {
+		return err
+	}
+
+	_, err = transaction.ExecContext(ctx, `
+        UPDATE two_factor_challenges SET used_at = now()
+        WHERE user_id = $1 AND used_at IS NULL`,
+		userId,
+	)
+	if err != nil {
+		return err
+	}
+
+	return transaction.Commit()
+}
+
+// ClaimTotpStep records step as the last one a code was accepted for. It
+// reports false when a code for this or a later step was already used, so
+// every code works only once.
+func (s *Store) ClaimTotpStep(ctx context.Context, userId int, step int64) (claimed bool, err error) {
+	query := `
+        UPDATE users SET totp_last_step = $2
+        WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)`
+
+	ctx, span := db.StartQuerySpan(ctx, "users.update_totp_last_step", query)
+	defer func() { tracing.End(span, err) }()
+
+	result, err := s.db.ExecContext(ctx, query, userId, step)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// ReplaceRecoveryCodes deletes the user's recovery codes, used or not, and
+// stores new ones.
+func (s *Store) ReplaceRecoveryCodes(ctx context.Context, userId int, codeHashes []string) (err error) {
+	ctx, span := db.StartQuerySpan(ctx, "recovery_codes.replace", insertRecoveryCodeQuery)
+	defer func() { tracing.End(span, err) }()
+
+	transaction, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer transaction.Rollback()
+
+	err = replaceRecoveryCodes(ctx, transaction, userId, codeHashes)
+	if err != nil {
+		return err
+	}
+
+	return transaction.Commit()
+}
+
+const insertRecoveryCodeQuery = `INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`
+
+func replaceRecoveryCodes(ctx context.Context, transaction *sql.Tx, userId int, codeHashes []string) error {
+	_, err := transaction.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userId)
+	if err != nil {
+		return err
+	}
+
+	for _, codeHash := range codeHashes {
+		_, err = transaction.ExecContext(ctx, insertRecoveryCodeQuery, userId, codeHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UseRecoveryCode spends one of the user's recovery codes. It reports false
+// when the code does not exist or was already used.
+func (s *Store) UseRecoveryCode(ctx context.Context, userId int, codeHash string) (used bool, err error) {
+	query := `
+        UPDATE recovery_codes SET used_at = now()
+        WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	ctx, span := db.StartQuerySpan(ctx, "recovery_codes.consume", query)
+	defer func() { tracing.End(span, err) }()
+
+	result, err := s.db.ExecContext(ctx, query, userId, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+func (s *Store) CreateTwoFactorChallenge(ctx context.Context, challenge types.TwoFactorChallenge) (err error) {
+	query := `
+        INSERT INTO two_factor_challenges (user_id, token_hash, expires_at)
+        VALUES ($1, $2, $3)`
+
+	ctx, span := db.StartQuerySpan(ctx, "two_factor_challenges.insert", query)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.db.ExecContext(ctx, query, challenge.UserId, challenge.TokenHash, challenge.ExpiresAt)
+	return err
+}
+
+// AttemptTwoFactorChallenge counts an attempt at answering the challenge and
+// returns it. InvalidOneTimeTokenErr is returned for unknown, completed and
+// expired challenges and for challenges that ran out of attempts.
+func (s *Store) AttemptTwoFactorChallenge(ctx context.Context, tokenHash string, maxAttempts int) (challenge types.TwoFactorChallenge, err error) {
+	query := `
+        UPDATE two_factor_challenges SET attempts = attempts + 1
+        WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now() AND attempts < $2
+        RETURNING id, user_id, token_hash, expires_at, attempts, used_at, created_at`
+
+	ctx, span := db.StartQuerySpan(ctx, "two_factor_challenges.attempt", query)
+	defer func() { tracing.End(span, err) }()
+
+	err = s.db.QueryRowContext(ctx, query, tokenHash, maxAttempts).Scan(
+		&challenge.Id,
+		&challenge.UserId,
+		&challenge.TokenHash,
+		&challenge.ExpiresAt,
+		&challenge.Attempts,
+		&challenge.UsedAt,
+		&challenge.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.TwoFactorChallenge{}, types.InvalidOneTimeTokenErr
+	}
+	if err != nil {
+		return types.TwoFactorChallenge{}, err
+	}
+
+	return challenge, nil
+}
+
+// CompleteTwoFactorChallenge marks the challenge as answered. It reports
+// false when it was already completed by a concurrent request.
+func (s *Store) CompleteTwoFactorChallenge(ctx context.Context, id int) (completed bool, err error) {
+	query := `UPDATE two_factor_challenges SET used_at = now() WHERE id = $1 AND used_at IS NULL`
+
+	ctx, span := db.StartQuerySpan(ctx, "two_factor_challenges.complete", query)
+	defer func() { tracing.End(span, err) }()
+
+	result, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
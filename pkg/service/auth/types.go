@@ -1,15 +1,18 @@
 package auth
 
 import (
+	"time"
+
 	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/webauthn"
 )
 
 type RegisterBody struct {
-	FirstName      *string `json:"first_name"`
-	LastName       *string `json:"last_name"`
-	Email          *string `json:"email"`
-	Password       *string `json:"password"`
-	PasswordVerify *string `json:"password_verify"`
+	FirstName      *string `json:"first_name" openapi:"minLength=1,maxLength=100"`
+	LastName       *string `json:"last_name" openapi:"minLength=1,maxLength=100"`
+	Email          *string `json:"email" openapi:"format=email,maxLength=320"`
+	Password       *string `json:"password" openapi:"minLength=1,maxLength=1024"`
+	PasswordVerify *string `json:"password_verify" openapi:"minLength=1,maxLength=1024"`
 }
 
 func (r *RegisterBody) IsValid() error {
@@ -42,8 +45,8 @@ func (r *RegisterBody) CreateInternalUser(passwordHash string) types.InternalUse
 }
 
 type LoginBody struct {
-	Email    *string `json:"email"`
-	Password *string `json:"password"`
+	Email    *string `json:"email" openapi:"format=email,maxLength=320"`
+	Password *string `json:"password" openapi:"minLength=1,maxLength=1024"`
 }
 
 func (l *LoginBody) IsValid() error {
@@ -53,3 +56,270 @@ func (l *LoginBody) IsValid() error {
 
 	return nil
 }
+
+type TokenResponseData struct {
+	Token                 string    `json:"auth_token"`
+	ExpiresAt             time.Time `json:"expires_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+type RegisterResponseData struct {
+	User types.User `json:"user"`
+	TokenResponseData
+}
+
+type LoginResponseData struct {
+	TokenResponseData
+}
+
+type RefreshBody struct {
+	RefreshToken *string `json:"refresh_token" openapi:"minLength=1,maxLength=200"`
+}
+
+func (b *RefreshBody) IsValid() error {
+	if b.RefreshToken == nil {
+		return types.InvalidBodyErr
+	}
+
+	return nil
+}
+
+type LogoutBody struct {
+	RefreshToken *string `json:"refresh_token,omitempty" openapi:"minLength=1,maxLength=200"`
+}
+
+type MessageResponseData struct {
+	Message string `json:"message"`
+}
+
+type ForgotPasswordBody struct {
+	Email *string `json:"email" openapi:"format=email,maxLength=320"`
+}
+
+func (b *ForgotPasswordBody) IsValid() error {
+	if b.Email == nil {
+		return types.InvalidBodyErr
+	}
+
+	return nil
+}
+
+type MagicLinkBody struct {
+	Email *string `json:"email" openapi:"format=email,maxLength=320"`
+}
+
+func (b *MagicLinkBody) IsValid() error {
+	if b.Email == nil {
+		return types.InvalidBodyErr
+	}
+
+	return nil
+}
+
+// MagicLinkResponseData carries the device token that has to be sent along
+// with the link from the email, so the link only works on this device.
+type MagicLinkResponseData struct {
+	Message     string    `json:"message"`
+	DeviceToken string    `json:"device_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type ResetPasswordBody struct {
+	Token          *string `json:"token" openapi:"minLength=1,maxLength=200"`
+	Password       *string `json:"password" openapi:"minLength=1,maxLength=1024"`
+	PasswordVerify *string `json:"password_verify" openapi:"minLength=1,maxLength=1024"`
+}
+
+func (b *ResetPasswordBody) IsValid() error {
+	if b.Token == nil || b.Password == nil || b.PasswordVerify == nil {
+		return types.InvalidBodyErr
+	}
+
+	if *b.Password != *b.PasswordVerify {
+		return types.PasswordsDoNotMatchErr
+	}
+
+	return nil
+}
+
+type VerifyEmailBody struct {
+	Token *string `json:"token" openapi:"minLength=1,maxLength=1000"`
+}
+
+func (b *VerifyEmailBody) IsValid() error {
+	if b.Token == nil {
+		return types.InvalidBodyErr
+	}
+
+	return nil
+}
+
+// TwoFactorChallengeData is returned by login instead of tokens when the user
+// has 2FA enabled. The challenge token is exchanged for tokens together with
+// a code.
+type TwoFactorChallengeData struct {
+	ChallengeToken string    `json:"challenge_token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+type TwoFactorLoginBody struct {
+	ChallengeToken *string `json:"challenge_token" openapi:"minLength=1,maxLength=200"`
+	Code           *string `json:"code" openapi:"minLength=1,maxLength=32"`
+}
+
+func (b *TwoFactorLoginBody) IsValid() error {
+	if b.ChallengeToken == nil || b.Code == nil {
+		return types.InvalidBodyErr
+	}
+
+	return nil
+}
+
+type TotpSetupResponseData struct {
+	Secret     string `json:"secret"`
+	OtpauthUri string `json:"otpauth_uri"`
+	// QrCode is a PNG data URI of the otpauth URI.
+	QrCode string `json:"qr_code"`
+}
+
+type TwoFactorCodeBody struct {
+	Code *string `json:"code" openapi:"minLength=1,maxLength=32"`
+}
+
+func (b *TwoFactorCodeBody) IsValid() error {
+	if b.Code == nil {
+		return types.InvalidBodyErr
+	}
+
+	return nil
+}
+
+type DisableTwoFactorBody struct {
+	Password *string `json:"password" openapi:"minLength=1,maxLength=1024"`
+	Code     *string `json:"code" openapi:"minLength=1,maxLength=32"`
+}
+
+func (b *DisableTwoFactorBody) IsValid() error {
+	if b.Password == nil || b.Code == nil {
+		return types.InvalidBodyErr
+	}
+
+	return nil
+}
+
+type RecoveryCodesResponseData struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type OAuthProviderData struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+type OAuthAuthorizeResponseData struct {
+	AuthorizationUrl string    `json:"authorization_url"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+type OAuthCallbackBody struct {
+	Code  *string `json:"code" openapi:"minLength=1,maxLength=2000"`
+	State *string `json:"state" openapi:"minLength=1,maxLength=200"`
+}
+
+func (b *OAuthCallbackBody) IsValid() error {
+	if b.Code == nil || b.State == nil {
+		return types.InvalidBodyErr
+	}
+
+	return nil
+}
+
+type UpdateProfileBody struct {
+	FirstName *string `json:"first_name,omitempty" openapi:"minLength=1,maxLength=100"`
+	LastName  *string `json:"last_name,omitempty" openapi:"minLength=1,maxLength=100"`
+}
+
+type ChangePasswordBody struct {
+	CurrentPassword *string `json:"current_password" openapi:"minLength=1,maxLength=1024"`
+	Password        *string `json:"password" openapi:"minLength=1,maxLength=1024"`
+	PasswordVerify  *string `json:"password_verify" openapi:"minLength=1,maxLength=1024"`
+}
+
+func (b *ChangePasswordBody) IsValid() error {
+	if b.CurrentPassword == nil || b.Password == nil || b.PasswordVerify == nil {
+		return types.InvalidBodyErr
+	}
+
+	if *b.Password != *b.PasswordVerify {
+		return types.PasswordsDoNotMatchErr
+	}
+
+	return nil
+}
+
+type ChangeEmailBody struct {
+	Email    *string `json:"email" openapi:"format=email,maxLength=320"`
+	Password *string `json:"password" openapi:"minLength=1,maxLength=1024"`
+}
+
+func (b *ChangeEmailBody) IsValid() error {
+	if b.Email == nil || b.Password == nil {
+		return types.InvalidBodyErr
+	}
+
+	return nil
+}
+
+type ConfirmEmailChangeBody struct {
+	Token *string `json:"token" openapi:"minLength=1,maxLength=1000"`
+}
+
+func (b *ConfirmEmailChangeBody) IsValid() error {
+	if b.Token == nil {
+		return types.InvalidBodyErr
+	}
+
+	return nil
+}
+
+// DeleteAccountBody re-authenticates the user. Code is required when the
+// user has 2FA enabled.
+type DeleteAccountBody struct {
+	Password *string `json:"password" openapi:"minLength=1,maxLength=1024"`
+	Code     *string `json:"code,omitempty" openapi:"minLength=1,maxLength=32"`
+}
+
+func (b *DeleteAccountBody) IsValid() error {
+	if b.Password == nil {
+		return types.InvalidBodyErr
+	}
+
+	return nil
+}
+
+type RegisterPasskeyBody struct {
+	// Name defaults to the browser and operating system of the request.
+	Name       *string                          `json:"name,omitempty" openapi:"minLength=1,maxLength=100"`
+	Credential *webauthn.RegistrationCredential `json:"credential"`
+}
+
+func (b *RegisterPasskeyBody) IsValid() error {
+	if b.Credential == nil {
+		return types.InvalidBodyErr
+	}
+
+	return nil
+}
+
+type PasskeyLoginBody struct {
+	Credential *webauthn.AuthenticationCredential `json:"credential"`
+}
+
+func (b *PasskeyLoginBody) IsValid() error {
+	if b.Credential == nil {
+		return types.InvalidBodyErr
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/mailer"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// verificationPayload is signed into email verification links. Including the
+// email means a link stops working once the user changes their address.
+type verificationPayload struct {
+	UserId    int    `json:"uid"`
+	Email     string `json:"email"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+func (h *Handler) createVerificationToken(user types.User) (string, error) {
+	return h.createSignedToken("email-verification", verificationPayload{
+		UserId:    user.Id,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(h.config.VerificationTTL).Unix(),
+	})
+}
+
+func (h *Handler) parseVerificationToken(token string) (verificationPayload, error) {
+	var payload verificationPayload
+	if err := h.parseSignedToken("email-verification", token, &payload); err != nil {
+		return verificationPayload{}, err
+	}
+
+	if time.Now().Unix() > payload.ExpiresAt {
+		return verificationPayload{}, types.InvalidOneTimeTokenErr
+	}
+
+	return payload, nil
+}
+
+// createSignedToken encodes the payload into a token signed for the purpose,
+// so a token issued for one kind of link is not accepted by another.
+func (h *Handler) createSignedToken(purpose string, payload any) (string, error) {
+	encodedPayload, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(encodedPayload)
+	return encoded + "." + h.sign(purpose, encoded), nil
+}
+
+func (h *Handler) parseSignedToken(purpose string, token string, payload any) error {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(h.sign(purpose, encoded))) {
+		return types.InvalidOneTimeTokenErr
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return types.InvalidOneTimeTokenErr
+	}
+
+	if err := json.Unmarshal(decoded, payload); err != nil {
+		return types.InvalidOneTimeTokenErr
+	}
+
+	return nil
+}
+
+func (h *Handler) sign(purpose string, encoded string) string {
+	mac := hmac.New(sha256.New, h.config.VerificationSecret)
+	mac.Write([]byte(purpose + ":" + encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// sendVerificationEmail emails a verification link unless verification is
+// turned off, the user is verified or was sent a link too recently. It
+// reports whether an email was sent.
+func (h *Handler) sendVerificationEmail(ctx context.Context, user types.User) (bool, error) {
+	if h.config.VerificationPolicy == VerificationOff || user.VerifiedAt != nil {
+		return false, nil
+	}
+
+	claimed, err := h.store.ClaimVerificationEmail(ctx, user.Id, h.config.VerificationResendInterval)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	token, err := h.createVerificationToken(user)
+	if err != nil {
+		return false, err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", h.config.AppUrl, url.QueryEscape(token))
+	h.sendEmail(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s\n",
+			user.FirstName, h.config.VerificationTTL, link),
+	})
+
+	return true, nil
+}
+
+func (h *Handler) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var body VerifyEmailBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := body.IsValid(); err != nil {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	payload, err := h.parseVerificationToken(*body.Token)
+	if err != nil {
+		service.SendErrorsResponse(w, []string{"Verification link is not valid or has expired"}, http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.store.GetInternalUserById(r.Context(), payload.UserId)
+	if errors.Is(err, types.UserDoesNotExistErr) || (err == nil && user.Email != payload.Email) {
+		service.SendErrorsResponse(w, []string{"Verification link is not valid or has expired"}, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if user.VerifiedAt == nil {
+		if _, err := h.store.MarkEmailVerified(r.Context(), user.Id, user.Email); err != nil {
+			service.SendInternalServerError(w, r, err)
+			return
+		}
+	}
+
+	service.SendJsonResponse(w, MessageResponseData{Message: "Email address was verified"}, http.StatusOK)
+}
+
+func (h *Handler) handleResendVerification(w http.ResponseWriter, r *http.Request) {
+	user, err := h.store.GetInternalUserById(r.Context(), service.UserIdFromContext(r.Context()))
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if user.VerifiedAt != nil {
+		service.SendErrorsResponse(w, []string{"Email address is already verified"}, http.StatusConflict)
+		return
+	}
+
+	if h.config.VerificationPolicy == VerificationOff {
+		service.SendErrorsResponse(w, []string{"Email verification is disabled"}, http.StatusNotFound)
+		return
+	}
+
+	sent, err := h.sendVerificationEmail(r.Context(), user.User)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if !sent {
+		w.Header().Set("Retry-After", fmt.Sprintf("%.0f", h.config.VerificationResendInterval.Seconds()))
+		service.SendErrorsResponse(w, []string{"A verification email was sent recently, please try again later"}, http.StatusTooManyRequests)
+		return
+	}
+
+	service.SendJsonResponse(w, MessageResponseData{Message: "Verification email was sent"}, http.StatusOK)
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
+)
+
+// MarkEmailVerified marks the user as verified if their email is still the
+// one that was verified. It reports whether the user was updated.
+func (s *Store) MarkEmailVerified(ctx context.Context, userId int, email string) (updated bool, err error) {
+	query := `
+        UPDATE users SET verified_at = now(), updated_at = DEFAULT
+        WHERE id = $1 AND email = $2 AND verified_at IS NULL`
+
+	ctx, span := db.StartQuerySpan(ctx, "users.update_verified_at", query)
+	defer func() { tracing.End(span, err) }()
+
+	result, err := s.db.ExecContext(ctx, query, userId, email)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// ClaimVerificationEmail records that a verification email is about to be
+// sent to an unverified user. It reports false when the user is verified or
+// was sent one less than interval ago.
+func (s *Store) ClaimVerificationEmail(ctx context.Context, userId int, interval time.Duration) (claimed bool, err error) {
+	query := `
+        UPDATE users SET verification_sent_at = now()
+        WHERE id = $1 AND verified_at IS NULL
+            AND (verification_sent_at IS NULL OR verification_sent_at < now() - make_interval(secs => $2))`
+
+	ctx, span := db.StartQuerySpan(ctx, "users.update_verification_sent_at", query)
+	defer func() { tracing.End(span, err) }()
+
+	result, err := s.db.ExecContext(ctx, query, userId, interval.Seconds())
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
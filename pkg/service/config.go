@@ -0,0 +1,40 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// DurationFromEnv parses the env variable with time.ParseDuration, returning
+// defaultValue when it is not set.
+func DurationFromEnv(name string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a valid duration: %w", name, err)
+	}
+
+	return duration, nil
+}
+
+// IntFromEnv parses the env variable as an integer, returning defaultValue
+// when it is not set.
+func IntFromEnv(name string, defaultValue int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a valid integer: %w", name, err)
+	}
+
+	return number, nil
+}
@@ -0,0 +1,88 @@
+package exports
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+type archiveFile struct {
+	name        string
+	description string
+	records     int
+	data        any
+}
+
+// exportIdentity includes the provider's subject, which the API hides.
+type exportIdentity struct {
+	types.Identity
+	Subject string `json:"subject"`
+}
+
+// buildArchive writes a ZIP with a JSON file per kind of record and a
+// README describing them.
+func buildArchive(data types.AccountData, createdAt time.Time) ([]byte, error) {
+	identities := make([]exportIdentity, len(data.Identities))
+	for i, identity := range data.Identities {
+		identities[i] = exportIdentity{Identity: identity, Subject: identity.Subject}
+	}
+
+	files := []archiveFile{
+		{"profile.json", "Your profile", 1, data.User},
+		{"resumes.json", "Your resumes", len(data.Resumes), data.Resumes},
+		{"identities.json", "Accounts at sign in providers linked to your account", len(identities), identities},
+		{"api_keys.json", "Your API keys that were not revoked, without the keys themselves", len(data.ApiKeys), data.ApiKeys},
+		{"sessions.json", "The devices you are logged in on", len(data.Sessions), data.Sessions},
+		{"passkeys.json", "Your passkeys, without their public keys", len(data.Passkeys), data.Passkeys},
+	}
+
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+
+	readme, err := archive.CreateHeader(&zip.FileHeader{Name: "README.md", Method: zip.Deflate, Modified: createdAt})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := readme.Write([]byte(manifest(data.User, files, createdAt))); err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		writer, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: createdAt})
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func manifest(user types.User, files []archiveFile, createdAt time.Time) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "# Data export\n\n")
+	fmt.Fprintf(&builder, "Everything stored about the account %s (user %d), exported at %s.\n\n",
+		user.Email, user.Id, createdAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&builder, "| File | Records | Contents |\n|---|---|---|\n")
+	for _, file := range files {
+		fmt.Fprintf(&builder, "| %s | %d | %s |\n", file.name, file.records, file.description)
+	}
+	fmt.Fprintf(&builder, "\nFiles are UTF-8 encoded JSON. Times are in RFC 3339 format. "+
+		"The service stores no uploaded files, so the archive contains none.\n")
+
+	return builder.String()
+}
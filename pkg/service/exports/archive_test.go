@@ -0,0 +1,123 @@
+package exports
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+func testAccountData() types.AccountData {
+	var data types.AccountData
+	data.User.Id = 7
+	data.User.Email = "user@example.com"
+
+	data.Resumes = []types.Resume{{UserId: 7, Name: "Backend"}, {UserId: 7, Name: "Frontend"}}
+
+	identity := types.Identity{UserId: 7, Provider: "google", Subject: "google subject", Email: "user@gmail.com"}
+	data.Identities = []types.Identity{identity}
+
+	data.ApiKeys = []types.ApiKey{}
+	data.Sessions = []types.Session{{UserId: 7, UserAgent: "curl/8.4.0"}, {UserId: 7}, {UserId: 7}}
+
+	return data
+}
+
+// readArchive returns the contents of every file in the archive by name, in
+// the order they were written.
+func readArchive(t *testing.T, archive []byte) ([]string, map[string][]byte) {
+	t.Helper()
+
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	files := make(map[string][]byte)
+	for _, file := range reader.File {
+		opened, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents, err := io.ReadAll(opened)
+		opened.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names = append(names, file.Name)
+		files[file.Name] = contents
+	}
+
+	return names, files
+}
+
+func TestBuildArchive(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	archive, err := buildArchive(testAccountData(), createdAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, files := readArchive(t, archive)
+	wantNames := []string{"README.md", "profile.json", "resumes.json", "identities.json", "api_keys.json", "sessions.json", "passkeys.json"}
+	if !slices.Equal(names, wantNames) {
+		t.Fatalf("files = %v, want %v", names, wantNames)
+	}
+
+	records := map[string]int{
+		"resumes.json":    2,
+		"identities.json": 1,
+		"api_keys.json":   0,
+		"sessions.json":   3,
+		"passkeys.json":   0,
+	}
+	for name, want := range records {
+		var decoded []map[string]any
+		if err := json.Unmarshal(files[name], &decoded); err != nil {
+			t.Fatalf("%s is not a JSON array: %v", name, err)
+		}
+		if len(decoded) != want {
+			t.Errorf("%s has %d records, want %d", name, len(decoded), want)
+		}
+	}
+
+	var profile map[string]any
+	if err := json.Unmarshal(files["profile.json"], &profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile["email"] != "user@example.com" {
+		t.Errorf("profile = %v", profile)
+	}
+
+	// The API hides the provider's subject, the export includes it.
+	var identities []map[string]any
+	if err := json.Unmarshal(files["identities.json"], &identities); err != nil {
+		t.Fatal(err)
+	}
+	if identities[0]["subject"] != "google subject" || identities[0]["provider"] != "google" {
+		t.Errorf("identities = %v", identities)
+	}
+
+	readme := string(files["README.md"])
+	for _, want := range []string{
+		"user@example.com (user 7), exported at 2024-03-01T12:00:00Z",
+		"| profile.json | 1 |",
+		"| resumes.json | 2 |",
+		"| identities.json | 1 |",
+		"| api_keys.json | 0 |",
+		"| sessions.json | 3 |",
+		"| passkeys.json | 0 |",
+	} {
+		if !strings.Contains(readme, want) {
+			t.Errorf("README.md does not contain %q:\n%s", want, readme)
+		}
+	}
+}
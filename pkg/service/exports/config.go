@@ -0,0 +1,42 @@
+package exports
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+)
+
+type Config struct {
+	// Secret signs download links.
+	Secret []byte
+	// TTL is how long an archive can be downloaded after it was built.
+	TTL time.Duration
+	// Timeout limits building an archive. Pending exports older than it are
+	// considered interrupted and a new one can be requested.
+	Timeout time.Duration
+}
+
+func ConfigFromEnv() (Config, error) {
+	var config Config
+	var err error
+
+	secret := os.Getenv("DATA_EXPORT_SECRET")
+	if secret == "" {
+		return Config{}, errors.New("DATA_EXPORT_SECRET not provided as an env variable")
+	}
+	config.Secret = []byte(secret)
+
+	config.TTL, err = service.DurationFromEnv("DATA_EXPORT_TTL", 24*time.Hour)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.Timeout, err = service.DurationFromEnv("DATA_EXPORT_TIMEOUT", 10*time.Minute)
+	if err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
+}
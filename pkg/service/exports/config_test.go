@@ -0,0 +1,22 @@
+package exports
+
+import "testing"
+
+func TestConfigFromEnvRequiresSecret(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-jwt-secret")
+	t.Setenv("DATA_EXPORT_SECRET", "")
+
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Error("ConfigFromEnv fell back to JWT_SECRET")
+	}
+
+	t.Setenv("DATA_EXPORT_SECRET", "test-data-export-secret")
+
+	config, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(config.Secret) != "test-data-export-secret" {
+		t.Errorf("secret = %q", config.Secret)
+	}
+}
@@ -0,0 +1,178 @@
+package exports
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	store  db.DataExportStore
+	config Config
+}
+
+func NewHandler(store db.DataExportStore, config Config) *Handler {
+	return &Handler{store: store, config: config}
+}
+
+// AddRoutes adds the routes of the signed in user, which have to come after
+// an authenticator.
+func (h *Handler) AddRoutes(r chi.Router) {
+	r.Post("/me/export", h.handlePostExport)
+	r.Get("/me/export/{exportId}", h.handleSingleExport)
+}
+
+// AddDownloadRoutes adds the download route, which is authorized by the
+// signed link instead of a token.
+func (h *Handler) AddDownloadRoutes(r chi.Router) {
+	r.Get("/exports/{exportId}/download", h.handleDownload)
+}
+
+// handlePostExport starts building an archive in the background. Requests
+// made while one is being built return that one instead.
+func (h *Handler) handlePostExport(w http.ResponseWriter, r *http.Request) {
+	export, created, err := h.store.CreateDataExport(r.Context(), service.UserIdFromContext(r.Context()), h.config.Timeout)
+	if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	if created {
+		go h.build(context.WithoutCancel(r.Context()), export)
+	}
+
+	service.SendJsonResponse(w, h.responseData(export), http.StatusAccepted)
+}
+
+func (h *Handler) handleSingleExport(w http.ResponseWriter, r *http.Request) {
+	exportId, err := strconv.Atoi(chi.URLParam(r, "exportId"))
+	if err != nil {
+		service.SendErrorsResponse(w, []string{"Provided url param was not parsable"}, http.StatusBadRequest)
+		return
+	}
+
+	export, err := h.store.GetDataExport(r.Context(), service.UserIdFromContext(r.Context()), exportId)
+	if errors.Is(err, types.DataExportDoesNotExistErr) {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusNotFound)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	service.SendJsonResponse(w, h.responseData(export), http.StatusOK)
+}
+
+func (h *Handler) handleDownload(w http.ResponseWriter, r *http.Request) {
+	exportId, err := strconv.Atoi(chi.URLParam(r, "exportId"))
+	if err != nil {
+		service.SendErrorsResponse(w, []string{"Provided url param was not parsable"}, http.StatusBadRequest)
+		return
+	}
+
+	if !h.verifyDownloadToken(exportId, r.URL.Query().Get("token")) {
+		service.SendErrorsResponse(w, []string{"Download link is not valid or has expired"}, http.StatusForbidden)
+		return
+	}
+
+	archive, err := h.store.GetDataExportArchive(r.Context(), exportId)
+	if errors.Is(err, types.DataExportDoesNotExistErr) {
+		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusNotFound)
+		return
+	} else if err != nil {
+		service.SendInternalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="data-export-%d.zip"`, exportId))
+	w.Header().Set("Content-Length", strconv.Itoa(len(archive)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+}
+
+// build collects the user's data and stores the archive, or marks the export
+// as failed.
+func (h *Handler) build(ctx context.Context, export types.DataExport) {
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "data_export.build")
+	var err error
+	defer func() { tracing.End(span, err) }()
+
+	err = h.buildArchive(ctx, export)
+	if err == nil {
+		return
+	}
+
+	slog.ErrorContext(ctx, "could not build data export", "export_id", export.Id, "user_id", export.UserId, "error", err)
+	if err := h.store.FailDataExport(context.WithoutCancel(ctx), export.Id); err != nil {
+		slog.ErrorContext(ctx, "could not mark data export as failed", "export_id", export.Id, "error", err)
+	}
+}
+
+func (h *Handler) buildArchive(ctx context.Context, export types.DataExport) error {
+	data, err := h.store.GetAccountData(ctx, export.UserId)
+	if err != nil {
+		return err
+	}
+
+	archive, err := buildArchive(data, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return h.store.CompleteDataExport(ctx, export.Id, archive, time.Now().Add(h.config.TTL))
+}
+
+func (h *Handler) responseData(export types.DataExport) DataExportResponseData {
+	data := DataExportResponseData{DataExport: export}
+	if export.Status != types.DataExportReady || export.ExpiresAt == nil || export.ExpiresAt.Before(time.Now()) {
+		return data
+	}
+
+	downloadUrl := fmt.Sprintf("/api/v1/exports/%d/download?token=%s", export.Id, url.QueryEscape(h.downloadToken(export.Id, *export.ExpiresAt)))
+	data.DownloadUrl = &downloadUrl
+	data.DownloadExpiresAt = export.ExpiresAt
+
+	return data
+}
+
+// downloadToken signs the export id and the link's expiry, so links can be
+// checked without storing them.
+func (h *Handler) downloadToken(exportId int, expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expires + "." + h.sign(exportId, expires)
+}
+
+func (h *Handler) verifyDownloadToken(exportId int, token string) bool {
+	expires, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(h.sign(exportId, expires))) {
+		return false
+	}
+
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	return err == nil && time.Now().Unix() < expiresAt
+}
+
+func (h *Handler) sign(exportId int, expires string) string {
+	mac := hmac.New(sha256.New, h.config.Secret)
+	fmt.Fprintf(mac, "data-export:%d:%s", exportId, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
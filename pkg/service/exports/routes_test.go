@@ -0,0 +1,100 @@
+package exports
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+func newTestHandler() *Handler {
+	return NewHandler(nil, Config{Secret: []byte("test-data-export-secret"), TTL: 24 * time.Hour, Timeout: time.Minute})
+}
+
+func TestDownloadToken(t *testing.T) {
+	h := newTestHandler()
+	expiresAt := time.Now().Add(time.Hour)
+	token := h.downloadToken(1, expiresAt)
+
+	if !h.verifyDownloadToken(1, token) {
+		t.Fatalf("token %q of export 1 was rejected", token)
+	}
+
+	expires, signature, _ := strings.Cut(token, ".")
+	later := strconv.FormatInt(expiresAt.Add(time.Hour).Unix(), 10)
+	other := NewHandler(nil, Config{Secret: []byte("other-secret")})
+
+	tampered := []byte(signature)
+	tampered[0] ^= 1
+
+	tests := []struct {
+		name     string
+		exportId int
+		token    string
+	}{
+		{"other export", 2, token},
+		{"extended expiry", 1, later + "." + signature},
+		{"tampered signature", 1, expires + "." + string(tampered)},
+		{"no signature", 1, expires},
+		{"empty", 1, ""},
+		{"expired", 1, h.downloadToken(1, time.Now().Add(-time.Second))},
+		{"other secret", 1, other.downloadToken(1, expiresAt)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if h.verifyDownloadToken(test.exportId, test.token) {
+				t.Errorf("token %q of export %d was accepted", test.token, test.exportId)
+			}
+		})
+	}
+}
+
+func TestResponseData(t *testing.T) {
+	h := newTestHandler()
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		status    string
+		expiresAt *time.Time
+		download  bool
+	}{
+		{"ready", types.DataExportReady, &future, true},
+		{"expired", types.DataExportReady, &past, false},
+		{"ready without expiry", types.DataExportReady, nil, false},
+		{"pending", types.DataExportPending, &future, false},
+		{"failed", types.DataExportFailed, &future, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			export := types.DataExport{UserId: 7, Status: test.status, ExpiresAt: test.expiresAt}
+			export.Id = 3
+
+			data := h.responseData(export)
+			if !test.download {
+				if data.DownloadUrl != nil || data.DownloadExpiresAt != nil {
+					t.Errorf("responseData = %+v, want no download", data)
+				}
+				return
+			}
+
+			if data.DownloadUrl == nil || data.DownloadExpiresAt == nil || !data.DownloadExpiresAt.Equal(future) {
+				t.Fatalf("responseData = %+v, want a download", data)
+			}
+
+			downloadUrl, err := url.Parse(*data.DownloadUrl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if downloadUrl.Path != "/api/v1/exports/3/download" || !h.verifyDownloadToken(3, downloadUrl.Query().Get("token")) {
+				t.Errorf("download url = %q", *data.DownloadUrl)
+			}
+		})
+	}
+}
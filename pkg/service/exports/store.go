@@ -0,0 +1,289 @@
+package exports
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/lib/pq"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(connection *db.DbConnection) *Store {
+	return &Store{db: connection.DB}
+}
+
+const dataExportFields = `id, user_id, status, size_bytes, created_at, completed_at, expires_at`
+
+func scanDataExportRow(row db.Scannable) (types.DataExport, error) {
+	var export types.DataExport
+	err := row.Scan(
+		&export.Id,
+		&export.UserId,
+		&export.Status,
+		&export.SizeBytes,
+		&export.CreatedAt,
+		&export.CompletedAt,
+		&export.ExpiresAt,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.DataExport{}, types.DataExportDoesNotExistErr
+	}
+	if err != nil {
+		return types.DataExport{}, err
+	}
+
+	return export, nil
+}
+
+// CreateDataExport also fails pending exports older than staleAfter, whose
+// build was interrupted by a restart, and removes archives that expired.
+func (s *Store) CreateDataExport(ctx context.Context, userId int, staleAfter time.Duration) (export types.DataExport, created bool, err error) {
+	query := `
+        INSERT INTO data_exports (user_id)
+        VALUES ($1)
+        RETURNING ` + dataExportFields
+
+	ctx, span := db.StartQuerySpan(ctx, "data_exports.insert", query)
+	defer func() { tracing.End(span, err) }()
+
+	transaction, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return types.DataExport{}, false, err
+	}
+	defer transaction.Rollback()
+
+	// Serializes concurrent requests of the same user.
+	_, err = transaction.ExecContext(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userId)
+	if err != nil {
+		return types.DataExport{}, false, err
+	}
+
+	_, err = transaction.ExecContext(ctx, `
+        UPDATE data_exports SET status = 'failed', completed_at = now()
+        WHERE user_id = $1 AND status = 'pending' AND created_at < now() - make_interval(secs => $2)`,
+		userId, staleAfter.Seconds(),
+	)
+	if err != nil {
+		return types.DataExport{}, false, err
+	}
+
+	_, err = transaction.ExecContext(ctx, `DELETE FROM data_exports WHERE expires_at < now()`)
+	if err != nil {
+		return types.DataExport{}, false, err
+	}
+
+	export, err = scanDataExportRow(transaction.QueryRowContext(ctx, `
+        SELECT `+dataExportFields+` FROM data_exports
+        WHERE user_id = $1 AND status = 'pending'`,
+		userId,
+	))
+	if err == nil {
+		return export, false, transaction.Commit()
+	}
+	if !errors.Is(err, types.DataExportDoesNotExistErr) {
+		return types.DataExport{}, false, err
+	}
+
+	export, err = scanDataExportRow(transaction.QueryRowContext(ctx, query, userId))
+	if err != nil {
+		return types.DataExport{}, false, err
+	}
+
+	return export, true, transaction.Commit()
+}
+
+func (s *Store) GetDataExport(ctx context.Context, userId int, id int) (export types.DataExport, err error) {
+	query := `SELECT ` + dataExportFields + ` FROM data_exports WHERE id = $1 AND user_id = $2`
+
+	ctx, span := db.StartQuerySpan(ctx, "data_exports.select", query)
+	defer func() {
+		if errors.Is(err, types.DataExportDoesNotExistErr) {
+			tracing.End(span, nil)
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	return scanDataExportRow(s.db.QueryRowContext(ctx, query, id, userId))
+}
+
+func (s *Store) CompleteDataExport(ctx context.Context, id int, archive []byte, expiresAt time.Time) (err error) {
+	query := `
+        UPDATE data_exports
+        SET status = 'ready', archive = $2, size_bytes = $3, completed_at = now(), expires_at = $4
+        WHERE id = $1 AND status = 'pending'`
+
+	ctx, span := db.StartQuerySpan(ctx, "data_exports.complete", query)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.db.ExecContext(ctx, query, id, archive, len(archive), expiresAt)
+	return err
+}
+
+func (s *Store) FailDataExport(ctx context.Context, id int) (err error) {
+	query := `
+        UPDATE data_exports SET status = 'failed', completed_at = now()
+        WHERE id = $1 AND status = 'pending'`
+
+	ctx, span := db.StartQuerySpan(ctx, "data_exports.fail", query)
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (s *Store) GetDataExportArchive(ctx context.Context, id int) (archive []byte, err error) {
+	query := `
+        SELECT archive FROM data_exports
+        WHERE id = $1 AND status = 'ready' AND expires_at > now()`
+
+	ctx, span := db.StartQuerySpan(ctx, "data_exports.select_archive", query)
+	defer func() {
+		if errors.Is(err, types.DataExportDoesNotExistErr) {
+			tracing.End(span, nil)
+			return
+		}
+		tracing.End(span, err)
+	}()
+
+	err = s.db.QueryRowContext(ctx, query, id).Scan(&archive)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, types.DataExportDoesNotExistErr
+	}
+
+	return archive, err
+}
+
+// GetAccountData reads every record of the user in one read only
+// transaction, so the export is a consistent snapshot.
+func (s *Store) GetAccountData(ctx context.Context, userId int) (data types.AccountData, err error) {
+	ctx, span := tracing.Start(ctx, "data_exports.collect")
+	defer func() { tracing.End(span, err) }()
+
+	transaction, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return types.AccountData{}, err
+	}
+	defer transaction.Rollback()
+
+	err = transaction.QueryRowContext(ctx, `
+        SELECT id, first_name, last_name, email, verified_at, role, disabled_at, created_at, updated_at
+        FROM users WHERE id = $1`,
+		userId,
+	).Scan(
+		&data.User.Id,
+		&data.User.FirstName,
+		&data.User.LastName,
+		&data.User.Email,
+		&data.User.VerifiedAt,
+		&data.User.Role,
+		&data.User.DisabledAt,
+		&data.User.CreatedAt,
+		&data.User.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.AccountData{}, types.UserDoesNotExistErr
+	}
+	if err != nil {
+		return types.AccountData{}, err
+	}
+
+	data.Resumes, err = queryAll(ctx, transaction, `
+        SELECT id, user_id, name, note, created_at, updated_at
+        FROM resumes WHERE user_id = $1 ORDER BY id`,
+		userId,
+		func(row db.Scannable) (resume types.Resume, err error) {
+			err = row.Scan(&resume.Id, &resume.UserId, &resume.Name, &resume.Note, &resume.CreatedAt, &resume.UpdatedAt)
+			return resume, err
+		},
+	)
+	if err != nil {
+		return types.AccountData{}, err
+	}
+
+	data.Identities, err = queryAll(ctx, transaction, `
+        SELECT id, user_id, provider, subject, email, last_login_at, created_at
+        FROM identities WHERE user_id = $1 ORDER BY id`,
+		userId,
+		func(row db.Scannable) (identity types.Identity, err error) {
+			err = row.Scan(&identity.Id, &identity.UserId, &identity.Provider, &identity.Subject,
+				&identity.Email, &identity.LastLoginAt, &identity.CreatedAt)
+			return identity, err
+		},
+	)
+	if err != nil {
+		return types.AccountData{}, err
+	}
+
+	data.ApiKeys, err = queryAll(ctx, transaction, `
+        SELECT id, user_id, name, prefix, scopes, last_used_at, expires_at, created_at
+        FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL ORDER BY id`,
+		userId,
+		func(row db.Scannable) (key types.ApiKey, err error) {
+			err = row.Scan(&key.Id, &key.UserId, &key.Name, &key.Prefix, pq.Array(&key.Scopes),
+				&key.LastUsedAt, &key.ExpiresAt, &key.CreatedAt)
+			return key, err
+		},
+	)
+	if err != nil {
+		return types.AccountData{}, err
+	}
+
+	data.Sessions, err = queryAll(ctx, transaction, `
+        SELECT id, user_agent, ip_address, created_at, last_seen_at, expires_at
+        FROM sessions WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now() ORDER BY id`,
+		userId,
+		func(row db.Scannable) (session types.Session, err error) {
+			err = row.Scan(&session.Id, &session.UserAgent, &session.IpAddress,
+				&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt)
+			return session, err
+		},
+	)
+	if err != nil {
+		return types.AccountData{}, err
+	}
+
+	data.Passkeys, err = queryAll(ctx, transaction, `
+        SELECT id, name, backup_eligible, backed_up, last_used_at, created_at
+        FROM passkeys WHERE user_id = $1 ORDER BY id`,
+		userId,
+		func(row db.Scannable) (passkey types.Passkey, err error) {
+			err = row.Scan(&passkey.Id, &passkey.Name, &passkey.BackupEligible, &passkey.BackedUp,
+				&passkey.LastUsedAt, &passkey.CreatedAt)
+			return passkey, err
+		},
+	)
+	if err != nil {
+		return types.AccountData{}, err
+	}
+
+	return data, transaction.Commit()
+}
+
+func queryAll[T any](ctx context.Context, transaction *sql.Tx, query string, userId int, scan func(db.Scannable) (T, error)) ([]T, error) {
+	rows, err := transaction.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]T, 0)
+	for rows.Next() {
+		record, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
@@ -0,0 +1,15 @@
+package exports
+
+import (
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+type DataExportResponseData struct {
+	types.DataExport
+	// DownloadUrl is set once the archive is ready. It works without
+	// authentication until DownloadExpiresAt.
+	DownloadUrl       *string    `json:"download_url" openapi:"nullable"`
+	DownloadExpiresAt *time.Time `json:"download_expires_at" openapi:"nullable"`
+}
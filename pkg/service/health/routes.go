@@ -0,0 +1,111 @@
+package health
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/go-chi/chi/v5"
+)
+
+const checkTimeout = 2 * time.Second
+
+type Handler struct {
+	db    *db.DbConnection
+	ready atomic.Bool
+}
+
+func NewHandler(connection *db.DbConnection) *Handler {
+	return &Handler{db: connection}
+}
+
+// SetReady marks the server as able (or no longer able) to accept traffic.
+// It is false while the server is starting up and after shutdown begins.
+func (h *Handler) SetReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+func (h *Handler) AddRoutes(r chi.Router) {
+	r.Get("/healthz", h.handleLiveness)
+	r.Get("/readyz", h.handleReadiness)
+}
+
+type Check struct {
+	Status  string   `json:"status"`
+	Error   string   `json:"error,omitempty"`
+	Pending []string `json:"pending,omitempty"`
+}
+
+type ReadinessResponseData struct {
+	Status string           `json:"status"`
+	Checks map[string]Check `json:"checks"`
+}
+
+type LivenessResponseData struct {
+	Status string `json:"status"`
+}
+
+func (h *Handler) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	service.SendJsonResponse(w, LivenessResponseData{Status: "ok"}, http.StatusOK)
+}
+
+func (h *Handler) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	data := ReadinessResponseData{
+		Status: "ok",
+		Checks: map[string]Check{
+			"server":     h.checkServer(),
+			"database":   h.checkDatabase(ctx),
+			"migrations": h.checkMigrations(ctx),
+		},
+	}
+
+	statusCode := http.StatusOK
+	for _, c := range data.Checks {
+		if c.Status != "ok" {
+			data.Status = "unavailable"
+			statusCode = http.StatusServiceUnavailable
+		}
+	}
+
+	service.SendJsonResponse(w, data, statusCode)
+}
+
+func (h *Handler) checkServer() Check {
+	if !h.ready.Load() {
+		return Check{Status: "unavailable", Error: "server is starting up or shutting down"}
+	}
+
+	return Check{Status: "ok"}
+}
+
+// checkDatabase and checkMigrations only log why they failed, as the
+// errors can name hosts, users and tables.
+func (h *Handler) checkDatabase(ctx context.Context) Check {
+	if err := h.db.DB.PingContext(ctx); err != nil {
+		slog.ErrorContext(ctx, "readiness check failed", "check", "database", "error", err)
+		return Check{Status: "unavailable"}
+	}
+
+	return Check{Status: "ok"}
+}
+
+func (h *Handler) checkMigrations(ctx context.Context) Check {
+	pending, err := h.db.PendingMigrations(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "readiness check failed", "check", "migrations", "error", err)
+		return Check{Status: "unavailable"}
+	}
+
+	if len(pending) > 0 {
+		return Check{Status: "unavailable", Error: "database migrations are not up to date", Pending: pending}
+	}
+
+	return Check{Status: "ok"}
+}
@@ -0,0 +1,87 @@
+// Package jwks publishes the public keys access tokens are signed with, so
+// other services can verify tokens without being able to create them.
+package jwks
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// cacheMaxAge is how long clients may cache the key set, in seconds. Keys
+// are published JWT_KEY_PUBLISH_AHEAD before they sign tokens, which has to
+// be longer.
+const cacheMaxAge = "300"
+
+type Handler struct {
+	keys *service.KeySet
+}
+
+// NewHandler serves an empty key set when keys is nil, which is the case
+// while tokens are signed with a shared secret.
+func NewHandler(keys *service.KeySet) *Handler {
+	return &Handler{keys: keys}
+}
+
+func (h *Handler) AddRoutes(r chi.Router) {
+	r.Get("/.well-known/jwks.json", h.handleJwks)
+}
+
+// Jwk is a public key as described in RFC 7517, with the RSA (RFC 7518) or
+// OKP (RFC 8037) parameters of its key type.
+type Jwk struct {
+	Kty string `json:"kty" openapi:"enum=RSA|OKP"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg" openapi:"enum=RS256|EdDSA"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type KeySetResponse struct {
+	Keys []Jwk `json:"keys"`
+}
+
+// handleJwks responds with a bare JWK set, without the JsonResponse
+// envelope, as JWT libraries expect it.
+func (h *Handler) handleJwks(w http.ResponseWriter, r *http.Request) {
+	response := KeySetResponse{Keys: make([]Jwk, 0)}
+
+	if h.keys != nil {
+		keys, err := h.keys.PublicKeys(r.Context())
+		if err != nil {
+			service.SendInternalServerError(w, r, err)
+			return
+		}
+
+		for _, key := range keys {
+			jwk := Jwk{Kid: key.Kid, Use: "sig", Alg: key.Algorithm}
+			switch public := key.PublicKey.(type) {
+			case *rsa.PublicKey:
+				jwk.Kty = "RSA"
+				jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+				jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+			case ed25519.PublicKey:
+				jwk.Kty = "OKP"
+				jwk.Crv = "Ed25519"
+				jwk.X = base64.RawURLEncoding.EncodeToString(public)
+			default:
+				continue
+			}
+			response.Keys = append(response.Keys, jwk)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age="+cacheMaxAge)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,134 @@
+package jwks
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+type memorySigningKeyStore struct {
+	keys []types.SigningKey
+}
+
+func (s *memorySigningKeyStore) GetSigningKeys(ctx context.Context) ([]types.SigningKey, error) {
+	return s.keys, nil
+}
+
+func (s *memorySigningKeyStore) CreateSigningKey(ctx context.Context, key types.SigningKey) (bool, error) {
+	s.keys = append(s.keys, key)
+	return true, nil
+}
+
+func getJwks(t *testing.T, keys *service.KeySet) KeySetResponse {
+	t.Helper()
+
+	r := chi.NewRouter()
+	NewHandler(keys).AddRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if cacheControl := w.Header().Get("Cache-Control"); cacheControl != "public, max-age="+cacheMaxAge {
+		t.Errorf("Cache-Control = %q", cacheControl)
+	}
+
+	var response KeySetResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	return response
+}
+
+func newTestKeySet(t *testing.T, algorithm string) (*service.KeySet, service.VerificationKey) {
+	t.Helper()
+
+	keys, err := service.NewKeySet(&memorySigningKeyStore{}, service.KeySetConfig{
+		Algorithm:        algorithm,
+		EncryptionSecret: []byte("test-encryption-secret"),
+		RotationInterval: time.Hour,
+		PublishAhead:     10 * time.Minute,
+		VerifyFor:        time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public, err := keys.PublicKeys(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(public) != 1 {
+		t.Fatalf("key set has %d keys, want 1", len(public))
+	}
+
+	return keys, public[0]
+}
+
+func TestJwksWithoutKeys(t *testing.T) {
+	response := getJwks(t, nil)
+	if response.Keys == nil || len(response.Keys) != 0 {
+		t.Errorf("keys = %#v, want an empty list", response.Keys)
+	}
+}
+
+func TestJwksEdDSA(t *testing.T) {
+	keys, key := newTestKeySet(t, service.JwtAlgorithmEdDSA)
+
+	response := getJwks(t, keys)
+	if len(response.Keys) != 1 {
+		t.Fatalf("keys = %+v", response.Keys)
+	}
+
+	jwk := response.Keys[0]
+	if jwk.Kty != "OKP" || jwk.Crv != "Ed25519" || jwk.Kid != key.Kid || jwk.Alg != "EdDSA" || jwk.Use != "sig" {
+		t.Errorf("jwk = %+v", jwk)
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.PublicKey.(ed25519.PublicKey).Equal(ed25519.PublicKey(x)) {
+		t.Error("x is not the public key")
+	}
+}
+
+func TestJwksRS256(t *testing.T) {
+	keys, key := newTestKeySet(t, service.JwtAlgorithmRS256)
+
+	response := getJwks(t, keys)
+	if len(response.Keys) != 1 {
+		t.Fatalf("keys = %+v", response.Keys)
+	}
+
+	jwk := response.Keys[0]
+	if jwk.Kty != "RSA" || jwk.Kid != key.Kid || jwk.Alg != "RS256" || jwk.Use != "sig" {
+		t.Errorf("jwk = %+v", jwk)
+	}
+	if jwk.E != "AQAB" {
+		t.Errorf("e = %q, want AQAB", jwk.E)
+	}
+
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if new(big.Int).SetBytes(n).Cmp(key.PublicKey.(*rsa.PublicKey).N) != 0 {
+		t.Error("n is not the modulus of the public key")
+	}
+}
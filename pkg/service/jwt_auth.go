@@ -1,74 +1,334 @@
 package service
 
 import (
-	"log"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/tracing"
 	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
 	"github.com/golang-jwt/jwt/v4"
 )
 
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
 var JwtClient JwtAuth
 
+func init() {
+	// Millisecond precision for iat lets tokens issued right after a user
+	// revoked all tokens be told apart from the revoked ones.
+	jwt.TimePrecision = time.Millisecond
+}
+
+// TokenRevocations is consulted for every verified token, see
+// RevocationCache.
+type TokenRevocations interface {
+	IsTokenRevoked(ctx context.Context, jti string, expiresAt time.Time) (bool, error)
+	IsSessionRevoked(ctx context.Context, sessionId int, expiresAt time.Time) (bool, error)
+	TokensValidAfter(ctx context.Context, userId int) (time.Time, error)
+}
+
+// JwtAuth signs access tokens with the rotating keys of a KeySet, selected
+// with JWT_ALGORITHM "EdDSA" (the default) or "RS256". With "HS256" tokens
+// are signed with JWT_SECRET instead, which cannot be rotated and lets
+// anyone who verifies tokens also create them. Tokens without a kid are
+// HS256 tokens. After switching to asymmetric keys they are only accepted
+// until JWT_ACCEPT_LEGACY_HS256_UNTIL, so the switch does not log everyone
+// out but a leaked JWT_SECRET cannot be used to forge tokens forever.
 type JwtAuth struct {
-	secret []byte
+	secret          []byte
+	legacyUntil     time.Time
+	keyConfig       KeySetConfig
+	keys            *KeySet
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	revocations     TokenRevocations
 }
 
 func (a *JwtAuth) InitJwtAuth() error {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		log.Fatal("JWT_SECRET not provided as an env variable")
+	var err error
+	a.accessTokenTTL, err = DurationFromEnv("ACCESS_TOKEN_TTL", defaultAccessTokenTTL)
+	if err != nil {
+		return err
+	}
+	a.refreshTokenTTL, err = DurationFromEnv("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	a.secret = nil
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		a.secret = []byte(secret)
+	}
+
+	a.keyConfig.Algorithm = os.Getenv("JWT_ALGORITHM")
+	switch a.keyConfig.Algorithm {
+	case "":
+		a.keyConfig.Algorithm = JwtAlgorithmEdDSA
+	case JwtAlgorithmHS256:
+		if a.secret == nil {
+			return errors.New("JWT_SECRET not provided as an env variable")
+		}
+		return nil
+	case JwtAlgorithmRS256, JwtAlgorithmEdDSA:
+	default:
+		return fmt.Errorf("unknown JWT_ALGORITHM %q", a.keyConfig.Algorithm)
+	}
+
+	a.legacyUntil = time.Time{}
+	if until := os.Getenv("JWT_ACCEPT_LEGACY_HS256_UNTIL"); until != "" {
+		a.legacyUntil, err = time.Parse(time.RFC3339, until)
+		if err != nil {
+			return fmt.Errorf("JWT_ACCEPT_LEGACY_HS256_UNTIL is not a valid RFC 3339 time: %w", err)
+		}
+		if a.secret == nil {
+			return errors.New("JWT_SECRET is needed to verify tokens while JWT_ACCEPT_LEGACY_HS256_UNTIL is set")
+		}
+	}
+
+	encryptionSecret := os.Getenv("JWT_KEY_ENCRYPTION_SECRET")
+	if encryptionSecret == "" {
+		return errors.New("JWT_KEY_ENCRYPTION_SECRET not provided as an env variable")
+	}
+	a.keyConfig.EncryptionSecret = []byte(encryptionSecret)
+
+	a.keyConfig.RotationInterval, err = DurationFromEnv("JWT_KEY_ROTATION_INTERVAL", 30*24*time.Hour)
+	if err != nil {
+		return err
+	}
+	a.keyConfig.PublishAhead, err = DurationFromEnv("JWT_KEY_PUBLISH_AHEAD", time.Hour)
+	if err != nil {
+		return err
+	}
+	if a.keyConfig.PublishAhead >= a.keyConfig.RotationInterval {
+		return errors.New("JWT_KEY_PUBLISH_AHEAD must be shorter than JWT_KEY_ROTATION_INTERVAL")
 	}
-	a.secret = []byte(secret)
+	a.keyConfig.VerifyFor = a.accessTokenTTL
 
 	return nil
 }
 
-func (a *JwtAuth) CreateToken(userId int) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		UserIdKey: userId,
-	})
+func (a *JwtAuth) SetRevocations(revocations TokenRevocations) {
+	a.revocations = revocations
+}
+
+// SetKeyStore stores the signing keys in store. It does nothing when tokens
+// are signed with JWT_SECRET.
+func (a *JwtAuth) SetKeyStore(store db.SigningKeyStore) error {
+	if a.keyConfig.Algorithm == JwtAlgorithmHS256 {
+		return nil
+	}
 
-	signed, err := token.SignedString(a.secret)
+	keys, err := NewKeySet(store, a.keyConfig)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	return signed, nil
+	a.keys = keys
+	return nil
+}
+
+// Keys returns the key set signing tokens, nil when they are signed with
+// JWT_SECRET.
+func (a *JwtAuth) Keys() *KeySet {
+	return a.keys
+}
+
+func (a *JwtAuth) AccessTokenTTL() time.Duration {
+	return a.accessTokenTTL
 }
 
-type customClaims struct {
-	UserId int `json:"user_id"`
+func (a *JwtAuth) RefreshTokenTTL() time.Duration {
+	return a.refreshTokenTTL
 }
 
-func (c *customClaims) Valid() error {
+type Claims struct {
+	UserId        int    `json:"user_id"`
+	EmailVerified bool   `json:"email_verified"`
+	Role          string `json:"role"`
+	// SessionId is the session the token was issued to, zero for tokens
+	// issued before sessions were tracked.
+	SessionId int `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func (c *Claims) Valid() error {
 	if c.UserId == 0 {
 		return types.UserIdNotProvidedErr
 	}
 
-	return nil
+	return c.RegisteredClaims.Valid()
+}
+
+// CreateToken issues a short lived access token for the user's session.
+func (a *JwtAuth) CreateToken(ctx context.Context, user types.User, sessionId int) (signed string, err error) {
+	_, span := tracing.Start(ctx, "jwt.create")
+	defer func() { tracing.End(span, err) }()
+
+	now := time.Now()
+	claims := &Claims{
+		UserId:        user.Id,
+		EmailVerified: user.VerifiedAt != nil,
+		Role:          user.Role,
+		SessionId:     sessionId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newTokenId(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(a.accessTokenTTL)),
+		},
+	}
+
+	if a.keys == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.secret)
+	}
+
+	key, err := a.keys.signingKey(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.Kid
+
+	return token.SignedString(key.private)
 }
 
 func (a *JwtAuth) VerifyToken(r *http.Request) (int, error) {
+	claims, err := a.ParseToken(r)
+	if err != nil {
+		return 0, err
+	}
+
+	return claims.UserId, nil
+}
+
+// ParseToken verifies the bearer token of the request and returns its claims.
+func (a *JwtAuth) ParseToken(r *http.Request) (claims *Claims, err error) {
+	_, span := tracing.Start(r.Context(), "jwt.verify")
+	defer func() { tracing.End(span, err) }()
+
 	authHeader := r.Header.Get("authorization")
 	if authHeader == "" {
-		return 0, types.MissingRequiredHeaderErr
+		return nil, types.MissingRequiredHeaderErr
 	}
 
 	isValid := strings.HasPrefix(authHeader, "Bearer ")
 	if !isValid {
-		return 0, types.WronglyFormattedAuthHeaderErr
+		return nil, types.WronglyFormattedAuthHeaderErr
 	}
 
 	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-	token, err := jwt.ParseWithClaims(tokenString, &customClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return a.secret, nil
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		return a.verificationKey(r.Context(), token)
 	})
 
-	claims := token.Claims.(*customClaims)
+	if err != nil {
+		return nil, err
+	}
+
+	claims = token.Claims.(*Claims)
+	if err := a.checkRevocation(r.Context(), claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (a *JwtAuth) verificationKey(ctx context.Context, token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if token.Method != jwt.SigningMethodHS256 || !a.acceptsHS256() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return a.secret, nil
+	}
+
+	if a.keys == nil {
+		return nil, types.UnknownSigningKeyErr
+	}
+
+	key, err := a.keys.verificationKey(ctx, kid)
+	if errors.Is(err, types.UnknownSigningKeyErr) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", types.SigningKeyLookupFailedErr, err)
+	}
+
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	return key.public, nil
+}
+
+// acceptsHS256 reports whether tokens signed with JWT_SECRET are valid,
+// which they are when they are the tokens issued or, after switching to
+// asymmetric keys, until JWT_ACCEPT_LEGACY_HS256_UNTIL.
+func (a *JwtAuth) acceptsHS256() bool {
+	if a.secret == nil {
+		return false
+	}
+
+	return a.keyConfig.Algorithm == JwtAlgorithmHS256 || time.Now().Before(a.legacyUntil)
+}
+
+func (a *JwtAuth) checkRevocation(ctx context.Context, claims *Claims) error {
+	if a.revocations == nil {
+		return nil
+	}
+
+	if claims.ID == "" || claims.IssuedAt == nil || claims.ExpiresAt == nil {
+		return types.TokenRevokedErr
+	}
+
+	revoked, err := a.revocations.IsTokenRevoked(ctx, claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		return fmt.Errorf("%w: %w", types.RevocationCheckFailedErr, err)
+	}
+	if revoked {
+		return types.TokenRevokedErr
+	}
+
+	if claims.SessionId != 0 {
+		revoked, err := a.revocations.IsSessionRevoked(ctx, claims.SessionId, claims.ExpiresAt.Time)
+		if err != nil {
+			return fmt.Errorf("%w: %w", types.RevocationCheckFailedErr, err)
+		}
+		if revoked {
+			return types.TokenRevokedErr
+		}
+	}
+
+	validAfter, err := a.revocations.TokensValidAfter(ctx, claims.UserId)
+	if errors.Is(err, types.UserDoesNotExistErr) {
+		return types.TokenRevokedErr
+	}
+	if errors.Is(err, types.UserDisabledErr) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", types.RevocationCheckFailedErr, err)
+	}
+	if claims.IssuedAt.Time.Before(validAfter) {
+		return types.TokenRevokedErr
+	}
 
-	return claims.UserId, err
+	return nil
+}
 
+func newTokenId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
 }
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func newTestJwtAuth(t *testing.T, env map[string]string) (*JwtAuth, error) {
+	t.Helper()
+
+	for _, name := range []string{"JWT_SECRET", "JWT_ALGORITHM", "JWT_KEY_ENCRYPTION_SECRET", "JWT_ACCEPT_LEGACY_HS256_UNTIL"} {
+		t.Setenv(name, env[name])
+	}
+
+	auth := &JwtAuth{}
+	if err := auth.InitJwtAuth(); err != nil {
+		return nil, err
+	}
+	if err := auth.SetKeyStore(&memorySigningKeyStore{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return auth, nil
+}
+
+func parseTestToken(auth *JwtAuth, token string) (*Claims, error) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return auth.ParseToken(r)
+}
+
+// legacyToken is a token signed with JWT_SECRET before keys were rotated.
+func legacyToken(t *testing.T, secret string) string {
+	t.Helper()
+
+	now := time.Now()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+		UserId: 7,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newTokenId(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestJwtAuthRotatingKeys(t *testing.T) {
+	auth, err := newTestJwtAuth(t, map[string]string{
+		"JWT_ALGORITHM":             JwtAlgorithmEdDSA,
+		"JWT_KEY_ENCRYPTION_SECRET": "test-encryption-secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := types.User{}
+	user.Id = 7
+	token, err := auth.CreateToken(context.Background(), user, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := parseTestToken(auth, token)
+	if err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+	if claims.UserId != 7 || claims.SessionId != 3 {
+		t.Errorf("claims = %+v", claims)
+	}
+}
+
+func TestJwtAuthLegacyHS256(t *testing.T) {
+	env := map[string]string{
+		"JWT_SECRET":                "test-jwt-secret",
+		"JWT_ALGORITHM":             JwtAlgorithmEdDSA,
+		"JWT_KEY_ENCRYPTION_SECRET": "test-encryption-secret",
+	}
+
+	for name, test := range map[string]struct {
+		until  string
+		secret string
+		valid  bool
+	}{
+		"not accepted":         {until: "", secret: env["JWT_SECRET"], valid: false},
+		"accepted until later": {until: time.Now().Add(time.Hour).Format(time.RFC3339), secret: env["JWT_SECRET"], valid: true},
+		"no longer accepted":   {until: time.Now().Add(-time.Hour).Format(time.RFC3339), secret: env["JWT_SECRET"], valid: false},
+		"other secret":         {until: time.Now().Add(time.Hour).Format(time.RFC3339), secret: "other-secret", valid: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			env["JWT_ACCEPT_LEGACY_HS256_UNTIL"] = test.until
+			auth, err := newTestJwtAuth(t, env)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = parseTestToken(auth, legacyToken(t, test.secret))
+			if valid := err == nil; valid != test.valid {
+				t.Errorf("token valid = %v, want %v (error %v)", valid, test.valid, err)
+			}
+		})
+	}
+}
+
+func TestJwtAuthHS256(t *testing.T) {
+	auth, err := newTestJwtAuth(t, map[string]string{
+		"JWT_SECRET":    "test-jwt-secret",
+		"JWT_ALGORITHM": JwtAlgorithmHS256,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := types.User{}
+	user.Id = 7
+	token, err := auth.CreateToken(context.Background(), user, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parseTestToken(auth, token); err != nil {
+		t.Errorf("ParseToken: %v", err)
+	}
+	if _, err := parseTestToken(auth, legacyToken(t, "other-secret")); err == nil {
+		t.Error("accepted a token signed with another secret")
+	}
+}
+
+func TestInitJwtAuthConfig(t *testing.T) {
+	for name, env := range map[string]map[string]string{
+		"HS256 without JWT_SECRET": {
+			"JWT_ALGORITHM": JwtAlgorithmHS256,
+		},
+		"no encryption secret": {
+			"JWT_SECRET":    "test-jwt-secret",
+			"JWT_ALGORITHM": JwtAlgorithmEdDSA,
+		},
+		"legacy tokens without JWT_SECRET": {
+			"JWT_ALGORITHM":                 JwtAlgorithmEdDSA,
+			"JWT_KEY_ENCRYPTION_SECRET":     "test-encryption-secret",
+			"JWT_ACCEPT_LEGACY_HS256_UNTIL": time.Now().Add(time.Hour).Format(time.RFC3339),
+		},
+		"legacy tokens until an invalid time": {
+			"JWT_SECRET":                    "test-jwt-secret",
+			"JWT_ALGORITHM":                 JwtAlgorithmEdDSA,
+			"JWT_KEY_ENCRYPTION_SECRET":     "test-encryption-secret",
+			"JWT_ACCEPT_LEGACY_HS256_UNTIL": "tomorrow",
+		},
+		"unknown algorithm": {
+			"JWT_ALGORITHM": "none",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := newTestJwtAuth(t, env); err == nil {
+				t.Error("InitJwtAuth succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	JwtAlgorithmHS256 = "HS256"
+	JwtAlgorithmRS256 = "RS256"
+	JwtAlgorithmEdDSA = "EdDSA"
+
+	// keyRefreshInterval is how often keys created by other replicas are
+	// picked up.
+	keyRefreshInterval = time.Minute
+	// unknownKidRefreshInterval limits how often tokens with an unknown kid
+	// reload the keys.
+	unknownKidRefreshInterval = 10 * time.Second
+)
+
+type KeySetConfig struct {
+	Algorithm string
+	// EncryptionSecret encrypts the private keys in the store.
+	EncryptionSecret []byte
+	// RotationInterval is how long a key signs tokens.
+	RotationInterval time.Duration
+	// PublishAhead is how long a key is in the JWKS before it signs tokens.
+	// It has to be longer than services verifying tokens cache the JWKS.
+	PublishAhead time.Duration
+	// VerifyFor is how long a retired key still verifies tokens, at least
+	// the lifetime of access tokens.
+	VerifyFor time.Duration
+}
+
+// VerificationKey is the public part of a signing key, as published in the
+// JWKS.
+type VerificationKey struct {
+	Kid       string
+	Algorithm string
+	PublicKey crypto.PublicKey
+}
+
+type parsedKey struct {
+	types.SigningKey
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+func (k parsedKey) method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// KeySet signs access tokens with asymmetric keys shared by all replicas
+// through the store. Keys rotate every RotationInterval. The next key is
+// created PublishAhead before the current one retires, so it is in the
+// JWKS of other services before it signs anything. Whichever replica notices
+// first creates it, the store keeps a single key per activation time.
+type KeySet struct {
+	store  db.SigningKeyStore
+	config KeySetConfig
+	aead   cipher.AEAD
+
+	mu       sync.Mutex
+	keys     []parsedKey
+	loadedAt time.Time
+}
+
+func NewKeySet(store db.SigningKeyStore, config KeySetConfig) (*KeySet, error) {
+	switch config.Algorithm {
+	case JwtAlgorithmRS256, JwtAlgorithmEdDSA:
+	default:
+		return nil, fmt.Errorf("unsupported signing key algorithm %q", config.Algorithm)
+	}
+
+	encryptionKey := sha256.Sum256(config.EncryptionSecret)
+	block, err := aes.NewCipher(encryptionKey[:])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeySet{store: store, config: config, aead: aead}, nil
+}
+
+// signingKey returns the key that signs tokens now.
+func (k *KeySet) signingKey(ctx context.Context) (parsedKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.refresh(ctx, keyRefreshInterval); err != nil {
+		return parsedKey{}, err
+	}
+
+	key, ok := k.activeKey(time.Now())
+	if !ok {
+		return parsedKey{}, errors.New("no signing key is active")
+	}
+
+	return key, nil
+}
+
+// verificationKey returns the key with the kid. Keys created by another
+// replica since the last refresh are loaded on demand.
+func (k *KeySet) verificationKey(ctx context.Context, kid string) (parsedKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for _, maxAge := range []time.Duration{keyRefreshInterval, unknownKidRefreshInterval} {
+		if err := k.refresh(ctx, maxAge); err != nil {
+			return parsedKey{}, err
+		}
+
+		for _, key := range k.keys {
+			if key.Kid == kid {
+				return key, nil
+			}
+		}
+	}
+
+	return parsedKey{}, types.UnknownSigningKeyErr
+}
+
+// PublicKeys returns every key that is published, including the next key
+// and retired keys that still verify tokens.
+func (k *KeySet) PublicKeys(ctx context.Context) ([]VerificationKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.refresh(ctx, keyRefreshInterval); err != nil {
+		return nil, err
+	}
+
+	keys := make([]VerificationKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		keys = append(keys, VerificationKey{Kid: key.Kid, Algorithm: key.Algorithm, PublicKey: key.public})
+	}
+
+	return keys, nil
+}
+
+// refresh reloads keys loaded longer than maxAge ago and creates the next
+// key when the rotation schedule asks for one.
+func (k *KeySet) refresh(ctx context.Context, maxAge time.Duration) error {
+	if time.Since(k.loadedAt) < maxAge {
+		return nil
+	}
+
+	if err := k.load(ctx); err != nil {
+		return err
+	}
+
+	activatesAt, needed := k.nextActivation(time.Now())
+	if !needed {
+		return nil
+	}
+
+	key, err := k.generate(activatesAt)
+	if err != nil {
+		return err
+	}
+
+	created, err := k.store.CreateSigningKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	if created {
+		slog.InfoContext(ctx, "created signing key", "kid", key.Kid, "algorithm", key.Algorithm, "activates_at", key.ActivatesAt)
+	}
+
+	return k.load(ctx)
+}
+
+func (k *KeySet) load(ctx context.Context) error {
+	stored, err := k.store.GetSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]parsedKey, 0, len(stored))
+	for _, key := range stored {
+		parsed, err := k.parse(key)
+		if err != nil {
+			// Most likely the encryption secret changed. Tokens of the key
+			// stop working and a new key is created in its place.
+			slog.WarnContext(ctx, "could not read signing key", "kid", key.Kid, "error", err)
+			continue
+		}
+		keys = append(keys, parsed)
+	}
+
+	k.keys = keys
+	k.loadedAt = time.Now()
+	return nil
+}
+
+// activeKey is the most recently activated key of the configured algorithm.
+// It keeps signing past its retirement until a successor exists.
+func (k *KeySet) activeKey(now time.Time) (parsedKey, bool) {
+	var active parsedKey
+	var ok bool
+	for _, key := range k.keys {
+		if key.Algorithm != k.config.Algorithm || key.ActivatesAt.After(now) {
+			continue
+		}
+		if !ok || key.ActivatesAt.After(active.ActivatesAt) {
+			active, ok = key, true
+		}
+	}
+
+	return active, ok
+}
+
+// nextActivation reports whether a key has to be created and when it starts
+// signing: right away when no key is active, otherwise when the active key
+// retires.
+func (k *KeySet) nextActivation(now time.Time) (time.Time, bool) {
+	active, ok := k.activeKey(now)
+	if !ok {
+		return now.Truncate(time.Second), true
+	}
+
+	for _, key := range k.keys {
+		if key.Algorithm == k.config.Algorithm && key.ActivatesAt.After(active.ActivatesAt) {
+			return time.Time{}, false
+		}
+	}
+
+	if now.Before(active.RetiresAt.Add(-k.config.PublishAhead)) {
+		return time.Time{}, false
+	}
+
+	return active.RetiresAt, true
+}
+
+func (k *KeySet) generate(activatesAt time.Time) (types.SigningKey, error) {
+	var private crypto.Signer
+	var err error
+	switch k.config.Algorithm {
+	case JwtAlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case JwtAlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		return types.SigningKey{}, err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return types.SigningKey{}, err
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return types.SigningKey{}, err
+	}
+
+	nonce := make([]byte, k.aead.NonceSize())
+	rand.Read(nonce)
+
+	kid := sha256.Sum256(publicDER)
+	retiresAt := activatesAt.Add(k.config.RotationInterval)
+
+	return types.SigningKey{
+		Kid:         base64.RawURLEncoding.EncodeToString(kid[:12]),
+		Algorithm:   k.config.Algorithm,
+		PrivateKey:  k.aead.Seal(nonce, nonce, privateDER, []byte(k.config.Algorithm)),
+		PublicKey:   publicDER,
+		ActivatesAt: activatesAt,
+		RetiresAt:   retiresAt,
+		ExpiresAt:   retiresAt.Add(k.config.VerifyFor),
+	}, nil
+}
+
+func (k *KeySet) parse(key types.SigningKey) (parsedKey, error) {
+	nonceSize := k.aead.NonceSize()
+	if len(key.PrivateKey) < nonceSize {
+		return parsedKey{}, errors.New("encrypted private key is too short")
+	}
+
+	privateDER, err := k.aead.Open(nil, key.PrivateKey[:nonceSize], key.PrivateKey[nonceSize:], []byte(key.Algorithm))
+	if err != nil {
+		return parsedKey{}, err
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(privateDER)
+	if err != nil {
+		return parsedKey{}, err
+	}
+
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return parsedKey{}, errors.New("private key cannot sign")
+	}
+
+	public, err := x509.ParsePKIXPublicKey(key.PublicKey)
+	if err != nil {
+		return parsedKey{}, err
+	}
+
+	return parsedKey{SigningKey: key, private: signer, public: public}, nil
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// memorySigningKeyStore keeps signing keys like the auth store, which only
+// returns keys that did not expire.
+type memorySigningKeyStore struct {
+	mu   sync.Mutex
+	keys []types.SigningKey
+}
+
+func (s *memorySigningKeyStore) GetSigningKeys(ctx context.Context) ([]types.SigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]types.SigningKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		if key.ExpiresAt.After(time.Now()) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (s *memorySigningKeyStore) CreateSigningKey(ctx context.Context, key types.SigningKey) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.keys {
+		if existing.Algorithm == key.Algorithm && existing.ActivatesAt.Equal(key.ActivatesAt) {
+			return false, nil
+		}
+	}
+	s.keys = append(s.keys, key)
+	return true, nil
+}
+
+var testKeySetConfig = KeySetConfig{
+	Algorithm:        JwtAlgorithmEdDSA,
+	EncryptionSecret: []byte("test-encryption-secret"),
+	RotationInterval: time.Hour,
+	PublishAhead:     10 * time.Minute,
+	VerifyFor:        2 * time.Hour,
+}
+
+func newTestKeySet(t *testing.T, store *memorySigningKeyStore, config KeySetConfig) *KeySet {
+	t.Helper()
+
+	keys, err := NewKeySet(store, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return keys
+}
+
+// storeTestKey stores a key of the set that activated at activatesAt, as if
+// created by another replica.
+func storeTestKey(t *testing.T, keys *KeySet, store *memorySigningKeyStore, activatesAt time.Time) types.SigningKey {
+	t.Helper()
+
+	key, err := keys.generate(activatesAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateSigningKey(context.Background(), key); err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func publicKids(t *testing.T, keys *KeySet) []string {
+	t.Helper()
+
+	public, err := keys.PublicKeys(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kids := make([]string, len(public))
+	for i, key := range public {
+		kids[i] = key.Kid
+	}
+
+	return kids
+}
+
+func TestNewKeySetAlgorithms(t *testing.T) {
+	for algorithm, valid := range map[string]bool{
+		JwtAlgorithmEdDSA: true,
+		JwtAlgorithmRS256: true,
+		JwtAlgorithmHS256: false,
+		"ES256":           false,
+	} {
+		config := testKeySetConfig
+		config.Algorithm = algorithm
+
+		_, err := NewKeySet(&memorySigningKeyStore{}, config)
+		if (err == nil) != valid {
+			t.Errorf("NewKeySet with %s: %v", algorithm, err)
+		}
+	}
+}
+
+func TestKeySetCreatesFirstKey(t *testing.T) {
+	for _, algorithm := range []string{JwtAlgorithmEdDSA, JwtAlgorithmRS256} {
+		t.Run(algorithm, func(t *testing.T) {
+			ctx := context.Background()
+			config := testKeySetConfig
+			config.Algorithm = algorithm
+			keys := newTestKeySet(t, &memorySigningKeyStore{}, config)
+
+			signing, err := keys.signingKey(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if signing.Algorithm != algorithm || signing.method().Alg() != algorithm {
+				t.Errorf("key algorithm = %s", signing.Algorithm)
+			}
+			if signing.ActivatesAt.After(time.Now()) || signing.RetiresAt.Sub(signing.ActivatesAt) != config.RotationInterval {
+				t.Errorf("key is active from %s until %s", signing.ActivatesAt, signing.RetiresAt)
+			}
+			if !signing.ExpiresAt.Equal(signing.RetiresAt.Add(config.VerifyFor)) {
+				t.Errorf("key expires at %s", signing.ExpiresAt)
+			}
+
+			verification, err := keys.verificationKey(ctx, signing.Kid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if verification.Kid != signing.Kid {
+				t.Errorf("verification kid = %s, want %s", verification.Kid, signing.Kid)
+			}
+
+			if kids := publicKids(t, keys); len(kids) != 1 || kids[0] != signing.Kid {
+				t.Errorf("published kids = %v", kids)
+			}
+
+			if _, err := keys.verificationKey(ctx, "unknown"); !errors.Is(err, types.UnknownSigningKeyErr) {
+				t.Errorf("unknown kid: %v", err)
+			}
+		})
+	}
+}
+
+func TestKeySetPublishesNextKeyAhead(t *testing.T) {
+	ctx := context.Background()
+	store := &memorySigningKeyStore{}
+	keys := newTestKeySet(t, store, testKeySetConfig)
+
+	// The key retires in 5 minutes, within PublishAhead.
+	current := storeTestKey(t, keys, store, time.Now().Add(-55*time.Minute).Truncate(time.Second))
+
+	signing, err := keys.signingKey(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signing.Kid != current.Kid {
+		t.Errorf("signing kid = %s, want the current key %s", signing.Kid, current.Kid)
+	}
+
+	if len(store.keys) != 2 {
+		t.Fatalf("store has %d keys, want the next key to be created", len(store.keys))
+	}
+	next := store.keys[1]
+	if !next.ActivatesAt.Equal(current.RetiresAt) {
+		t.Errorf("next key activates at %s, want %s", next.ActivatesAt, current.RetiresAt)
+	}
+
+	if kids := publicKids(t, keys); len(kids) != 2 || kids[0] != current.Kid || kids[1] != next.Kid {
+		t.Errorf("published kids = %v, want the current and the next key", kids)
+	}
+
+	// Another refresh does not create more keys.
+	keys.loadedAt = time.Time{}
+	if _, err := keys.signingKey(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.keys) != 2 {
+		t.Errorf("store has %d keys, want 2", len(store.keys))
+	}
+}
+
+func TestKeySetDoesNotRotateEarly(t *testing.T) {
+	store := &memorySigningKeyStore{}
+	keys := newTestKeySet(t, store, testKeySetConfig)
+
+	// The key retires in 30 minutes, before the next one has to be published.
+	storeTestKey(t, keys, store, time.Now().Add(-30*time.Minute).Truncate(time.Second))
+
+	if _, err := keys.signingKey(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.keys) != 1 {
+		t.Errorf("store has %d keys, want 1", len(store.keys))
+	}
+}
+
+func TestKeySetRotatesRetiredKey(t *testing.T) {
+	ctx := context.Background()
+	store := &memorySigningKeyStore{}
+	keys := newTestKeySet(t, store, testKeySetConfig)
+
+	// Nothing refreshed the keys while the key retired.
+	retired := storeTestKey(t, keys, store, time.Now().Add(-90*time.Minute).Truncate(time.Second))
+
+	signing, err := keys.signingKey(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signing.Kid == retired.Kid {
+		t.Fatal("the retired key still signs tokens")
+	}
+	if !signing.ActivatesAt.Equal(retired.RetiresAt) {
+		t.Errorf("new key activates at %s, want %s", signing.ActivatesAt, retired.RetiresAt)
+	}
+
+	// The retired key verifies tokens until it expires.
+	if _, err := keys.verificationKey(ctx, retired.Kid); err != nil {
+		t.Errorf("retired key does not verify tokens: %v", err)
+	}
+}
+
+func TestKeySetSharesKeysBetweenReplicas(t *testing.T) {
+	ctx := context.Background()
+	store := &memorySigningKeyStore{}
+	first := newTestKeySet(t, store, testKeySetConfig)
+	second := newTestKeySet(t, store, testKeySetConfig)
+
+	firstKey, err := first.signingKey(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondKey, err := second.signingKey(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if firstKey.Kid != secondKey.Kid || len(store.keys) != 1 {
+		t.Errorf("replicas sign with %s and %s, store has %d keys", firstKey.Kid, secondKey.Kid, len(store.keys))
+	}
+
+	// A key created by another replica since the last refresh is loaded
+	// when a token signed with it arrives.
+	created := storeTestKey(t, first, store, time.Now().Add(time.Hour).Truncate(time.Second))
+
+	if _, err := second.verificationKey(ctx, created.Kid); !errors.Is(err, types.UnknownSigningKeyErr) {
+		t.Errorf("keys were reloaded right after loading them: %v", err)
+	}
+
+	second.loadedAt = time.Now().Add(-unknownKidRefreshInterval)
+	if _, err := second.verificationKey(ctx, created.Kid); err != nil {
+		t.Errorf("new key was not loaded: %v", err)
+	}
+}
+
+func TestKeySetSkipsUnreadableKeys(t *testing.T) {
+	ctx := context.Background()
+	store := &memorySigningKeyStore{}
+	old := storeTestKey(t, newTestKeySet(t, store, testKeySetConfig), store, time.Now().Add(-time.Minute).Truncate(time.Second))
+
+	config := testKeySetConfig
+	config.EncryptionSecret = []byte("changed-encryption-secret")
+	keys := newTestKeySet(t, store, config)
+
+	signing, err := keys.signingKey(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signing.Kid == old.Kid {
+		t.Fatal("a key encrypted with another secret signs tokens")
+	}
+
+	if _, err := keys.verificationKey(ctx, old.Kid); !errors.Is(err, types.UnknownSigningKeyErr) {
+		t.Errorf("key encrypted with another secret: %v", err)
+	}
+	if kids := publicKids(t, keys); len(kids) != 1 || kids[0] != signing.Kid {
+		t.Errorf("published kids = %v", kids)
+	}
+}
+
+func TestKeySetDropsExpiredKeys(t *testing.T) {
+	ctx := context.Background()
+	store := &memorySigningKeyStore{}
+	keys := newTestKeySet(t, store, testKeySetConfig)
+
+	// Retired three hours ago, longer than VerifyFor.
+	expired := storeTestKey(t, keys, store, time.Now().Add(-4*time.Hour).Truncate(time.Second))
+
+	if _, err := keys.signingKey(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keys.verificationKey(ctx, expired.Kid); !errors.Is(err, types.UnknownSigningKeyErr) {
+		t.Errorf("expired key: %v", err)
+	}
+}
@@ -1,17 +1,26 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"strconv"
 	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
 )
 
 var UserIdKey = "USER_ID"
+var ClaimsKey = "CLAIMS"
+
+const RequestIdHeader = "X-Request-ID"
 
 type response struct {
-	Timestamp  time.Time         `json:"timestamp"`
-	StatusCode int               `json:"status_code"`
+	Timestamp  time.Time `json:"timestamp"`
+	StatusCode int       `json:"status_code"`
 }
 
 type JsonResponse struct {
@@ -20,10 +29,32 @@ type JsonResponse struct {
 }
 
 type ErrorResponse struct {
-	Errors []string `json:"errors"`
+	Errors      []string           `json:"errors"`
+	FieldErrors []types.FieldError `json:"field_errors,omitempty"`
+	RequestId   string             `json:"request_id,omitempty"`
 	response
 }
 
+func UserIdFromContext(ctx context.Context) int {
+	userId, _ := ctx.Value(UserIdKey).(int)
+	return userId
+}
+
+func ClaimsFromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(ClaimsKey).(*Claims)
+	return claims
+}
+
+// ClientIP returns the address the request came from. Behind a proxy it is
+// only the client's address when the proxy is listed in TRUSTED_PROXIES.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 func SendJsonResponse(w http.ResponseWriter, data any, statusCode int) {
 	r := JsonResponse{
 		Data: data,
@@ -38,7 +69,8 @@ func SendJsonResponse(w http.ResponseWriter, data any, statusCode int) {
 
 func SendErrorsResponse(w http.ResponseWriter, errors []string, statusCode int) {
 	r := ErrorResponse{
-		Errors: errors,
+		Errors:    errors,
+		RequestId: w.Header().Get(RequestIdHeader),
 		response: response{
 			Timestamp:  time.Now(),
 			StatusCode: statusCode,
@@ -48,13 +80,39 @@ func SendErrorsResponse(w http.ResponseWriter, errors []string, statusCode int)
 	sendJson(w, r, statusCode)
 }
 
-func SendInternalServerError(w http.ResponseWriter) {
+// SendFieldErrorsResponse sends a 400 response listing every field error,
+// both structured and as plain messages in Errors.
+func SendFieldErrorsResponse(w http.ResponseWriter, fieldErrors []types.FieldError) {
+	errors := make([]string, 0, len(fieldErrors))
+	for _, e := range fieldErrors {
+		location := e.In
+		if e.Pointer != "" {
+			location += " " + e.Pointer
+		}
+		errors = append(errors, fmt.Sprintf("%s: %s", location, e.Message))
+	}
+
+	r := ErrorResponse{
+		Errors:      errors,
+		FieldErrors: fieldErrors,
+		RequestId:   w.Header().Get(RequestIdHeader),
+		response: response{
+			Timestamp:  time.Now(),
+			StatusCode: http.StatusBadRequest,
+		},
+	}
+
+	sendJson(w, r, http.StatusBadRequest)
+}
+
+func SendInternalServerError(w http.ResponseWriter, r *http.Request, err error) {
+	slog.ErrorContext(r.Context(), "internal server error", "method", r.Method, "path", r.URL.Path, "error", err)
 	SendErrorsResponse(w, []string{"Internal server error"}, http.StatusInternalServerError)
 }
 
 func sendJson(w http.ResponseWriter, val any, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	w.Header().Add("Content-type", "application/json")
 	json.NewEncoder(w).Encode(val)
 }
 
@@ -15,32 +15,37 @@ import (
 )
 
 type Handler struct {
-	store db.GenericStore[types.Resume]
+	store *db.GenericStore[types.Resume]
 }
 
-func NewHandler(store db.GenericStore[types.Resume]) *Handler {
+func NewHandler(store *db.GenericStore[types.Resume]) *Handler {
 	return &Handler{store: store}
 }
 
 func (h *Handler) AddRoutes(r chi.Router) {
 	r.Route("/resumes", func(r chi.Router) {
-		r.Use(middleware.JwtAuthenticator(service.TokenAuth))
-		r.Get("/", h.handleResumes)
-		r.Get("/{resumeId}", h.handleSingleResume)
-		r.Post("/", h.handlePostResume)
-		r.Put("/{resumeId}", h.handlePutResume)
-		r.Delete("/{resumeId}", h.handleDeleteResume)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireScope(types.ScopeResumesRead))
+			r.Get("/", h.handleResumes)
+			r.Get("/{resumeId}", h.handleSingleResume)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireScope(types.ScopeResumesWrite))
+			r.Post("/", h.handlePostResume)
+			r.Put("/{resumeId}", h.handlePutResume)
+			r.Delete("/{resumeId}", h.handleDeleteResume)
+		})
 	})
 }
 
 func (h *Handler) handleResumes(w http.ResponseWriter, r *http.Request) {
 	pagination := service.GetPaginationParams(r)
 
-	// TODO: Get user id
-	var user_id int = 1
-	resumes, err := h.store.GetRecords(user_id, pagination.GetOffset(), pagination.Count)
+	userId := service.UserIdFromContext(r.Context())
+	resumes, err := h.store.GetRecords(r.Context(), userId, pagination.GetOffset(), pagination.Count)
 	if err != nil {
-		service.SendInternalServerError(w)
+		service.SendInternalServerError(w, r, err)
 		return
 	}
 
@@ -54,13 +59,13 @@ func (h *Handler) handleSingleResume(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resume, err := h.store.GetRecord(resumeId, 1)
+	resume, err := h.store.GetRecord(r.Context(), resumeId, service.UserIdFromContext(r.Context()))
 	if errors.Is(err, sql.ErrNoRows) {
 		service.SendErrorsResponse(w, []string{"Resume does not exist"}, http.StatusNotFound)
 		return
 	}
 	if err != nil {
-		service.SendInternalServerError(w)
+		service.SendInternalServerError(w, r, err)
 		return
 	}
 
@@ -68,18 +73,20 @@ func (h *Handler) handleSingleResume(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) handlePostResume(w http.ResponseWriter, r *http.Request) {
-	decoder := json.NewDecoder(r.Body)
 	var body ResumePostBody
-	decoder.Decode(&body)
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
 
 	if err := body.IsValid(); err != nil {
 		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
 		return
 	}
 
-	newResume, err := h.store.CreateRecord(1, *body.Name, *body.Note)
+	newResume, err := h.store.CreateRecord(r.Context(), service.UserIdFromContext(r.Context()), *body.Name, *body.Note)
 	if err != nil {
-		service.SendErrorsResponse(w, []string{err.Error()}, http.StatusBadRequest)
+		service.SendInternalServerError(w, r, err)
 		return
 	}
 
@@ -87,9 +94,11 @@ func (h *Handler) handlePostResume(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) handlePutResume(w http.ResponseWriter, r *http.Request) {
-	decoder := json.NewDecoder(r.Body)
 	var body ResumePostBody
-	decoder.Decode(&body)
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		service.SendErrorsResponse(w, []string{types.InvalidBodyErr.Error()}, http.StatusBadRequest)
+		return
+	}
 
 	resumeId, err := strconv.Atoi(chi.URLParam(r, "resumeId"))
 	if err != nil {
@@ -102,13 +111,13 @@ func (h *Handler) handlePutResume(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	newResume, err := h.store.UpdateRecord(resumeId, *body.Name, *body.Note)
+	newResume, err := h.store.UpdateRecord(r.Context(), resumeId, *body.Name, *body.Note, service.UserIdFromContext(r.Context()))
 	if errors.Is(err, sql.ErrNoRows) {
-		w.WriteHeader(http.StatusNotFound)
+		service.SendErrorsResponse(w, []string{"Resume does not exist"}, http.StatusNotFound)
 		return
 	}
 	if err != nil {
-		service.SendInternalServerError(w)
+		service.SendInternalServerError(w, r, err)
 		return
 	}
 
@@ -122,9 +131,9 @@ func (h *Handler) handleDeleteResume(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.store.DeleteRecord(resumeId)
+	err = h.store.DeleteRecord(r.Context(), resumeId, service.UserIdFromContext(r.Context()))
 	if err != nil {
-		service.SendInternalServerError(w)
+		service.SendInternalServerError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,76 @@
+package resumes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db/dbtest"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+	"github.com/go-chi/chi/v5"
+)
+
+func newTestRouter(h *Handler, userId int) http.Handler {
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), service.UserIdKey, userId)))
+		})
+	})
+	h.AddRoutes(r)
+	return r
+}
+
+func TestHandlePutResume(t *testing.T) {
+	connection := dbtest.Connect(t)
+	userId := dbtest.CreateUser(t, connection)
+	store := NewStore(connection)
+
+	created, err := store.CreateRecord(context.Background(), userId, "Backend", "")
+	if err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+
+	router := newTestRouter(NewHandler(store), userId)
+
+	t.Run("updates the resume", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/resumes/"+strconv.Itoa(created.Id), strings.NewReader(`{"name":"Frontend","note":"React"}`)))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+		}
+
+		var body struct {
+			Data types.Resume `json:"data"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Data.Name != "Frontend" || body.Data.Note != "React" {
+			t.Errorf("updated resume = %+v", body.Data)
+		}
+	})
+
+	t.Run("missing resume", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/resumes/"+strconv.Itoa(created.Id+1_000_000), strings.NewReader(`{"name":"Frontend","note":""}`)))
+
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+		}
+
+		var body service.ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if len(body.Errors) != 1 || body.Errors[0] != "Resume does not exist" {
+			t.Errorf("errors = %v", body.Errors)
+		}
+	})
+}
@@ -9,16 +9,17 @@ func NewStore(connection *db.DbConnection) *db.GenericStore[types.Resume] {
 	tableName := "resumes"
 	fields := []string{"id", "user_id", "name", "note", "created_at", "updated_at"}
 	neededFields := []string{"user_id", "name", "note"}
-	updateFields := []string{ "name", "note"}
+	updateFields := []string{"name", "note"}
 
 	return &db.GenericStore[types.Resume]{
 		Db:              connection.DB,
 		Scanner:         &resumeScanner{},
+		TableName:       tableName,
 		SelectManyQuery: db.CreateSelectManyQuery(tableName, fields),
 		SelectQuery:     db.CreateSelectQuery(tableName, fields, "WHERE id = $1 AND user_id = $2"),
 		CreateQuery:     db.CreateCreateQuery(tableName, neededFields, fields),
-		UpdateQuery:     db.CreateUpdateQuery(tableName, updateFields, fields),
-		DeleteQuery:     db.CreateDeleteQuery(tableName),
+		UpdateQuery:     db.CreateUpdateQuery(tableName, updateFields, fields, "WHERE id = $1 AND user_id = $4"),
+		DeleteQuery:     db.CreateDeleteQuery(tableName) + " AND user_id = $2",
 	}
 }
 
@@ -0,0 +1,33 @@
+package resumes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db/dbtest"
+)
+
+func TestStoreUpdateRecord(t *testing.T) {
+	connection := dbtest.Connect(t)
+	userId := dbtest.CreateUser(t, connection)
+	store := NewStore(connection)
+	ctx := context.Background()
+
+	created, err := store.CreateRecord(ctx, userId, "Backend", "")
+	if err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+
+	updated, err := store.UpdateRecord(ctx, created.Id, "Backend (Go)", "Tailored", userId)
+	if err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	if updated.Id != created.Id || updated.Name != "Backend (Go)" || updated.Note != "Tailored" {
+		t.Errorf("UpdateRecord returned %+v", updated)
+	}
+
+	otherUserId := dbtest.CreateUser(t, connection)
+	if _, err := store.UpdateRecord(ctx, created.Id, "Stolen", "", otherUserId); err == nil {
+		t.Error("UpdateRecord updated a resume of another user")
+	}
+}
@@ -1,17 +1,17 @@
 package resumes
 
 import (
-	"github.com/CelanMatjaz/job_application_tracker_api/pkg/service"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
 )
 
 type ResumePostBody struct {
-	Name *string `json:"name"`
-	Note *string `json:"note"`
+	Name *string `json:"name" openapi:"minLength=1,maxLength=200"`
+	Note *string `json:"note" openapi:"maxLength=10000"`
 }
 
 func (l *ResumePostBody) IsValid() error {
 	if l.Name == nil || l.Note == nil {
-		return service.InvalidBodyErr
+		return types.InvalidBodyErr
 	}
 
 	return nil
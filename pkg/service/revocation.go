@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/db"
+)
+
+const (
+	DefaultRevocationCacheTTL = 10 * time.Second
+	revocationCachePruneSize  = 10000
+)
+
+type cachedRevocation struct {
+	revoked   bool
+	expiresAt time.Time
+}
+
+// RevocationCache answers token revocation checks from memory where it can.
+// Revoked tokens are remembered until they would have expired anyway, tokens
+// and sessions found valid are rechecked after ttl so revocations made by
+// other replicas are picked up. Per user cutoffs are not cached: disabling a
+// user or logging them out everywhere has to apply on every replica right
+// away, and it costs a single lookup by primary key.
+type RevocationCache struct {
+	store db.RevocationStore
+	ttl   time.Duration
+
+	mu     sync.Mutex
+	tokens map[string]cachedRevocation
+}
+
+func NewRevocationCache(store db.RevocationStore, ttl time.Duration) *RevocationCache {
+	return &RevocationCache{
+		store:  store,
+		ttl:    ttl,
+		tokens: make(map[string]cachedRevocation),
+	}
+}
+
+func (c *RevocationCache) IsTokenRevoked(ctx context.Context, jti string, expiresAt time.Time) (bool, error) {
+	return c.isRevoked(jti, expiresAt, func() (bool, error) {
+		return c.store.IsTokenRevoked(ctx, jti)
+	})
+}
+
+// IsSessionRevoked is checked for tokens issued to a session, expiresAt is
+// the expiry of the token.
+func (c *RevocationCache) IsSessionRevoked(ctx context.Context, sessionId int, expiresAt time.Time) (bool, error) {
+	return c.isRevoked(sessionCacheKey(sessionId), expiresAt, func() (bool, error) {
+		return c.store.IsSessionRevoked(ctx, sessionId)
+	})
+}
+
+// InvalidateSession drops what is cached about the session, so revoking it
+// applies to the next request on this replica.
+func (c *RevocationCache) InvalidateSession(sessionId int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.tokens, sessionCacheKey(sessionId))
+}
+
+// isRevoked caches revocations of tokens and sessions under key, which do not
+// collide as token ids are hex encoded.
+func (c *RevocationCache) isRevoked(key string, expiresAt time.Time, check func() (bool, error)) (bool, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	cached, ok := c.tokens[key]
+	c.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.revoked, nil
+	}
+
+	revoked, err := check()
+	if err != nil {
+		return false, err
+	}
+
+	cacheUntil := expiresAt
+	if !revoked {
+		cacheUntil = minTime(expiresAt, now.Add(c.ttl))
+	}
+	c.storeToken(key, cachedRevocation{revoked: revoked, expiresAt: cacheUntil})
+
+	return revoked, nil
+}
+
+func (c *RevocationCache) RevokeToken(ctx context.Context, jti string, userId int, expiresAt time.Time) error {
+	if err := c.store.RevokeToken(ctx, jti, userId, expiresAt); err != nil {
+		return err
+	}
+
+	c.storeToken(jti, cachedRevocation{revoked: true, expiresAt: expiresAt})
+	return nil
+}
+
+// TokensValidAfter returns the time before which every token issued to the
+// user is rejected. It is zero when the user never revoked all tokens and
+// types.UserDisabledErr is returned for disabled users.
+func (c *RevocationCache) TokensValidAfter(ctx context.Context, userId int) (time.Time, error) {
+	return c.store.GetTokensValidAfter(ctx, userId)
+}
+
+func (c *RevocationCache) SetTokensValidAfter(ctx context.Context, userId int, validAfter time.Time) error {
+	return c.store.SetTokensValidAfter(ctx, userId, validAfter)
+}
+
+func (c *RevocationCache) storeToken(jti string, revocation cachedRevocation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.tokens) >= revocationCachePruneSize {
+		now := time.Now()
+		for key, cached := range c.tokens {
+			if now.After(cached.expiresAt) {
+				delete(c.tokens, key)
+			}
+		}
+	}
+
+	c.tokens[jti] = revocation
+}
+
+func sessionCacheKey(sessionId int) string {
+	return "session:" + strconv.Itoa(sessionId)
+}
+
+func minTime(a time.Time, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/types"
+)
+
+// memoryRevocationStore is the store shared by every replica, which the
+// tests change directly to act as another replica.
+type memoryRevocationStore struct {
+	mu            sync.Mutex
+	revokedTokens map[string]bool
+	tokenChecks   int
+	validAfter    time.Time
+	disabled      bool
+}
+
+func (s *memoryRevocationStore) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokenChecks++
+	return s.revokedTokens[jti], nil
+}
+
+func (s *memoryRevocationStore) IsSessionRevoked(ctx context.Context, id int) (bool, error) {
+	return false, nil
+}
+
+func (s *memoryRevocationStore) RevokeToken(ctx context.Context, jti string, userId int, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revokedTokens[jti] = true
+	return nil
+}
+
+func (s *memoryRevocationStore) GetTokensValidAfter(ctx context.Context, userId int) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.disabled {
+		return time.Time{}, types.UserDisabledErr
+	}
+	return s.validAfter, nil
+}
+
+func (s *memoryRevocationStore) SetTokensValidAfter(ctx context.Context, userId int, validAfter time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.validAfter = validAfter
+	return nil
+}
+
+func (s *memoryRevocationStore) set(change func(s *memoryRevocationStore)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change(s)
+}
+
+func newRevocationTest(t *testing.T) (*JwtAuth, *memoryRevocationStore, string) {
+	t.Helper()
+
+	auth, err := newTestJwtAuth(t, map[string]string{
+		"JWT_SECRET":    "test-jwt-secret",
+		"JWT_ALGORITHM": JwtAlgorithmHS256,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := &memoryRevocationStore{revokedTokens: make(map[string]bool)}
+	auth.SetRevocations(NewRevocationCache(store, time.Hour))
+
+	user := types.User{}
+	user.Id = 7
+	token, err := auth.CreateToken(context.Background(), user, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parseTestToken(auth, token); err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+
+	return auth, store, token
+}
+
+func TestDisabledUserIsRejectedImmediately(t *testing.T) {
+	auth, store, token := newRevocationTest(t)
+
+	// Disabled on another replica, whose cache this one knows nothing of.
+	store.set(func(s *memoryRevocationStore) { s.disabled = true })
+
+	if _, err := parseTestToken(auth, token); !errors.Is(err, types.UserDisabledErr) {
+		t.Errorf("ParseToken error = %v, want UserDisabledErr", err)
+	}
+
+	store.set(func(s *memoryRevocationStore) { s.disabled = false })
+
+	if _, err := parseTestToken(auth, token); err != nil {
+		t.Errorf("ParseToken after enabling the user: %v", err)
+	}
+}
+
+func TestForcedLogoutIsRejectedImmediately(t *testing.T) {
+	auth, store, token := newRevocationTest(t)
+
+	store.set(func(s *memoryRevocationStore) { s.validAfter = time.Now().Add(time.Second) })
+
+	if _, err := parseTestToken(auth, token); !errors.Is(err, types.TokenRevokedErr) {
+		t.Errorf("ParseToken error = %v, want TokenRevokedErr", err)
+	}
+}
+
+func TestRevocationCacheCachesTokens(t *testing.T) {
+	store := &memoryRevocationStore{revokedTokens: map[string]bool{"revoked": true}}
+	cache := NewRevocationCache(store, time.Hour)
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Minute)
+
+	for range 3 {
+		if revoked, err := cache.IsTokenRevoked(ctx, "valid", expiresAt); err != nil || revoked {
+			t.Fatalf("IsTokenRevoked(valid) = %v, %v", revoked, err)
+		}
+		if revoked, err := cache.IsTokenRevoked(ctx, "revoked", expiresAt); err != nil || !revoked {
+			t.Fatalf("IsTokenRevoked(revoked) = %v, %v", revoked, err)
+		}
+	}
+
+	if store.tokenChecks != 2 {
+		t.Errorf("store was checked %d times, want once per token", store.tokenChecks)
+	}
+}
@@ -0,0 +1,26 @@
+package types
+
+import "time"
+
+const (
+	ScopeResumesRead  = "resumes:read"
+	ScopeResumesWrite = "resumes:write"
+)
+
+// Scopes lists every scope an API key can be granted.
+var Scopes = []string{
+	ScopeResumesRead,
+	ScopeResumesWrite,
+}
+
+type ApiKey struct {
+	Common
+	UserId     int        `json:"-" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	KeyHash    string     `json:"-" db:"key_hash"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at" openapi:"nullable"`
+	ExpiresAt  *time.Time `json:"expires_at" db:"expires_at" openapi:"nullable"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
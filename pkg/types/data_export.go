@@ -0,0 +1,32 @@
+package types
+
+import "time"
+
+const (
+	DataExportPending = "pending"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+)
+
+// DataExport is an archive of everything stored about a user, built in the
+// background after they request it.
+type DataExport struct {
+	Common
+	UserId      int        `json:"-" db:"user_id"`
+	Status      string     `json:"status" db:"status" openapi:"enum=pending|ready|failed"`
+	SizeBytes   *int64     `json:"size_bytes" db:"size_bytes" openapi:"nullable"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	CompletedAt *time.Time `json:"completed_at" db:"completed_at" openapi:"nullable"`
+	// ExpiresAt is when the archive is deleted and its link stops working.
+	ExpiresAt *time.Time `json:"expires_at" db:"expires_at" openapi:"nullable"`
+}
+
+// AccountData is every record owned by a user, as written to data exports.
+type AccountData struct {
+	User       User
+	Resumes    []Resume
+	Identities []Identity
+	ApiKeys    []ApiKey
+	Sessions   []Session
+	Passkeys   []Passkey
+}
@@ -9,4 +9,29 @@ var (
 	PasswordsDoNotMatchErr        = errors.New("passwords do not match")
 	WronglyFormattedAuthHeaderErr = errors.New("authentication error is not formatted correctly")
 	MissingRequiredHeaderErr      = errors.New("request is missing required header")
+	BodyTooLargeErr               = errors.New("request body is too large")
+	RefreshTokenDoesNotExistErr   = errors.New("refresh token does not exist")
+	RefreshTokenReusedErr         = errors.New("refresh token was already used")
+	TokenRevokedErr               = errors.New("token was revoked")
+	RevocationCheckFailedErr      = errors.New("could not check whether token was revoked")
+	InvalidOneTimeTokenErr        = errors.New("token is not valid or has expired")
+	IdentityDoesNotExistErr       = errors.New("identity does not exist")
+	InvalidApiKeyErr              = errors.New("api key is not valid")
+	UserDisabledErr               = errors.New("user is disabled")
+	DataExportDoesNotExistErr     = errors.New("data export does not exist")
+	EmailTakenErr                 = errors.New("user with provided email already exists")
+	UnknownSigningKeyErr          = errors.New("token is signed with an unknown key")
+	SigningKeyLookupFailedErr     = errors.New("could not look up signing key")
+	PasswordMismatchErr           = errors.New("password does not match")
+	PasskeyDoesNotExistErr        = errors.New("passkey does not exist")
+	PasskeyExistsErr              = errors.New("passkey is already registered")
 )
+
+// FieldError describes a problem with a single value of a request. In is the
+// part of the request the value came from ("body", "query" or "path") and
+// Pointer is a JSON pointer to the value within it.
+type FieldError struct {
+	In      string `json:"in"`
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,35 @@
+package types
+
+import "time"
+
+// Passkey is a WebAuthn credential a user signs in with instead of a
+// password. PublicKey is COSE encoded.
+type Passkey struct {
+	Common
+	UserId            int      `json:"-" db:"user_id"`
+	Name              string   `json:"name" db:"name"`
+	CredentialId      []byte   `json:"-" db:"credential_id"`
+	PublicKey         []byte   `json:"-" db:"public_key"`
+	Algorithm         int64    `json:"-" db:"algorithm"`
+	SignCount         uint32   `json:"-" db:"sign_count"`
+	Aaguid            []byte   `json:"-" db:"aaguid"`
+	AttestationFormat string   `json:"-" db:"attestation_format"`
+	Transports        []string `json:"-" db:"transports"`
+	// BackupEligible passkeys can be synced between the user's devices and
+	// BackedUp ones are.
+	BackupEligible bool       `json:"backup_eligible" db:"backup_eligible"`
+	BackedUp       bool       `json:"backed_up" db:"backed_up"`
+	LastUsedAt     *time.Time `json:"last_used_at" db:"last_used_at" openapi:"nullable"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// WebAuthnChallenge is issued at the start of a passkey registration or
+// login and spent when it finishes. UserId is only set for registrations.
+type WebAuthnChallenge struct {
+	Common
+	ChallengeHash string    `json:"-" db:"challenge_hash"`
+	Ceremony      string    `json:"ceremony" db:"ceremony"`
+	UserId        *int      `json:"-" db:"user_id"`
+	ExpiresAt     time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
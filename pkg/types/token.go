@@ -0,0 +1,86 @@
+package types
+
+import "time"
+
+type RefreshToken struct {
+	Common
+	UserId    int        `json:"user_id" db:"user_id"`
+	FamilyId  string     `json:"family_id" db:"family_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+	RevokedAt *time.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+type PasswordResetToken struct {
+	Common
+	UserId    int        `json:"user_id" db:"user_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// MagicLink is a single use login link. It only works together with the
+// device token handed to the client that requested it.
+type MagicLink struct {
+	Common
+	UserId     int        `json:"user_id" db:"user_id"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	DeviceHash string     `json:"-" db:"device_hash"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt     *time.Time `json:"used_at" db:"used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+type TwoFactorChallenge struct {
+	Common
+	UserId    int        `json:"user_id" db:"user_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	Attempts  int        `json:"attempts" db:"attempts"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+type OAuthState struct {
+	Common
+	StateHash    string    `json:"-" db:"state_hash"`
+	Provider     string    `json:"provider" db:"provider"`
+	CodeVerifier string    `json:"-" db:"code_verifier"`
+	Nonce        string    `json:"-" db:"nonce"`
+	ExpiresAt    time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// Session is a login on one device. It lasts as long as the refresh token
+// family started by the login and is seen again on every refresh.
+type Session struct {
+	Common
+	UserId     int        `json:"-" db:"user_id"`
+	FamilyId   string     `json:"-" db:"family_id"`
+	Device     string     `json:"device" db:"-"`
+	UserAgent  string     `json:"user_agent" db:"user_agent"`
+	IpAddress  string     `json:"ip_address" db:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt  *time.Time `json:"-" db:"revoked_at"`
+	// Current marks the session of the token the request was made with.
+	Current bool `json:"current" db:"-"`
+}
+
+// SigningKey signs access tokens between ActivatesAt and RetiresAt and
+// verifies them until ExpiresAt. PrivateKey is encrypted, PublicKey is PKIX
+// DER.
+type SigningKey struct {
+	Kid         string    `json:"kid" db:"kid"`
+	Algorithm   string    `json:"algorithm" db:"algorithm"`
+	PrivateKey  []byte    `json:"-" db:"private_key"`
+	PublicKey   []byte    `json:"-" db:"public_key"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	ActivatesAt time.Time `json:"activates_at" db:"activates_at"`
+	RetiresAt   time.Time `json:"retires_at" db:"retires_at"`
+	ExpiresAt   time.Time `json:"expires_at" db:"expires_at"`
+}
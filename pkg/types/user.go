@@ -1,18 +1,65 @@
 package types
 
+import "time"
+
 type CommonUser struct {
 	FirstName string `json:"first_name" db:"first_name"`
 	LastName  string `json:"last_name" db:"last_name"`
 	Email     string `json:"email" db:"email"`
 }
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	Common
 	CommonUser
+	VerifiedAt *time.Time `json:"verified_at" db:"verified_at" openapi:"nullable"`
+	Role       string     `json:"role" db:"role" openapi:"enum=user|admin"`
+	DisabledAt *time.Time `json:"disabled_at" db:"disabled_at" openapi:"nullable"`
 	Timestamps
 }
 
 type InternalUser struct {
 	User
-	PasswordHash string `json:"password_hash" db:"password_hash"`
+	PasswordHash  string     `json:"password_hash" db:"password_hash"`
+	TotpSecret    *string    `json:"-" db:"totp_secret"`
+	TotpEnabledAt *time.Time `json:"-" db:"totp_enabled_at"`
+}
+
+func (u InternalUser) TwoFactorEnabled() bool {
+	return u.TotpEnabledAt != nil
+}
+
+// Identity links a user to their account at an OAuth2 or OpenID Connect
+// provider.
+type Identity struct {
+	Common
+	UserId      int        `json:"-" db:"user_id"`
+	Provider    string     `json:"provider" db:"provider"`
+	Subject     string     `json:"-" db:"subject"`
+	Email       string     `json:"email" db:"email"`
+	LastLoginAt *time.Time `json:"last_login_at" db:"last_login_at" openapi:"nullable"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// UserUsage counts the records a user owns and their active sessions.
+type UserUsage struct {
+	Resumes        int `json:"resumes"`
+	ApiKeys        int `json:"api_keys"`
+	Identities     int `json:"identities"`
+	ActiveSessions int `json:"active_sessions"`
+}
+
+// Usage counts users and records across the whole service.
+type Usage struct {
+	Users          int `json:"users"`
+	VerifiedUsers  int `json:"verified_users"`
+	DisabledUsers  int `json:"disabled_users"`
+	Admins         int `json:"admins"`
+	Resumes        int `json:"resumes"`
+	ApiKeys        int `json:"api_keys"`
+	ActiveSessions int `json:"active_sessions"`
 }
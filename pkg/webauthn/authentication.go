@@ -0,0 +1,72 @@
+package webauthn
+
+import (
+	"bytes"
+	"slices"
+)
+
+// Authentication describes a verified authentication.
+type Authentication struct {
+	// SignCount is the authenticator's new signature counter, to be stored
+	// with the credential.
+	SignCount uint32
+	BackedUp  bool
+}
+
+// VerifyAuthentication verifies an authentication credential created for the
+// challenge with the stored credential it names. userHandle is the handle of
+// the credential's owner, which the response has to carry; pass nil when the
+// ceremony listed the allowed credentials and the handle is optional.
+//
+// ErrSignCount is returned when the signature counter did not increase past
+// the stored one, as the credential was probably cloned. Authenticators that
+// do not implement counters always report zero, which is accepted.
+func (rp RelyingParty) VerifyAuthentication(credential AuthenticationCredential, challenge []byte, stored Credential, userHandle []byte) (Authentication, error) {
+	if credential.Type != credentialType {
+		return Authentication{}, verificationError("credential type is %q", credential.Type)
+	}
+
+	if !bytes.Equal(credential.RawId, stored.Id) {
+		return Authentication{}, verificationError("credential id does not match the stored credential")
+	}
+
+	response := credential.Response
+	if userHandle != nil && !bytes.Equal(response.UserHandle, userHandle) {
+		return Authentication{}, verificationError("user handle does not match the credential's owner")
+	}
+
+	clientDataHash, err := rp.verifyClientData(response.ClientDataJSON, ceremonyGet, challenge)
+	if err != nil {
+		return Authentication{}, err
+	}
+
+	authData, err := ParseAuthenticatorData(response.AuthenticatorData)
+	if err != nil {
+		return Authentication{}, err
+	}
+	if err := authData.verify(rp); err != nil {
+		return Authentication{}, err
+	}
+
+	// Whether a credential can be backed up is fixed when it is created.
+	if authData.Has(FlagBackupEligible) != stored.BackupEligible {
+		return Authentication{}, verificationError("backup eligibility of the credential changed")
+	}
+
+	publicKey, err := ParsePublicKey(stored.PublicKey)
+	if err != nil {
+		return Authentication{}, err
+	}
+	if err := publicKey.Verify(slices.Concat(response.AuthenticatorData, clientDataHash), response.Signature); err != nil {
+		return Authentication{}, err
+	}
+
+	if (authData.SignCount != 0 || stored.SignCount != 0) && authData.SignCount <= stored.SignCount {
+		return Authentication{}, ErrSignCount
+	}
+
+	return Authentication{
+		SignCount: authData.SignCount,
+		BackedUp:  authData.Has(FlagBackedUp),
+	}, nil
+}
@@ -0,0 +1,116 @@
+package webauthn
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/webauthn/internal/cbor"
+)
+
+// Flags of authenticator data.
+const (
+	FlagUserPresent        byte = 0x01
+	FlagUserVerified       byte = 0x04
+	FlagBackupEligible     byte = 0x08
+	FlagBackedUp           byte = 0x10
+	FlagAttestedCredential byte = 0x40
+	FlagExtensions         byte = 0x80
+)
+
+// authenticatorDataMinLength covers the RP ID hash, flags and counter.
+const authenticatorDataMinLength = 32 + 1 + 4
+
+// AuthenticatorData is what the authenticator signs, describing the
+// ceremony and, at registration, the new credential.
+type AuthenticatorData struct {
+	RpIdHash  []byte
+	Flags     byte
+	SignCount uint32
+
+	// AAGUID, CredentialId and PublicKey are only set when
+	// FlagAttestedCredential is.
+	AAGUID       []byte
+	CredentialId []byte
+	// PublicKey is the credential's COSE key as it was encoded.
+	PublicKey []byte
+}
+
+func (d AuthenticatorData) Has(flag byte) bool {
+	return d.Flags&flag == flag
+}
+
+func ParseAuthenticatorData(data []byte) (AuthenticatorData, error) {
+	if len(data) < authenticatorDataMinLength {
+		return AuthenticatorData{}, verificationError("authenticator data is too short")
+	}
+
+	parsed := AuthenticatorData{
+		RpIdHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[authenticatorDataMinLength:]
+
+	if parsed.Has(FlagAttestedCredential) {
+		if len(rest) < 18 {
+			return AuthenticatorData{}, verificationError("attested credential data is too short")
+		}
+
+		parsed.AAGUID = rest[:16]
+		length := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+
+		if length == 0 || length > 1023 || len(rest) < length {
+			return AuthenticatorData{}, verificationError("credential id length is not valid")
+		}
+		parsed.CredentialId = rest[:length]
+		rest = rest[length:]
+
+		_, after, err := cbor.Decode(rest)
+		if err != nil {
+			return AuthenticatorData{}, verificationError("credential public key is not valid CBOR")
+		}
+		parsed.PublicKey = rest[:len(rest)-len(after)]
+		rest = after
+	}
+
+	if parsed.Has(FlagExtensions) {
+		extensions, after, err := cbor.Decode(rest)
+		if err != nil {
+			return AuthenticatorData{}, verificationError("extensions are not valid CBOR")
+		}
+		if _, ok := extensions.(map[any]any); !ok {
+			return AuthenticatorData{}, verificationError("extensions are not a map")
+		}
+		rest = after
+	}
+
+	if len(rest) > 0 {
+		return AuthenticatorData{}, verificationError("authenticator data has %d trailing bytes", len(rest))
+	}
+
+	return parsed, nil
+}
+
+// verify checks the flags and that the data was created for the relying
+// party.
+func (d AuthenticatorData) verify(rp RelyingParty) error {
+	if subtle.ConstantTimeCompare(d.RpIdHash, rp.idHash()) != 1 {
+		return verificationError("credential was created for another relying party")
+	}
+
+	// Passkeys replace the password, so the authenticator has to have
+	// verified the user, e.g. with a PIN or biometrics.
+	if !d.Has(FlagUserPresent) {
+		return verificationError("user was not present")
+	}
+	if !d.Has(FlagUserVerified) {
+		return verificationError("user was not verified")
+	}
+
+	if d.Has(FlagBackedUp) && !d.Has(FlagBackupEligible) {
+		return verificationError("credential is backed up but not backup eligible")
+	}
+
+	return nil
+}
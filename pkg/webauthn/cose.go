@@ -0,0 +1,135 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"math/big"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/webauthn/internal/cbor"
+)
+
+// COSE algorithm identifiers of the supported signature algorithms.
+const (
+	AlgorithmES256 int64 = -7
+	AlgorithmEdDSA int64 = -8
+	AlgorithmRS256 int64 = -257
+)
+
+// Algorithms are offered at registration in order of preference.
+var Algorithms = []int64{AlgorithmES256, AlgorithmEdDSA, AlgorithmRS256}
+
+// COSE key parameters (RFC 9053).
+const (
+	coseKeyType   int64 = 1
+	coseAlgorithm int64 = 3
+
+	coseCurve int64 = -1
+	coseX     int64 = -2
+	coseY     int64 = -3
+
+	coseModulus  int64 = -1
+	coseExponent int64 = -2
+
+	coseKeyTypeOKP int64 = 1
+	coseKeyTypeEC2 int64 = 2
+	coseKeyTypeRSA int64 = 3
+
+	coseCurveP256    int64 = 1
+	coseCurveEd25519 int64 = 6
+
+	minRSABits = 2048
+)
+
+// PublicKey is a credential public key decoded from its COSE encoding.
+type PublicKey struct {
+	Algorithm int64
+	Key       crypto.PublicKey
+}
+
+func ParsePublicKey(encoded []byte) (PublicKey, error) {
+	value, err := cbor.DecodeAll(encoded)
+	if err != nil {
+		return PublicKey{}, verificationError("public key is not valid CBOR")
+	}
+
+	key, ok := value.(map[any]any)
+	if !ok {
+		return PublicKey{}, verificationError("public key is not a COSE key")
+	}
+
+	keyType, _ := key[coseKeyType].(int64)
+	algorithm, _ := key[coseAlgorithm].(int64)
+
+	switch {
+	case algorithm == AlgorithmES256 && keyType == coseKeyTypeEC2:
+		curve, _ := key[coseCurve].(int64)
+		x, _ := key[coseX].([]byte)
+		y, _ := key[coseY].([]byte)
+		if curve != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return PublicKey{}, verificationError("ES256 key is not a P-256 key")
+		}
+
+		// crypto/ecdh rejects points that are not on the curve.
+		point := append(append([]byte{0x04}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return PublicKey{}, verificationError("ES256 key is not a valid point")
+		}
+
+		return PublicKey{Algorithm: algorithm, Key: &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}}, nil
+	case algorithm == AlgorithmEdDSA && keyType == coseKeyTypeOKP:
+		curve, _ := key[coseCurve].(int64)
+		x, _ := key[coseX].([]byte)
+		if curve != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+			return PublicKey{}, verificationError("EdDSA key is not an Ed25519 key")
+		}
+
+		return PublicKey{Algorithm: algorithm, Key: ed25519.PublicKey(x)}, nil
+	case algorithm == AlgorithmRS256 && keyType == coseKeyTypeRSA:
+		modulus, _ := key[coseModulus].([]byte)
+		exponent, _ := key[coseExponent].([]byte)
+		if len(exponent) == 0 || len(exponent) > 4 {
+			return PublicKey{}, verificationError("RS256 key exponent is not valid")
+		}
+
+		n := new(big.Int).SetBytes(modulus)
+		e := int(new(big.Int).SetBytes(exponent).Int64())
+		if n.BitLen() < minRSABits || e < 3 || e%2 == 0 {
+			return PublicKey{}, verificationError("RS256 key is too weak")
+		}
+
+		return PublicKey{Algorithm: algorithm, Key: &rsa.PublicKey{N: n, E: e}}, nil
+	default:
+		return PublicKey{}, verificationError("key type %d with algorithm %d is not supported", keyType, algorithm)
+	}
+}
+
+// Verify checks a signature over data made with the key's algorithm.
+func (k PublicKey) Verify(data []byte, signature []byte) error {
+	valid := false
+
+	switch key := k.Key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		valid = ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, data, signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	}
+
+	if !valid {
+		return verificationError("signature is not valid")
+	}
+
+	return nil
+}
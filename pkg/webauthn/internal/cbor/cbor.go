@@ -0,0 +1,277 @@
+// Package cbor encodes and decodes the subset of CBOR (RFC 8949) used by
+// WebAuthn: integers, byte and text strings, arrays, maps, booleans and null,
+// all with definite lengths.
+//
+// Decoded integers are int64, byte strings []byte, text strings string,
+// arrays []any and maps map[any]any with int64 or string keys.
+package cbor
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf8"
+)
+
+const (
+	majorUnsigned = 0
+	majorNegative = 1
+	majorBytes    = 2
+	majorText     = 3
+	majorArray    = 4
+	majorMap      = 5
+	majorTag      = 6
+	majorSimple   = 7
+
+	// maxDepth bounds the nesting of arrays and maps.
+	maxDepth = 16
+)
+
+var ErrMalformed = errors.New("cbor: malformed data")
+
+// Decode decodes the first item of data and returns the bytes after it.
+func Decode(data []byte) (value any, rest []byte, err error) {
+	d := decoder{data: data}
+	value, err = d.item(0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return value, d.data[d.offset:], nil
+}
+
+// DecodeAll decodes data, which must be exactly one item.
+func DecodeAll(data []byte) (any, error) {
+	value, rest, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%w: %d bytes after the item", ErrMalformed, len(rest))
+	}
+
+	return value, nil
+}
+
+type decoder struct {
+	data   []byte
+	offset int
+}
+
+func (d *decoder) item(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: nested too deeply", ErrMalformed)
+	}
+
+	major, argument, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case majorUnsigned:
+		if argument > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: integer out of range", ErrMalformed)
+		}
+		return int64(argument), nil
+	case majorNegative:
+		if argument > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: integer out of range", ErrMalformed)
+		}
+		return -1 - int64(argument), nil
+	case majorBytes, majorText:
+		value, err := d.take(argument)
+		if err != nil {
+			return nil, err
+		}
+		if major == majorBytes {
+			return append([]byte(nil), value...), nil
+		}
+		if !utf8.Valid(value) {
+			return nil, fmt.Errorf("%w: text string is not UTF-8", ErrMalformed)
+		}
+		return string(value), nil
+	case majorArray:
+		// Every item takes at least a byte, which bounds the allocation.
+		if argument > uint64(len(d.data)-d.offset) {
+			return nil, fmt.Errorf("%w: array longer than the data", ErrMalformed)
+		}
+		array := make([]any, argument)
+		for i := range array {
+			if array[i], err = d.item(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return array, nil
+	case majorMap:
+		if argument > uint64(len(d.data)-d.offset)/2 {
+			return nil, fmt.Errorf("%w: map longer than the data", ErrMalformed)
+		}
+		m := make(map[any]any, argument)
+		for i := uint64(0); i < argument; i++ {
+			key, err := d.item(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, fmt.Errorf("%w: map key of type %T", ErrMalformed, key)
+			}
+			if _, ok := m[key]; ok {
+				return nil, fmt.Errorf("%w: duplicate map key %v", ErrMalformed, key)
+			}
+			if m[key], err = d.item(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case majorSimple:
+		switch argument {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22:
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: unsupported simple value %d", ErrMalformed, argument)
+	default:
+		return nil, fmt.Errorf("%w: unsupported major type %d", ErrMalformed, major)
+	}
+}
+
+// head reads an item's major type and argument.
+func (d *decoder) head() (major byte, argument uint64, err error) {
+	b, err := d.take(1)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	major = b[0] >> 5
+	info := b[0] & 0x1f
+
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		b, err = d.take(1)
+		if err != nil {
+			return 0, 0, err
+		}
+		return major, uint64(b[0]), nil
+	case info == 25:
+		b, err = d.take(2)
+		if err != nil {
+			return 0, 0, err
+		}
+		return major, uint64(binary.BigEndian.Uint16(b)), nil
+	case info == 26:
+		b, err = d.take(4)
+		if err != nil {
+			return 0, 0, err
+		}
+		return major, uint64(binary.BigEndian.Uint32(b)), nil
+	case info == 27:
+		b, err = d.take(8)
+		if err != nil {
+			return 0, 0, err
+		}
+		return major, binary.BigEndian.Uint64(b), nil
+	default:
+		return 0, 0, fmt.Errorf("%w: indefinite lengths are not supported", ErrMalformed)
+	}
+}
+
+func (d *decoder) take(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.offset) {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrMalformed)
+	}
+
+	b := d.data[d.offset : d.offset+int(n)]
+	d.offset += int(n)
+	return b, nil
+}
+
+// Pair is an entry of a Map.
+type Pair struct {
+	Key   any
+	Value any
+}
+
+// Map is encoded as a CBOR map with its pairs in order, so the encoding is
+// deterministic.
+type Map []Pair
+
+// Marshal encodes ints, []byte, strings, bools, nil, []any and Map.
+func Marshal(value any) ([]byte, error) {
+	return appendItem(nil, value)
+}
+
+func appendItem(out []byte, value any) ([]byte, error) {
+	var err error
+
+	switch v := value.(type) {
+	case int:
+		return appendInt(out, int64(v)), nil
+	case int64:
+		return appendInt(out, v), nil
+	case []byte:
+		return append(appendHead(out, majorBytes, uint64(len(v))), v...), nil
+	case string:
+		return append(appendHead(out, majorText, uint64(len(v))), v...), nil
+	case bool:
+		if v {
+			return appendHead(out, majorSimple, 21), nil
+		}
+		return appendHead(out, majorSimple, 20), nil
+	case nil:
+		return appendHead(out, majorSimple, 22), nil
+	case []any:
+		out = appendHead(out, majorArray, uint64(len(v)))
+		for _, item := range v {
+			if out, err = appendItem(out, item); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case Map:
+		out = appendHead(out, majorMap, uint64(len(v)))
+		for _, pair := range v {
+			if out, err = appendItem(out, pair.Key); err != nil {
+				return nil, err
+			}
+			if out, err = appendItem(out, pair.Value); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("cbor: cannot marshal %T", value)
+	}
+}
+
+func appendInt(out []byte, v int64) []byte {
+	if v < 0 {
+		return appendHead(out, majorNegative, uint64(-1-v))
+	}
+	return appendHead(out, majorUnsigned, uint64(v))
+}
+
+func appendHead(out []byte, major byte, argument uint64) []byte {
+	major <<= 5
+
+	switch {
+	case argument < 24:
+		return append(out, major|byte(argument))
+	case argument <= math.MaxUint8:
+		return append(out, major|24, byte(argument))
+	case argument <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(out, major|25), uint16(argument))
+	case argument <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(out, major|26), uint32(argument))
+	default:
+		return binary.BigEndian.AppendUint64(append(out, major|27), argument)
+	}
+}
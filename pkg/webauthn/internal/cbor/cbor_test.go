@@ -0,0 +1,172 @@
+package cbor
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		value any
+	}{
+		{"small unsigned", []byte{0x17}, int64(23)},
+		{"one byte unsigned", []byte{0x18, 0x18}, int64(24)},
+		{"two byte unsigned", []byte{0x19, 0x03, 0xe8}, int64(1000)},
+		{"eight byte unsigned", []byte{0x1b, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, int64(math.MaxInt64)},
+		{"negative", []byte{0x26}, int64(-7)},
+		{"smallest negative", []byte{0x3b, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, int64(math.MinInt64)},
+		{"byte string", []byte{0x43, 0x01, 0x02, 0x03}, []byte{1, 2, 3}},
+		{"text string", []byte{0x63, 'a', 'l', 'g'}, "alg"},
+		{"array", []byte{0x82, 0x01, 0x20}, []any{int64(1), int64(-1)}},
+		{"map", []byte{0xa2, 0x01, 0x02, 0x63, 'f', 'm', 't', 0xf5}, map[any]any{int64(1): int64(2), "fmt": true}},
+		{"false", []byte{0xf4}, false},
+		{"null", []byte{0xf6}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := DecodeAll(test.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(value, test.value) {
+				t.Errorf("DecodeAll = %#v, want %#v", value, test.value)
+			}
+		})
+	}
+}
+
+func TestDecodeReturnsRest(t *testing.T) {
+	value, rest, err := Decode([]byte{0x01, 0x02, 0x03})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != int64(1) || !bytes.Equal(rest, []byte{0x02, 0x03}) {
+		t.Errorf("Decode = %v, %v", value, rest)
+	}
+}
+
+// nested returns depth arrays, each holding the next.
+func nested(depth int) []byte {
+	return append(bytes.Repeat([]byte{0x81}, depth), 0x00)
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		message string
+	}{
+		{"empty", []byte{}, "unexpected end of data"},
+		{"truncated argument", []byte{0x19, 0x03}, "unexpected end of data"},
+		{"truncated byte string", []byte{0x43, 0x01, 0x02}, "unexpected end of data"},
+		{"truncated array", []byte{0x82, 0x01, 0x19}, "unexpected end of data"},
+		{"truncated map", []byte{0xa1, 0x01, 0x19}, "unexpected end of data"},
+		{"oversized byte string", []byte{0x5b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, "unexpected end of data"},
+		{"oversized array", []byte{0x9b, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}, "array longer than the data"},
+		{"oversized map", []byte{0xba, 0xff, 0xff, 0xff, 0xff, 0x01, 0x02}, "map longer than the data"},
+		{"unsigned out of range", []byte{0x1b, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, "integer out of range"},
+		{"negative out of range", []byte{0x3b, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, "integer out of range"},
+		{"duplicate integer keys", []byte{0xa2, 0x01, 0x02, 0x01, 0x03}, "duplicate map key 1"},
+		{"duplicate text keys", []byte{0xa2, 0x61, 'a', 0x01, 0x61, 'a', 0x02}, "duplicate map key a"},
+		{"byte string key", []byte{0xa1, 0x41, 0x01, 0x02}, "map key of type []uint8"},
+		{"too deep", nested(maxDepth + 1), "nested too deeply"},
+		{"non UTF-8 text", []byte{0x62, 0xc3, 0x28}, "text string is not UTF-8"},
+		{"indefinite length", []byte{0x9f, 0x01, 0xff}, "indefinite lengths are not supported"},
+		{"tag", []byte{0xc0, 0x01}, "unsupported major type 6"},
+		{"undefined", []byte{0xf7}, "unsupported simple value 23"},
+		{"trailing bytes", []byte{0x01, 0x02}, "1 bytes after the item"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := DecodeAll(test.data)
+			if !errors.Is(err, ErrMalformed) {
+				t.Fatalf("DecodeAll = %v, %v, want ErrMalformed", value, err)
+			}
+			if !strings.Contains(err.Error(), test.message) {
+				t.Errorf("error = %q, want it to mention %q", err, test.message)
+			}
+		})
+	}
+}
+
+func TestDecodeMaxDepth(t *testing.T) {
+	if _, err := DecodeAll(nested(maxDepth)); err != nil {
+		t.Errorf("DecodeAll of %d nested arrays: %v", maxDepth, err)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	encoded, err := Marshal(Map{
+		{Key: "fmt", Value: "none"},
+		{Key: 3, Value: -7},
+		{Key: int64(-2), Value: []byte{0xaa}},
+		{Key: "list", Value: []any{true, false, nil, int64(math.MaxUint32 + 1)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []byte{
+		0xa4,
+		0x63, 'f', 'm', 't', 0x64, 'n', 'o', 'n', 'e',
+		0x03, 0x26,
+		0x21, 0x41, 0xaa,
+		0x64, 'l', 'i', 's', 't', 0x84, 0xf5, 0xf4, 0xf6, 0x1b, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00,
+	}
+	if !bytes.Equal(encoded, want) {
+		t.Fatalf("Marshal = %x, want %x", encoded, want)
+	}
+
+	value, err := DecodeAll(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := value.(map[any]any)
+	if decoded["fmt"] != "none" || decoded[int64(3)] != int64(-7) || len(decoded["list"].([]any)) != 4 {
+		t.Errorf("decoded %#v", decoded)
+	}
+}
+
+func TestMarshalUnsupported(t *testing.T) {
+	if _, err := Marshal(1.5); err == nil {
+		t.Error("Marshal of a float succeeded")
+	}
+	if _, err := Marshal([]any{map[string]int{}}); err == nil {
+		t.Error("Marshal of a nested Go map succeeded")
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	f.Add([]byte{0xa2, 0x01, 0x02, 0x63, 'f', 'm', 't', 0xf5})
+	f.Add([]byte{0x82, 0x43, 0x01, 0x02, 0x03, 0x20})
+	f.Add(nested(maxDepth))
+	f.Add([]byte{0x9b, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		value, rest, err := Decode(data)
+		if err != nil {
+			if !errors.Is(err, ErrMalformed) {
+				t.Fatalf("error %v does not wrap ErrMalformed", err)
+			}
+			return
+		}
+
+		if len(rest) >= len(data) || !bytes.HasSuffix(data, rest) {
+			t.Fatalf("rest %x is not a suffix of the data after the item", rest)
+		}
+
+		// Decoding the item alone gives the same value.
+		again, err := DecodeAll(data[:len(data)-len(rest)])
+		if err != nil || !reflect.DeepEqual(again, value) {
+			t.Fatalf("DecodeAll of the item = %#v, %v, want %#v", again, err, value)
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package webauthn
+
+// The types below follow the JSON forms of the WebAuthn dictionaries, which
+// browsers parse and produce with PublicKeyCredential.parseCreationOptionsFromJSON,
+// parseRequestOptionsFromJSON and toJSON, so they keep the spec's camelCase
+// names.
+
+const (
+	credentialType = "public-key"
+
+	requirementRequired = "required"
+)
+
+type RelyingPartyEntity struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserEntity is the account a credential is created for. Id is an opaque
+// handle, which authenticators return when the credential is used.
+type UserEntity struct {
+	Id          URLEncodedBytes `json:"id" openapi:"format=base64url"`
+	Name        string          `json:"name"`
+	DisplayName string          `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type      string `json:"type"`
+	Algorithm int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string          `json:"type"`
+	Id         URLEncodedBytes `json:"id" openapi:"format=base64url"`
+	Transports []string        `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// CreationOptions start a registration ceremony.
+type CreationOptions struct {
+	Challenge              URLEncodedBytes        `json:"challenge" openapi:"format=base64url"`
+	RelyingParty           RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions start an authentication ceremony.
+type RequestOptions struct {
+	Challenge        URLEncodedBytes        `json:"challenge" openapi:"format=base64url"`
+	Timeout          int64                  `json:"timeout"`
+	RpId             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// RegistrationCredential is the client's response to CreationOptions.
+type RegistrationCredential struct {
+	Id       string              `json:"id"`
+	RawId    URLEncodedBytes     `json:"rawId" openapi:"format=base64url"`
+	Type     string              `json:"type"`
+	Response AttestationResponse `json:"response"`
+}
+
+type AttestationResponse struct {
+	ClientDataJSON    URLEncodedBytes `json:"clientDataJSON" openapi:"format=base64url"`
+	AttestationObject URLEncodedBytes `json:"attestationObject" openapi:"format=base64url"`
+	Transports        []string        `json:"transports,omitempty"`
+}
+
+// AuthenticationCredential is the client's response to RequestOptions.
+type AuthenticationCredential struct {
+	Id       string            `json:"id"`
+	RawId    URLEncodedBytes   `json:"rawId" openapi:"format=base64url"`
+	Type     string            `json:"type"`
+	Response AssertionResponse `json:"response"`
+}
+
+type AssertionResponse struct {
+	ClientDataJSON    URLEncodedBytes `json:"clientDataJSON" openapi:"format=base64url"`
+	AuthenticatorData URLEncodedBytes `json:"authenticatorData" openapi:"format=base64url"`
+	Signature         URLEncodedBytes `json:"signature" openapi:"format=base64url"`
+	// UserHandle is the user entity's id, returned for discoverable
+	// credentials.
+	UserHandle URLEncodedBytes `json:"userHandle,omitempty" openapi:"format=base64url"`
+}
+
+// CreationOptions asks for a discoverable credential that verifies the user,
+// so it can sign in without a password or even an email. exclude lists the
+// user's existing credentials, which authenticators refuse to register
+// again.
+func (rp RelyingParty) CreationOptions(challenge []byte, user UserEntity, exclude []CredentialDescriptor) CreationOptions {
+	params := make([]CredentialParameter, len(Algorithms))
+	for i, algorithm := range Algorithms {
+		params[i] = CredentialParameter{Type: credentialType, Algorithm: algorithm}
+	}
+
+	if exclude == nil {
+		exclude = make([]CredentialDescriptor, 0)
+	}
+
+	attestation := rp.Attestation
+	if attestation == "" {
+		attestation = "none"
+	}
+
+	return CreationOptions{
+		Challenge:          challenge,
+		RelyingParty:       RelyingPartyEntity{Id: rp.Id, Name: rp.Name},
+		User:               user,
+		PubKeyCredParams:   params,
+		Timeout:            rp.Timeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:        requirementRequired,
+			RequireResidentKey: true,
+			UserVerification:   requirementRequired,
+		},
+		Attestation: attestation,
+	}
+}
+
+// RequestOptions lets the user pick any of their credentials for the
+// relying party when allow is empty.
+func (rp RelyingParty) RequestOptions(challenge []byte, allow []CredentialDescriptor) RequestOptions {
+	if allow == nil {
+		allow = make([]CredentialDescriptor, 0)
+	}
+
+	return RequestOptions{
+		Challenge:        challenge,
+		Timeout:          rp.Timeout.Milliseconds(),
+		RpId:             rp.Id,
+		AllowCredentials: allow,
+		UserVerification: requirementRequired,
+	}
+}
+
+// Descriptor returns the descriptor of a stored credential for
+// CreationOptions and RequestOptions.
+func (c Credential) Descriptor() CredentialDescriptor {
+	return CredentialDescriptor{Type: credentialType, Id: c.Id, Transports: c.Transports}
+}
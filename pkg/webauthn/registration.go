@@ -0,0 +1,212 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"slices"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/webauthn/internal/cbor"
+)
+
+// Attestation statement formats.
+const (
+	AttestationNone   = "none"
+	AttestationPacked = "packed"
+)
+
+// transports are the authenticator transports kept from registrations.
+var transports = []string{"ble", "hybrid", "internal", "nfc", "smart-card", "usb"}
+
+// idFidoGenCeAaguid is the extension of attestation certificates holding the
+// authenticator's AAGUID.
+var idFidoGenCeAaguid = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+// Credential is a verified credential, stored with its user to verify later
+// authentications.
+type Credential struct {
+	Id []byte
+	// PublicKey is COSE encoded.
+	PublicKey []byte
+	Algorithm int64
+	SignCount uint32
+	// AAGUID identifies the authenticator model.
+	AAGUID            []byte
+	AttestationFormat string
+	Transports        []string
+	// BackupEligible credentials can be synced between devices, like passkeys
+	// in password managers, and BackedUp ones are.
+	BackupEligible bool
+	BackedUp       bool
+}
+
+// VerifyRegistration verifies a registration credential created for the
+// challenge and returns the credential to store. The caller still has to
+// make sure no user registered the credential before.
+func (rp RelyingParty) VerifyRegistration(credential RegistrationCredential, challenge []byte) (Credential, error) {
+	if credential.Type != credentialType {
+		return Credential{}, verificationError("credential type is %q", credential.Type)
+	}
+
+	clientDataHash, err := rp.verifyClientData(credential.Response.ClientDataJSON, ceremonyCreate, challenge)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	value, err := cbor.DecodeAll(credential.Response.AttestationObject)
+	if err != nil {
+		return Credential{}, verificationError("attestation object is not valid CBOR")
+	}
+	attestation, ok := value.(map[any]any)
+	if !ok {
+		return Credential{}, verificationError("attestation object is not a map")
+	}
+
+	format, _ := attestation["fmt"].(string)
+	statement, _ := attestation["attStmt"].(map[any]any)
+	rawAuthData, _ := attestation["authData"].([]byte)
+	if statement == nil || rawAuthData == nil {
+		return Credential{}, verificationError("attestation object is missing fields")
+	}
+
+	authData, err := ParseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return Credential{}, err
+	}
+	if err := authData.verify(rp); err != nil {
+		return Credential{}, err
+	}
+	if !authData.Has(FlagAttestedCredential) {
+		return Credential{}, verificationError("authenticator data has no attested credential")
+	}
+
+	if !bytes.Equal(authData.CredentialId, credential.RawId) {
+		return Credential{}, verificationError("credential id does not match the authenticator data")
+	}
+
+	publicKey, err := ParsePublicKey(authData.PublicKey)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	switch format {
+	case AttestationNone:
+		if len(statement) != 0 {
+			return Credential{}, verificationError("none attestation statement is not empty")
+		}
+	case AttestationPacked:
+		signed := slices.Concat(rawAuthData, clientDataHash)
+		if err := verifyPackedAttestation(statement, signed, authData.AAGUID, publicKey); err != nil {
+			return Credential{}, err
+		}
+	default:
+		return Credential{}, verificationError("attestation format %q is not supported", format)
+	}
+
+	return Credential{
+		Id:                slices.Clone(authData.CredentialId),
+		PublicKey:         slices.Clone(authData.PublicKey),
+		Algorithm:         publicKey.Algorithm,
+		SignCount:         authData.SignCount,
+		AAGUID:            slices.Clone(authData.AAGUID),
+		AttestationFormat: format,
+		Transports:        knownTransports(credential.Response.Transports),
+		BackupEligible:    authData.Has(FlagBackupEligible),
+		BackedUp:          authData.Has(FlagBackedUp),
+	}, nil
+}
+
+// verifyPackedAttestation verifies a packed attestation statement, signed
+// either by an attestation certificate or, with self attestation, by the
+// credential itself.
+func verifyPackedAttestation(statement map[any]any, signed []byte, aaguid []byte, publicKey PublicKey) error {
+	algorithm, ok := statement["alg"].(int64)
+	if !ok {
+		return verificationError("packed attestation has no algorithm")
+	}
+	signature, ok := statement["sig"].([]byte)
+	if !ok {
+		return verificationError("packed attestation has no signature")
+	}
+
+	chain, hasCertificates := statement["x5c"]
+	if !hasCertificates {
+		if algorithm != publicKey.Algorithm {
+			return verificationError("self attestation algorithm does not match the credential")
+		}
+		return publicKey.Verify(signed, signature)
+	}
+
+	certificates, _ := chain.([]any)
+	if len(certificates) == 0 {
+		return verificationError("packed attestation certificate chain is empty")
+	}
+	der, _ := certificates[0].([]byte)
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return verificationError("attestation certificate is not valid")
+	}
+
+	signatureAlgorithm, ok := x509SignatureAlgorithms[algorithm]
+	if !ok {
+		return verificationError("attestation algorithm %d is not supported", algorithm)
+	}
+	if err := certificate.CheckSignature(signatureAlgorithm, signed, signature); err != nil {
+		return verificationError("attestation signature is not valid")
+	}
+
+	return verifyAttestationCertificate(certificate, aaguid)
+}
+
+var x509SignatureAlgorithms = map[int64]x509.SignatureAlgorithm{
+	AlgorithmES256: x509.ECDSAWithSHA256,
+	-35:            x509.ECDSAWithSHA384,
+	-36:            x509.ECDSAWithSHA512,
+	AlgorithmEdDSA: x509.PureEd25519,
+	AlgorithmRS256: x509.SHA256WithRSA,
+	-258:           x509.SHA384WithRSA,
+	-259:           x509.SHA512WithRSA,
+}
+
+// verifyAttestationCertificate checks the requirements of packed
+// attestation certificates (WebAuthn §8.2.1).
+func verifyAttestationCertificate(certificate *x509.Certificate, aaguid []byte) error {
+	if certificate.Version != 3 {
+		return verificationError("attestation certificate is not version 3")
+	}
+
+	subject := certificate.Subject
+	if len(subject.Country) != 1 || len(subject.Organization) != 1 || subject.CommonName == "" ||
+		!slices.Equal(subject.OrganizationalUnit, []string{"Authenticator Attestation"}) {
+		return verificationError("attestation certificate subject is not valid")
+	}
+
+	if !certificate.BasicConstraintsValid || certificate.IsCA {
+		return verificationError("attestation certificate is a CA certificate")
+	}
+
+	for _, extension := range certificate.Extensions {
+		if !extension.Id.Equal(idFidoGenCeAaguid) {
+			continue
+		}
+
+		var value []byte
+		rest, err := asn1.Unmarshal(extension.Value, &value)
+		if err != nil || len(rest) > 0 || extension.Critical || !bytes.Equal(value, aaguid) {
+			return verificationError("attestation certificate AAGUID does not match the authenticator data")
+		}
+	}
+
+	return nil
+}
+
+func knownTransports(values []string) []string {
+	known := make([]string, 0, len(values))
+	for _, value := range values {
+		if slices.Contains(transports, value) && !slices.Contains(known, value) {
+			known = append(known, value)
+		}
+	}
+
+	return known
+}
@@ -0,0 +1,160 @@
+// Package webauthn implements the relying party side of WebAuthn Level 2
+// registration and authentication ceremonies for passkeys.
+//
+// Credentials are verified with ES256, EdDSA or RS256 keys. Registrations
+// are accepted with "none" and "packed" attestation; packed attestation
+// certificates are checked but not chained to a trusted root, as there is no
+// metadata service to get roots from.
+//
+// The package does no I/O. Challenges are created with NewChallenge, sent in
+// the ceremony's options and kept by the caller, which looks them up with
+// ChallengeOf once the client responds. The webauthntest package has a
+// software authenticator for tests.
+package webauthn
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	ChallengeLength = 32
+
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+)
+
+// ErrVerification is wrapped by every error caused by a credential that is
+// malformed or does not pass verification.
+var ErrVerification = errors.New("webauthn: credential could not be verified")
+
+// ErrSignCount means the authenticator's signature counter did not increase,
+// which happens when it was cloned. It wraps ErrVerification.
+var ErrSignCount = fmt.Errorf("%w: signature counter did not increase", ErrVerification)
+
+func verificationError(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrVerification}, args...)...)
+}
+
+// RelyingParty is the service credentials are created for.
+type RelyingParty struct {
+	// Id is the domain credentials are scoped to, e.g. "example.com", which
+	// must be the origins' host or one of its parents.
+	Id   string
+	Name string
+	// Origins are the origins ceremonies may happen on, e.g.
+	// "https://app.example.com".
+	Origins []string
+	// Timeout is how long the user has to complete a ceremony.
+	Timeout time.Duration
+	// Attestation is the attestation conveyance requested at registration,
+	// "none" or "direct".
+	Attestation string
+}
+
+// URLEncodedBytes is encoded as unpadded base64url in JSON, as in the JSON
+// forms of WebAuthn options and credentials. Padded input is accepted too.
+type URLEncodedBytes []byte
+
+func (b URLEncodedBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *URLEncodedBytes) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return fmt.Errorf("value is not base64url: %w", err)
+	}
+
+	*b = decoded
+	return nil
+}
+
+// NewChallenge returns a random challenge for a ceremony.
+func NewChallenge() []byte {
+	challenge := make([]byte, ChallengeLength)
+	rand.Read(challenge)
+	return challenge
+}
+
+// ClientData is what the client collected for the authenticator to sign.
+type ClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// ChallengeOf returns the challenge a credential response was created for,
+// for the caller to find the ceremony it belongs to. The challenge is only
+// compared against the expected one during verification.
+func ChallengeOf(clientDataJSON []byte) ([]byte, error) {
+	clientData, err := parseClientData(clientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := base64.RawURLEncoding.DecodeString(clientData.Challenge)
+	if err != nil {
+		return nil, verificationError("client data challenge is not base64url")
+	}
+
+	return challenge, nil
+}
+
+func parseClientData(clientDataJSON []byte) (ClientData, error) {
+	var clientData ClientData
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return ClientData{}, verificationError("client data is not valid JSON")
+	}
+
+	return clientData, nil
+}
+
+// verifyClientData checks the client data of a ceremony and returns its hash,
+// which the authenticator signed.
+func (rp RelyingParty) verifyClientData(clientDataJSON []byte, ceremony string, challenge []byte) ([]byte, error) {
+	clientData, err := parseClientData(clientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	if clientData.Type != ceremony {
+		return nil, verificationError("client data type is %q, not %q", clientData.Type, ceremony)
+	}
+
+	received, err := base64.RawURLEncoding.DecodeString(clientData.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return nil, verificationError("challenge does not match")
+	}
+
+	if !slices.Contains(rp.Origins, clientData.Origin) {
+		return nil, verificationError("origin %q is not allowed", clientData.Origin)
+	}
+
+	// Ceremonies in frames of other sites could be used to trick users.
+	if clientData.CrossOrigin {
+		return nil, verificationError("cross origin ceremonies are not allowed")
+	}
+
+	hash := sha256.Sum256(clientDataJSON)
+	return hash[:], nil
+}
+
+func (rp RelyingParty) idHash() []byte {
+	hash := sha256.Sum256([]byte(rp.Id))
+	return hash[:]
+}
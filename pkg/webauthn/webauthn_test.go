@@ -0,0 +1,298 @@
+package webauthn_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/webauthn"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/webauthn/internal/cbor"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/webauthn/webauthntest"
+)
+
+const testOrigin = "http://localhost:3000"
+
+var testRelyingParty = webauthn.RelyingParty{
+	Id:      "localhost",
+	Name:    "Test",
+	Origins: []string{testOrigin},
+	Timeout: time.Minute,
+}
+
+var testUser = webauthn.UserEntity{Id: []byte("user handle"), Name: "user@example.com", DisplayName: "User"}
+
+func register(t *testing.T, authenticator *webauthntest.Authenticator) (webauthn.RegistrationCredential, []byte) {
+	t.Helper()
+
+	challenge := webauthn.NewChallenge()
+	credential, err := authenticator.Register(testRelyingParty.CreationOptions(challenge, testUser, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return credential, challenge
+}
+
+func login(t *testing.T, authenticator *webauthntest.Authenticator, stored webauthn.Credential) (webauthn.Authentication, error) {
+	t.Helper()
+
+	challenge := webauthn.NewChallenge()
+	credential, err := authenticator.Login(testRelyingParty.RequestOptions(challenge, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return testRelyingParty.VerifyAuthentication(credential, challenge, stored, testUser.Id)
+}
+
+// expectVerificationError checks that err is a verification error with the
+// message.
+func expectVerificationError(t *testing.T, err error, message string) {
+	t.Helper()
+
+	if !errors.Is(err, webauthn.ErrVerification) {
+		t.Fatalf("error = %v, want a verification error", err)
+	}
+	if !strings.Contains(err.Error(), message) {
+		t.Errorf("error = %q, want it to mention %q", err, message)
+	}
+}
+
+// changeAttestation re-encodes the credential's attestation object after
+// change modified its statement or authenticator data.
+func changeAttestation(t *testing.T, credential *webauthn.RegistrationCredential, change func(statement map[any]any, authData []byte) []byte) {
+	t.Helper()
+
+	value, err := cbor.DecodeAll(credential.Response.AttestationObject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attestation := value.(map[any]any)
+	statement := attestation["attStmt"].(map[any]any)
+	authData := change(statement, attestation["authData"].([]byte))
+
+	encodedStatement := make(cbor.Map, 0, len(statement))
+	for key, value := range statement {
+		encodedStatement = append(encodedStatement, cbor.Pair{Key: key, Value: value})
+	}
+
+	credential.Response.AttestationObject, err = cbor.Marshal(cbor.Map{
+		{Key: "fmt", Value: attestation["fmt"]},
+		{Key: "attStmt", Value: encodedStatement},
+		{Key: "authData", Value: authData},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	tests := []struct {
+		name        string
+		attestation webauthntest.Attestation
+		format      string
+	}{
+		{"none", webauthntest.AttestationNone, webauthn.AttestationNone},
+		{"self", webauthntest.AttestationSelf, webauthn.AttestationPacked},
+		{"basic", webauthntest.AttestationBasic, webauthn.AttestationPacked},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			authenticator := webauthntest.NewAuthenticator(testOrigin)
+			authenticator.Attestation = test.attestation
+
+			credential, challenge := register(t, authenticator)
+			verified, err := testRelyingParty.VerifyRegistration(credential, challenge)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if verified.AttestationFormat != test.format || verified.Algorithm != webauthn.AlgorithmES256 ||
+				verified.SignCount != 1 || !slices.Equal(verified.AAGUID, authenticator.AAGUID[:]) ||
+				!slices.Equal(verified.Transports, []string{"internal"}) || verified.BackupEligible {
+				t.Errorf("VerifyRegistration = %+v", verified)
+			}
+		})
+	}
+}
+
+func TestVerifyRegistrationAttestation(t *testing.T) {
+	tests := []struct {
+		name        string
+		attestation webauthntest.Attestation
+		change      func(statement map[any]any)
+		message     string
+	}{
+		{"none with a statement", webauthntest.AttestationNone, func(statement map[any]any) {
+			statement["alg"] = webauthn.AlgorithmES256
+		}, "none attestation statement is not empty"},
+		{"packed without an algorithm", webauthntest.AttestationSelf, func(statement map[any]any) {
+			delete(statement, "alg")
+		}, "packed attestation has no algorithm"},
+		{"packed without a signature", webauthntest.AttestationSelf, func(statement map[any]any) {
+			delete(statement, "sig")
+		}, "packed attestation has no signature"},
+		{"self with another algorithm", webauthntest.AttestationSelf, func(statement map[any]any) {
+			statement["alg"] = webauthn.AlgorithmRS256
+		}, "self attestation algorithm does not match the credential"},
+		{"self with a wrong signature", webauthntest.AttestationSelf, func(statement map[any]any) {
+			signature := statement["sig"].([]byte)
+			signature[len(signature)-1] ^= 0xff
+		}, "signature is not valid"},
+		{"basic without certificates", webauthntest.AttestationBasic, func(statement map[any]any) {
+			statement["x5c"] = []any{}
+		}, "packed attestation certificate chain is empty"},
+		{"basic with a malformed certificate", webauthntest.AttestationBasic, func(statement map[any]any) {
+			statement["x5c"] = []any{[]byte("certificate")}
+		}, "attestation certificate is not valid"},
+		{"basic with an unsupported algorithm", webauthntest.AttestationBasic, func(statement map[any]any) {
+			statement["alg"] = int64(42)
+		}, "attestation algorithm 42 is not supported"},
+		{"basic with a wrong signature", webauthntest.AttestationBasic, func(statement map[any]any) {
+			signature := statement["sig"].([]byte)
+			signature[len(signature)-1] ^= 0xff
+		}, "attestation signature is not valid"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			authenticator := webauthntest.NewAuthenticator(testOrigin)
+			authenticator.Attestation = test.attestation
+
+			credential, challenge := register(t, authenticator)
+			changeAttestation(t, &credential, func(statement map[any]any, authData []byte) []byte {
+				test.change(statement)
+				return authData
+			})
+
+			_, err := testRelyingParty.VerifyRegistration(credential, challenge)
+			expectVerificationError(t, err, test.message)
+		})
+	}
+}
+
+func TestVerifyRegistrationCertificateAAGUID(t *testing.T) {
+	authenticator := webauthntest.NewAuthenticator(testOrigin)
+	authenticator.Attestation = webauthntest.AttestationBasic
+	register(t, authenticator)
+
+	// The attestation certificate keeps the AAGUID of the first registration.
+	authenticator.AAGUID[0] ^= 0xff
+	credential, challenge := register(t, authenticator)
+
+	_, err := testRelyingParty.VerifyRegistration(credential, challenge)
+	expectVerificationError(t, err, "attestation certificate AAGUID does not match the authenticator data")
+}
+
+func TestVerifyRegistrationFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(authData []byte)
+		message string
+	}{
+		{"user not present", func(authData []byte) {
+			authData[32] &^= webauthn.FlagUserPresent
+		}, "user was not present"},
+		{"user not verified", func(authData []byte) {
+			authData[32] &^= webauthn.FlagUserVerified
+		}, "user was not verified"},
+		{"backed up but not eligible", func(authData []byte) {
+			authData[32] |= webauthn.FlagBackedUp
+		}, "credential is backed up but not backup eligible"},
+		{"without a credential", func(authData []byte) {
+			authData[32] &^= webauthn.FlagAttestedCredential
+		}, "authenticator data has"},
+		{"other relying party", func(authData []byte) {
+			authData[0] ^= 0xff
+		}, "credential was created for another relying party"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			credential, challenge := register(t, webauthntest.NewAuthenticator(testOrigin))
+			changeAttestation(t, &credential, func(statement map[any]any, authData []byte) []byte {
+				test.change(authData)
+				return authData
+			})
+
+			_, err := testRelyingParty.VerifyRegistration(credential, challenge)
+			expectVerificationError(t, err, test.message)
+		})
+	}
+}
+
+func TestVerifyAuthentication(t *testing.T) {
+	authenticator := webauthntest.NewAuthenticator(testOrigin)
+	authenticator.BackupEligible = true
+
+	credential, challenge := register(t, authenticator)
+	stored, err := testRelyingParty.VerifyRegistration(credential, challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.BackupEligible || !stored.BackedUp {
+		t.Errorf("stored = %+v, want a backed up credential", stored)
+	}
+
+	authentication, err := login(t, authenticator, stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authentication.SignCount != 2 || !authentication.BackedUp {
+		t.Errorf("VerifyAuthentication = %+v", authentication)
+	}
+}
+
+func TestVerifyAuthenticationBackupEligibility(t *testing.T) {
+	authenticator := webauthntest.NewAuthenticator(testOrigin)
+
+	credential, challenge := register(t, authenticator)
+	stored, err := testRelyingParty.VerifyRegistration(credential, challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authenticator.BackupEligible = true
+	_, err = login(t, authenticator, stored)
+	expectVerificationError(t, err, "backup eligibility of the credential changed")
+}
+
+func TestVerifyAuthenticationSignCount(t *testing.T) {
+	tests := []struct {
+		name           string
+		disableCounter bool
+		storedCount    uint32
+		signCount      uint32
+		err            error
+	}{
+		{"increased", false, 5, 5, nil},
+		{"not increased", false, 5, 4, webauthn.ErrSignCount},
+		{"decreased", false, 5, 1, webauthn.ErrSignCount},
+		{"not implemented", true, 0, 0, nil},
+		{"reset to zero", true, 5, 0, webauthn.ErrSignCount},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			authenticator := webauthntest.NewAuthenticator(testOrigin)
+
+			credential, challenge := register(t, authenticator)
+			stored, err := testRelyingParty.VerifyRegistration(credential, challenge)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stored.SignCount = test.storedCount
+			authenticator.SignCount = test.signCount
+			authenticator.DisableCounter = test.disableCounter
+
+			_, err = login(t, authenticator, stored)
+			if !errors.Is(err, test.err) {
+				t.Errorf("VerifyAuthentication error = %v, want %v", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+// Package webauthntest provides a software authenticator, which performs
+// WebAuthn ceremonies in tests the way a browser and a passkey provider
+// would.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/webauthn"
+	"github.com/CelanMatjaz/job_application_tracker_api/pkg/webauthn/internal/cbor"
+)
+
+// Attestation is the kind of attestation statement created at registration.
+type Attestation int
+
+const (
+	// AttestationNone creates "none" statements.
+	AttestationNone Attestation = iota
+	// AttestationSelf creates "packed" statements signed by the credential.
+	AttestationSelf
+	// AttestationBasic creates "packed" statements signed by an attestation
+	// certificate.
+	AttestationBasic
+)
+
+var (
+	ErrNoCredential = errors.New("webauthntest: no credential for the relying party")
+	ErrExcluded     = errors.New("webauthntest: a credential in excludeCredentials is registered")
+	ErrAlgorithm    = errors.New("webauthntest: ES256 is not in pubKeyCredParams")
+)
+
+var idFidoGenCeAaguid = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+// Authenticator holds discoverable ES256 credentials and verifies users
+// without asking. Fields may be changed between ceremonies.
+type Authenticator struct {
+	// Origin is reported as the origin of every ceremony.
+	Origin      string
+	AAGUID      [16]byte
+	Attestation Attestation
+	// SignCount is the counter shared by all credentials, incremented before
+	// every signature unless DisableCounter is set. Lowering it makes the
+	// authenticator look cloned.
+	SignCount      uint32
+	DisableCounter bool
+	// BackupEligible makes credentials look like synced passkeys.
+	BackupEligible bool
+
+	mu          sync.Mutex
+	credentials []*credential
+	attestation *attestationCertificate
+}
+
+type credential struct {
+	id         []byte
+	rpId       string
+	userHandle []byte
+	key        *ecdsa.PrivateKey
+}
+
+type attestationCertificate struct {
+	key *ecdsa.PrivateKey
+	der []byte
+}
+
+func NewAuthenticator(origin string) *Authenticator {
+	authenticator := &Authenticator{Origin: origin}
+	rand.Read(authenticator.AAGUID[:])
+	return authenticator
+}
+
+// Register creates a credential for the options, replacing the one the
+// authenticator held for the same user, and returns the response a browser
+// would send.
+func (a *Authenticator) Register(options webauthn.CreationOptions) (webauthn.RegistrationCredential, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !slices.ContainsFunc(options.PubKeyCredParams, func(param webauthn.CredentialParameter) bool {
+		return param.Algorithm == webauthn.AlgorithmES256
+	}) {
+		return webauthn.RegistrationCredential{}, ErrAlgorithm
+	}
+
+	for _, excluded := range options.ExcludeCredentials {
+		if a.find(options.RelyingParty.Id, excluded.Id) != nil {
+			return webauthn.RegistrationCredential{}, ErrExcluded
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return webauthn.RegistrationCredential{}, err
+	}
+
+	created := &credential{
+		id:         make([]byte, 16),
+		rpId:       options.RelyingParty.Id,
+		userHandle: slices.Clone(options.User.Id),
+		key:        key,
+	}
+	rand.Read(created.id)
+
+	a.credentials = slices.DeleteFunc(a.credentials, func(c *credential) bool {
+		return c.rpId == created.rpId && slices.Equal(c.userHandle, created.userHandle)
+	})
+	a.credentials = append(a.credentials, created)
+
+	clientDataJSON, err := a.clientData("webauthn.create", options.Challenge)
+	if err != nil {
+		return webauthn.RegistrationCredential{}, err
+	}
+
+	publicKey, err := cbor.Marshal(cbor.Map{
+		{Key: 1, Value: 2},
+		{Key: 3, Value: webauthn.AlgorithmES256},
+		{Key: -1, Value: 1},
+		{Key: -2, Value: key.X.FillBytes(make([]byte, 32))},
+		{Key: -3, Value: key.Y.FillBytes(make([]byte, 32))},
+	})
+	if err != nil {
+		return webauthn.RegistrationCredential{}, err
+	}
+
+	authData := a.authenticatorData(created.rpId, webauthn.FlagAttestedCredential)
+	authData = append(authData, a.AAGUID[:]...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(created.id)))
+	authData = append(authData, created.id...)
+	authData = append(authData, publicKey...)
+
+	format, statement, err := a.attestationStatement(key, authData, clientDataJSON)
+	if err != nil {
+		return webauthn.RegistrationCredential{}, err
+	}
+
+	attestationObject, err := cbor.Marshal(cbor.Map{
+		{Key: "fmt", Value: format},
+		{Key: "attStmt", Value: statement},
+		{Key: "authData", Value: authData},
+	})
+	if err != nil {
+		return webauthn.RegistrationCredential{}, err
+	}
+
+	return webauthn.RegistrationCredential{
+		Id:    base64.RawURLEncoding.EncodeToString(created.id),
+		RawId: created.id,
+		Type:  "public-key",
+		Response: webauthn.AttestationResponse{
+			ClientDataJSON:    clientDataJSON,
+			AttestationObject: attestationObject,
+			Transports:        []string{"internal"},
+		},
+	}, nil
+}
+
+// Login signs the options' challenge with the newest credential for the
+// relying party that the options allow and returns the response a browser
+// would send.
+func (a *Authenticator) Login(options webauthn.RequestOptions) (webauthn.AuthenticationCredential, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var chosen *credential
+	for _, c := range slices.Backward(a.credentials) {
+		allowed := len(options.AllowCredentials) == 0 || slices.ContainsFunc(options.AllowCredentials, func(descriptor webauthn.CredentialDescriptor) bool {
+			return slices.Equal(descriptor.Id, c.id)
+		})
+		if c.rpId == options.RpId && allowed {
+			chosen = c
+			break
+		}
+	}
+	if chosen == nil {
+		return webauthn.AuthenticationCredential{}, ErrNoCredential
+	}
+
+	clientDataJSON, err := a.clientData("webauthn.get", options.Challenge)
+	if err != nil {
+		return webauthn.AuthenticationCredential{}, err
+	}
+
+	authData := a.authenticatorData(chosen.rpId, 0)
+	signature, err := sign(chosen.key, authData, clientDataJSON)
+	if err != nil {
+		return webauthn.AuthenticationCredential{}, err
+	}
+
+	return webauthn.AuthenticationCredential{
+		Id:    base64.RawURLEncoding.EncodeToString(chosen.id),
+		RawId: chosen.id,
+		Type:  "public-key",
+		Response: webauthn.AssertionResponse{
+			ClientDataJSON:    clientDataJSON,
+			AuthenticatorData: authData,
+			Signature:         signature,
+			UserHandle:        chosen.userHandle,
+		},
+	}, nil
+}
+
+func (a *Authenticator) find(rpId string, id []byte) *credential {
+	for _, c := range a.credentials {
+		if c.rpId == rpId && slices.Equal(c.id, id) {
+			return c
+		}
+	}
+	return nil
+}
+
+func (a *Authenticator) clientData(ceremony string, challenge []byte) ([]byte, error) {
+	return json.Marshal(webauthn.ClientData{
+		Type:      ceremony,
+		Challenge: base64.RawURLEncoding.EncodeToString(challenge),
+		Origin:    a.Origin,
+	})
+}
+
+// authenticatorData returns the RP ID hash, flags and counter, advancing the
+// counter.
+func (a *Authenticator) authenticatorData(rpId string, flags byte) []byte {
+	flags |= webauthn.FlagUserPresent | webauthn.FlagUserVerified
+	if a.BackupEligible {
+		flags |= webauthn.FlagBackupEligible | webauthn.FlagBackedUp
+	}
+
+	if !a.DisableCounter {
+		a.SignCount++
+	}
+
+	rpIdHash := sha256.Sum256([]byte(rpId))
+	data := append(rpIdHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, a.SignCount)
+}
+
+func (a *Authenticator) attestationStatement(key *ecdsa.PrivateKey, authData []byte, clientDataJSON []byte) (string, cbor.Map, error) {
+	switch a.Attestation {
+	case AttestationSelf:
+		signature, err := sign(key, authData, clientDataJSON)
+		if err != nil {
+			return "", nil, err
+		}
+		return webauthn.AttestationPacked, cbor.Map{
+			{Key: "alg", Value: webauthn.AlgorithmES256},
+			{Key: "sig", Value: signature},
+		}, nil
+	case AttestationBasic:
+		certificate, err := a.attestationCertificate()
+		if err != nil {
+			return "", nil, err
+		}
+		signature, err := sign(certificate.key, authData, clientDataJSON)
+		if err != nil {
+			return "", nil, err
+		}
+		return webauthn.AttestationPacked, cbor.Map{
+			{Key: "alg", Value: webauthn.AlgorithmES256},
+			{Key: "sig", Value: signature},
+			{Key: "x5c", Value: []any{certificate.der}},
+		}, nil
+	default:
+		return webauthn.AttestationNone, cbor.Map{}, nil
+	}
+}
+
+// attestationCertificate creates a self-signed certificate meeting the
+// requirements of packed attestation on first use.
+func (a *Authenticator) attestationCertificate() (*attestationCertificate, error) {
+	if a.attestation != nil {
+		return a.attestation, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	aaguid, err := asn1.Marshal(a.AAGUID[:])
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			Country:            []string{"SI"},
+			Organization:       []string{"webauthntest"},
+			OrganizationalUnit: []string{"Authenticator Attestation"},
+			CommonName:         "webauthntest software authenticator",
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		BasicConstraintsValid: true,
+		ExtraExtensions:       []pkix.Extension{{Id: idFidoGenCeAaguid, Value: aaguid}},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	a.attestation = &attestationCertificate{key: key, der: der}
+	return a.attestation, nil
+}
+
+func sign(key *ecdsa.PrivateKey, authData []byte, clientDataJSON []byte) ([]byte, error) {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(slices.Concat(authData, clientDataHash[:]))
+	return ecdsa.SignASN1(rand.Reader, key, digest[:])
+}